@@ -1,86 +1,100 @@
 package main
 
 import (
+	"bufio"
+	"context"
+	"encoding/json"
 	"flag"
 	"fmt"
+	"io"
 	"log/slog"
 	"os"
+	"path/filepath"
 	"strings"
 
+	dockercontainer "github.com/docker/docker/api/types/container"
+
 	"watchcow/internal/fpkgen"
 )
 
+// Container defaults for labels given without docker inspect JSON
+const (
+	defaultContainerID   = "debug1234567890"
+	defaultContainerName = "debug-container"
+	defaultImage         = "nginx:latest"
+)
+
+// staticInspector serves one container to the generator in place of the Docker daemon,
+// so the labels go through the same extraction as a container the daemon inspects.
+type staticInspector struct {
+	container dockercontainer.InspectResponse
+}
+
+func (s staticInspector) ContainerInspect(ctx context.Context, containerID string) (dockercontainer.InspectResponse, error) {
+	return s.container, nil
+}
+
 func main() {
 	// Flags
 	outputDir := flag.String("output", "./debug-output", "Output directory for generated app")
+	fpkPath := flag.String("fpk", "", "Also pack the generated app into this .fpk file")
+	inspectPath := flag.String("inspect", "", "Read the container from docker inspect JSON (- for stdin)")
+	labelFile := flag.String("env-file", "", "Read labels from a file of key=value lines")
+	printManifest := flag.Bool("print-manifest", false, "Print the rendered manifest to stdout")
+	printUIConfig := flag.Bool("print-ui-config", false, "Print the rendered app/ui/config JSON to stdout")
+	flag.Usage = printUsage
 	flag.Parse()
 
+	// Keep stdout for the rendered files when printing them
+	var out io.Writer = os.Stdout
+	if *printManifest || *printUIConfig {
+		out = os.Stderr
+	}
+
 	// Configure logging
-	handler := slog.NewTextHandler(os.Stdout, &slog.HandlerOptions{Level: slog.LevelDebug})
+	handler := slog.NewTextHandler(out, &slog.HandlerOptions{Level: slog.LevelDebug})
 	slog.SetDefault(slog.New(handler))
 
-	// Parse key=value arguments
-	args := flag.Args()
-	if len(args) == 0 {
+	if *inspectPath == "" && *labelFile == "" && flag.NArg() == 0 {
 		printUsage()
 		os.Exit(1)
 	}
 
-	// Build config from arguments
-	config := &fpkgen.AppConfig{
-		// Defaults
-		AppName:       "debug.test-app",
-		Version:       "1.0.0",
-		DisplayName:   "Test App",
-		Description:   "Debug generated app",
-		Maintainer:    "WatchCow Debug",
-		ContainerID:   "debug123456",
-		ContainerName: "debug-container",
-		Image:         "nginx:latest",
-		Protocol:      "http",
-		Port:          "8080",
-		Path:          "/",
-		UIType:        "url",
-		Icon:          "",
-		RestartPolicy: "unless-stopped",
-		Labels:        make(map[string]string),
-	}
-
-	// Override with provided key=value pairs
-	for _, arg := range args {
-		parts := strings.SplitN(arg, "=", 2)
-		if len(parts) != 2 {
-			fmt.Printf("Invalid argument: %s (expected key=value)\n", arg)
-			continue
-		}
-		key, value := parts[0], parts[1]
-		applyConfig(config, key, value)
-	}
-
-	// Print config
-	fmt.Println("=== Configuration ===")
-	fmt.Printf("AppName:      %s\n", config.AppName)
-	fmt.Printf("DisplayName:  %s\n", config.DisplayName)
-	fmt.Printf("Version:      %s\n", config.Version)
-	fmt.Printf("Description:  %s\n", config.Description)
-	fmt.Printf("Maintainer:   %s\n", config.Maintainer)
-	fmt.Printf("Port:         %s\n", config.Port)
-	fmt.Printf("Protocol:     %s\n", config.Protocol)
-	fmt.Printf("Path:         %s\n", config.Path)
-	fmt.Printf("UIType:       %s\n", config.UIType)
-	fmt.Printf("Icon:         %s\n", config.Icon)
-	fmt.Printf("Image:        %s\n", config.Image)
-	fmt.Printf("Output:       %s\n", *outputDir)
-	fmt.Println()
+	container, err := buildContainer(*inspectPath, *labelFile, flag.Args())
+	if err != nil {
+		slog.Error("Invalid input", "error", err)
+		os.Exit(1)
+	}
 
-	// Create generator (uses template engine internally)
-	generator, err := fpkgen.NewGenerator()
+	// Create generator with the container standing in for the Docker daemon
+	generator, err := fpkgen.NewGeneratorWithClient(staticInspector{container: container})
 	if err != nil {
 		slog.Error("Failed to create generator", "error", err)
 		os.Exit(1)
 	}
 	defer generator.Close()
 
+	config, err := generator.ConfigFromContainer(context.Background(), container.ID)
+	if err != nil {
+		slog.Error("Failed to extract config", "error", err)
+		os.Exit(1)
+	}
+
+	printConfig(out, config, *outputDir)
+
+	problems := fpkgen.ValidateLabels(config.Labels)
+	if len(problems) > 0 {
+		fmt.Fprintln(out, "=== Label Problems ===")
+		for _, p := range problems {
+			fmt.Fprintf(out, "%-8s %s\n", p.Severity, p)
+		}
+		fmt.Fprintln(out)
+	}
+	if err := problems.Err(); err != nil {
+		slog.Error("Not generating app, the daemon would not install it", "error", err)
+		os.Exit(1)
+	}
+
 	// Ensure output directory exists
 	if err := os.MkdirAll(*outputDir, 0755); err != nil {
 		slog.Error("Failed to create output directory", "error", err)
@@ -93,84 +107,260 @@ func main() {
 		os.Exit(1)
 	}
 
-	fmt.Println("=== Generated Files ===")
-	printTree(*outputDir, "")
-	fmt.Println()
-	fmt.Printf("Output directory: %s\n", *outputDir)
+	fmt.Fprintln(out, "=== Generated Files ===")
+	printTree(out, *outputDir, "")
+	fmt.Fprintln(out)
+	fmt.Fprintf(out, "Output directory: %s\n", *outputDir)
+
+	if *fpkPath != "" {
+		if err := fpkgen.WritePackage(*outputDir, *fpkPath); err != nil {
+			slog.Error("Failed to pack app", "error", err)
+			os.Exit(1)
+		}
+		fmt.Fprintf(out, "Package:          %s\n", *fpkPath)
+	}
+
+	if *printManifest {
+		if err := copyFile(os.Stdout, filepath.Join(*outputDir, "manifest")); err != nil {
+			slog.Error("Failed to print manifest", "error", err)
+			os.Exit(1)
+		}
+	}
+	if *printUIConfig {
+		if err := copyFile(os.Stdout, filepath.Join(*outputDir, "app", "ui", "config")); err != nil {
+			slog.Error("Failed to print UI config", "error", err)
+			os.Exit(1)
+		}
+	}
 }
 
 func printUsage() {
-	fmt.Println("Debug Generator - Generate fnOS app directory from key=value pairs")
+	fmt.Println("Debug Generator - Generate fnOS app directory from container labels")
 	fmt.Println()
 	fmt.Println("Usage:")
-	fmt.Println("  debug-generator [flags] key=value [key=value ...]")
+	fmt.Println("  debug-generator [flags] [key=value ...]")
 	fmt.Println()
 	fmt.Println("Flags:")
-	fmt.Println("  -output string   Output directory (default \"./debug-output\")")
+	fmt.Println("  -output string      Output directory (default \"./debug-output\")")
+	fmt.Println("  -fpk string         Also write an .fpk package to this path")
+	fmt.Println("  -inspect string     Read the container from `docker inspect` JSON (- for stdin)")
+	fmt.Println("  -env-file string    Read labels from a file of key=value lines (docker --label-file format)")
+	fmt.Println("  -print-manifest     Print the rendered manifest to stdout (other output goes to stderr)")
+	fmt.Println("  -print-ui-config    Print the rendered app/ui/config JSON to stdout")
+	fmt.Println()
+	fmt.Println("Arguments are container labels, e.g. watchcow.service_port=80 or")
+	fmt.Println("watchcow.admin.path=/admin. The watchcow. prefix may be omitted. Labels")
+	fmt.Println("from arguments override the env-file, which overrides the inspect JSON.")
+	fmt.Println("All labels are extracted exactly as the daemon does, including entries,")
+	fmt.Println("redirect, file_types, no_display, control, manifest, shares and wizards.")
 	fmt.Println()
-	fmt.Println("Supported keys (following fnOS manifest conventions):")
-	fmt.Println("  appname        - App identifier (e.g., watchcow.myapp)")
-	fmt.Println("  display_name   - Display name")
-	fmt.Println("  desc           - Description")
-	fmt.Println("  version        - Version (e.g., 1.0.0)")
-	fmt.Println("  maintainer     - Maintainer name")
-	fmt.Println("  service_port   - Service port")
-	fmt.Println("  protocol       - http or https")
-	fmt.Println("  path           - URL path")
-	fmt.Println("  ui_type        - url or iframe")
-	fmt.Println("  icon           - Icon URL")
-	fmt.Println("  image          - Docker image name")
-	fmt.Println("  container_name - Container name")
+	fmt.Println("Two keys set the container instead of a label:")
+	fmt.Println("  image          - Docker image name (default: " + defaultImage + ")")
+	fmt.Println("  container_name - Container name (default: " + defaultContainerName + ")")
 	fmt.Println()
-	fmt.Println("Example:")
-	fmt.Println("  debug-generator appname=watchcow.nginx display_name=\"Nginx Server\" service_port=80")
+	fmt.Println("Examples:")
+	fmt.Println("  debug-generator watchcow.appname=watchcow.nginx watchcow.display_name=\"Nginx Server\" watchcow.service_port=80")
+	fmt.Println("  docker inspect nginx | debug-generator -inspect - -print-manifest")
+}
+
+// buildContainer assembles the container to generate from: the docker inspect JSON
+// if given, otherwise a default container, with labels from the label file and the
+// key=value arguments applied on top.
+func buildContainer(inspectPath, labelFile string, args []string) (dockercontainer.InspectResponse, error) {
+	container := defaultContainer()
+	if inspectPath != "" {
+		c, err := readInspect(inspectPath)
+		if err != nil {
+			return container, err
+		}
+		container = c
+	}
+	normalizeContainer(&container)
+
+	var pairs []string
+	if labelFile != "" {
+		lines, err := readLabelFile(labelFile)
+		if err != nil {
+			return container, err
+		}
+		pairs = append(pairs, lines...)
+	}
+	pairs = append(pairs, args...)
+
+	for _, pair := range pairs {
+		key, value, ok := strings.Cut(pair, "=")
+		if !ok || key == "" {
+			return container, fmt.Errorf("invalid argument: %s (expected key=value)", pair)
+		}
+		switch key {
+		case "image":
+			container.Config.Image = value
+		case "container_name":
+			container.Name = "/" + value
+		default:
+			if !strings.HasPrefix(key, "watchcow.") {
+				key = "watchcow." + key
+			}
+			container.Config.Labels[key] = value
+		}
+	}
+	return container, nil
+}
+
+// defaultContainer returns the container used when no inspect JSON is given.
+func defaultContainer() dockercontainer.InspectResponse {
+	return dockercontainer.InspectResponse{
+		ContainerJSONBase: &dockercontainer.ContainerJSONBase{
+			ID:   defaultContainerID,
+			Name: "/" + defaultContainerName,
+			HostConfig: &dockercontainer.HostConfig{
+				RestartPolicy: dockercontainer.RestartPolicy{Name: dockercontainer.RestartPolicyUnlessStopped},
+			},
+		},
+		Config: &dockercontainer.Config{
+			Image: defaultImage,
+		},
+	}
+}
+
+// normalizeContainer fills in the parts of the inspect response extraction relies on,
+// so hand-written or trimmed JSON works.
+func normalizeContainer(c *dockercontainer.InspectResponse) {
+	if c.ContainerJSONBase == nil {
+		c.ContainerJSONBase = &dockercontainer.ContainerJSONBase{}
+	}
+	if len(c.ID) < 12 {
+		c.ID = defaultContainerID
+	}
+	if c.Name == "" {
+		c.Name = "/" + defaultContainerName
+	}
+	if c.HostConfig == nil {
+		c.HostConfig = &dockercontainer.HostConfig{}
+	}
+	if c.Config == nil {
+		c.Config = &dockercontainer.Config{}
+	}
+	if c.Config.Image == "" {
+		c.Config.Image = defaultImage
+	}
+	if c.Config.Labels == nil {
+		c.Config.Labels = make(map[string]string)
+	}
 }
 
-func applyConfig(config *fpkgen.AppConfig, key, value string) {
-	switch key {
-	case "appname":
-		config.AppName = value
-	case "display_name":
-		config.DisplayName = value
-	case "desc":
-		config.Description = value
-	case "version":
-		config.Version = value
-	case "maintainer":
-		config.Maintainer = value
-	case "service_port":
-		config.Port = value
-	case "protocol":
-		config.Protocol = value
-	case "path":
-		config.Path = value
-	case "ui_type":
-		config.UIType = value
-	case "icon":
-		config.Icon = value
-	case "image":
-		config.Image = value
-	case "container_name":
-		config.ContainerName = value
-	default:
-		fmt.Printf("Unknown key: %s\n", key)
+// readInspect reads the output of docker inspect: an array of containers
+// (the first one is used) or a single container object.
+func readInspect(path string) (dockercontainer.InspectResponse, error) {
+	var data []byte
+	var err error
+	if path == "-" {
+		data, err = io.ReadAll(os.Stdin)
+	} else {
+		data, err = os.ReadFile(path)
+	}
+	if err != nil {
+		return dockercontainer.InspectResponse{}, err
+	}
+
+	var containers []dockercontainer.InspectResponse
+	if err := json.Unmarshal(data, &containers); err != nil {
+		var c dockercontainer.InspectResponse
+		if err := json.Unmarshal(data, &c); err != nil {
+			return c, fmt.Errorf("invalid docker inspect JSON: %w", err)
+		}
+		containers = append(containers, c)
+	}
+	if len(containers) == 0 {
+		return dockercontainer.InspectResponse{}, fmt.Errorf("docker inspect JSON has no containers")
+	}
+	if len(containers) > 1 {
+		slog.Warn("docker inspect JSON has several containers, using the first", "count", len(containers))
+	}
+	return containers[0], nil
+}
+
+// readLabelFile reads key=value lines; empty lines and lines starting with # are skipped.
+func readLabelFile(path string) ([]string, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	var pairs []string
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		pairs = append(pairs, line)
+	}
+	return pairs, scanner.Err()
+}
+
+func printConfig(w io.Writer, config *fpkgen.AppConfig, outputDir string) {
+	fmt.Fprintln(w, "=== Configuration ===")
+	fmt.Fprintf(w, "AppName:      %s\n", config.AppName)
+	fmt.Fprintf(w, "DisplayName:  %s\n", config.DisplayName)
+	fmt.Fprintf(w, "Version:      %s\n", config.Version)
+	fmt.Fprintf(w, "Description:  %s\n", config.Description)
+	fmt.Fprintf(w, "Maintainer:   %s\n", config.Maintainer)
+	fmt.Fprintf(w, "Container:    %s\n", config.ContainerName)
+	fmt.Fprintf(w, "Image:        %s\n", config.Image)
+	fmt.Fprintf(w, "Arch:         %s\n", config.Arch)
+	fmt.Fprintf(w, "Lifecycle:    %s\n", config.Lifecycle)
+	fmt.Fprintf(w, "Output:       %s\n", outputDir)
+	for _, e := range config.Entries {
+		name := e.Name
+		if name == "" {
+			name = "(default)"
+		}
+		fmt.Fprintf(w, "Entry %s: %s %s://:%s%s ui_type=%s all_users=%t no_display=%t",
+			name, e.Title, e.Protocol, e.Port, e.Path, e.UIType, e.AllUsers, e.NoDisplay)
+		if len(e.FileTypes) > 0 {
+			fmt.Fprintf(w, " file_types=%s", strings.Join(e.FileTypes, ","))
+		}
+		if e.Redirect != "" {
+			fmt.Fprintf(w, " redirect=%s", e.Redirect)
+		}
+		if e.Control != nil {
+			fmt.Fprintf(w, " control=%s/%s/%s", e.Control.AccessPerm, e.Control.PortPerm, e.Control.PathPerm)
+		}
+		fmt.Fprintln(w)
+	}
+	for _, s := range config.Shares {
+		fmt.Fprintf(w, "Share %s: %s\n", s.Name, s.Source)
+	}
+	fmt.Fprintln(w)
+}
+
+// copyFile writes the contents of a generated file to w.
+func copyFile(w io.Writer, path string) error {
+	f, err := os.Open(path)
+	if err != nil {
+		return err
 	}
+	defer f.Close()
+	_, err = io.Copy(w, f)
+	return err
 }
 
-func printTree(path string, prefix string) {
+func printTree(w io.Writer, path string, prefix string) {
 	entries, _ := os.ReadDir(path)
 	for i, entry := range entries {
 		connector := "├── "
 		if i == len(entries)-1 {
 			connector = "└── "
 		}
-		fmt.Printf("%s%s%s\n", prefix, connector, entry.Name())
+		fmt.Fprintf(w, "%s%s%s\n", prefix, connector, entry.Name())
 		if entry.IsDir() {
 			newPrefix := prefix + "│   "
 			if i == len(entries)-1 {
 				newPrefix = prefix + "    "
 			}
-			printTree(path+"/"+entry.Name(), newPrefix)
+			printTree(w, path+"/"+entry.Name(), newPrefix)
 		}
 	}
 }
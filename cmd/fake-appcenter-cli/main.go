@@ -0,0 +1,14 @@
+// Command fake-appcenter-cli is a stand-in for fnOS appcenter-cli.
+// It keeps installed apps in a state file so WatchCow can run end to end
+// on any Linux box. Point WatchCow at it with WATCHCOW_APPCENTER_CLI.
+package main
+
+import (
+	"os"
+
+	"watchcow/internal/fakeappcenter"
+)
+
+func main() {
+	os.Exit(fakeappcenter.Run(os.Args[1:], os.Stdout, os.Stderr))
+}
@@ -0,0 +1,81 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"log/slog"
+	"os"
+
+	"watchcow/internal/compose"
+	"watchcow/internal/fpkgen"
+)
+
+// runGenerateMode generates the fnOS packages of the enabled services in a compose file
+// without a Docker daemon, e.g. to build and review packages in CI.
+func runGenerateMode(args []string) int {
+	fs := flag.NewFlagSet("generate", flag.ContinueOnError)
+	outputDir := fs.String("output", "./fnos-packages", "Output directory")
+	pack := fs.Bool("fpk", false, "Write .fpk packages instead of app directories")
+	arch := fs.String("arch", "", "Arch of services without watchcow.arch: x86_64 or arm64 (default: host)")
+	offline := fs.Bool("offline", false, "Use the default icon instead of downloading http(s) icons")
+	debug := fs.Bool("debug", false, "Enable debug logging")
+	fs.Usage = func() {
+		fmt.Fprintln(fs.Output(), "Usage: watchcow generate [-output dir] [-fpk] [-arch x86_64|arm64] [-offline] compose.yaml")
+		fmt.Fprintln(fs.Output(), "\nhttp(s) icons, including the default icon URL derived from the image name,")
+		fmt.Fprintln(fs.Output(), "are downloaded and need network access; an icon that cannot be downloaded")
+		fmt.Fprintln(fs.Output(), "is replaced by the default icon. Use -offline for builds without network.")
+		fmt.Fprintln(fs.Output())
+		fs.PrintDefaults()
+	}
+	if err := fs.Parse(args); err != nil {
+		return exitFailed
+	}
+	if fs.NArg() != 1 {
+		fs.Usage()
+		return exitFailed
+	}
+
+	opts := compose.GenerateOptions{OutputDir: *outputDir, Pack: *pack, Offline: *offline}
+	if *arch != "" {
+		normalized, ok := fpkgen.NormalizeArch(*arch)
+		if !ok {
+			fmt.Fprintf(os.Stderr, "Unknown arch: %s\n", *arch)
+			return exitFailed
+		}
+		opts.Arch = normalized
+	}
+
+	logLevel := slog.LevelWarn
+	if *debug {
+		logLevel = slog.LevelDebug
+	}
+	slog.SetDefault(slog.New(slog.NewTextHandler(os.Stderr, &slog.HandlerOptions{Level: logLevel})))
+
+	project, err := compose.Load(fs.Arg(0))
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Failed to load %s: %v\n", fs.Arg(0), err)
+		return exitFailed
+	}
+
+	// The compose project stands in for the Docker client
+	generator, err := fpkgen.NewGeneratorWithClient(project)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Failed to create generator: %v\n", err)
+		return exitFailed
+	}
+	defer generator.Close()
+
+	packages, err := project.Generate(context.Background(), generator, opts)
+	for _, pkg := range packages {
+		fmt.Printf("%s: %s %s (%s) -> %s\n", pkg.Service, pkg.Config.AppName, pkg.Config.Version, pkg.Config.Arch, pkg.Path)
+	}
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Failed to generate packages:\n%v\n", err)
+		return exitProblems
+	}
+	if len(packages) == 0 {
+		fmt.Println("No services with watchcow.enable=true")
+	}
+	return exitOK
+}
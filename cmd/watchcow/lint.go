@@ -0,0 +1,269 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"strings"
+	"text/tabwriter"
+
+	"watchcow/internal/compose"
+	"watchcow/internal/fpkgen"
+)
+
+// Exit codes of the lint and generate subcommands
+const (
+	exitOK       = 0 // No label errors (warnings are allowed)
+	exitProblems = 1 // At least one service has label errors or failed to generate
+	exitFailed   = 2 // Usage error or unreadable compose file
+)
+
+// lintResult is the lint report of one compose service
+type lintResult struct {
+	Service   string          `json:"service"`
+	Container string          `json:"container"`
+	Enabled   bool            `json:"enabled"` // watchcow.enable=true; disabled services are not installed
+	App       lintApp         `json:"app"`
+	Problems  fpkgen.Problems `json:"problems"`
+}
+
+// lintApp is the app extracted from the labels of a service
+type lintApp struct {
+	AppName     string            `json:"appname"`
+	DisplayName string            `json:"display_name"`
+	Version     string            `json:"version"`
+	Description string            `json:"desc"`
+	Maintainer  string            `json:"maintainer"`
+	Arch        string            `json:"arch"`
+	Lifecycle   string            `json:"lifecycle"`
+	TemplateSet string            `json:"template_set,omitempty"`
+	Manifest    map[string]string `json:"manifest,omitempty"`
+	Entries     []lintEntry       `json:"entries"`
+	Shares      []lintShare       `json:"shares,omitempty"`
+}
+
+type lintEntry struct {
+	Name      string   `json:"name"`
+	Title     string   `json:"title"`
+	Protocol  string   `json:"protocol"`
+	Port      string   `json:"port"`
+	Path      string   `json:"path"`
+	UIType    string   `json:"ui_type"`
+	AllUsers  bool     `json:"all_users"`
+	NoDisplay bool     `json:"no_display"`
+	FileTypes []string `json:"file_types,omitempty"`
+	Redirect  string   `json:"redirect,omitempty"`
+	Icon      string   `json:"icon"`
+}
+
+type lintShare struct {
+	Name   string `json:"name"`
+	Source string `json:"source"`
+}
+
+// runLintMode checks the watchcow labels of every service in a compose file that has any,
+// using the same extraction and validation as the daemon, and prints the result.
+func runLintMode(args []string) int {
+	fs := flag.NewFlagSet("lint", flag.ContinueOnError)
+	format := fs.String("format", "table", "Output format: table or json")
+	fs.Usage = func() {
+		fmt.Fprintln(fs.Output(), "Usage: watchcow lint [-format table|json] compose.yaml")
+		fs.PrintDefaults()
+	}
+	if err := fs.Parse(args); err != nil {
+		return exitFailed
+	}
+	if fs.NArg() != 1 || (*format != "table" && *format != "json") {
+		fs.Usage()
+		return exitFailed
+	}
+
+	// Problems are reported in the output; only unexpected failures are logged
+	slog.SetDefault(slog.New(slog.NewTextHandler(os.Stderr, &slog.HandlerOptions{Level: slog.LevelError})))
+
+	project, err := compose.Load(fs.Arg(0))
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Failed to load %s: %v\n", fs.Arg(0), err)
+		return exitFailed
+	}
+
+	results, err := lintProject(context.Background(), project)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Failed to lint %s: %v\n", fs.Arg(0), err)
+		return exitFailed
+	}
+
+	if *format == "json" {
+		enc := json.NewEncoder(os.Stdout)
+		enc.SetIndent("", "  ")
+		enc.Encode(results)
+	} else {
+		printLintTable(os.Stdout, results)
+	}
+
+	for _, r := range results {
+		if r.Problems.HasErrors() {
+			return exitProblems
+		}
+	}
+	return exitOK
+}
+
+// lintProject extracts and validates the app of each service with watchcow labels.
+// The compose project stands in for the Docker client, so extraction runs the daemon's code path.
+func lintProject(ctx context.Context, project *compose.Project) ([]lintResult, error) {
+	generator, err := fpkgen.NewGeneratorWithClient(project)
+	if err != nil {
+		return nil, err
+	}
+	defer generator.Close()
+
+	results := []lintResult{}
+	for _, s := range project.Services {
+		if !hasWatchcowLabels(s.Labels) {
+			continue
+		}
+		config, err := generator.ConfigFromContainer(ctx, s.Name)
+		if err != nil {
+			return nil, err
+		}
+		problems := fpkgen.ValidateLabels(config.Labels)
+		if problems == nil {
+			problems = fpkgen.Problems{}
+		}
+		results = append(results, lintResult{
+			Service:   s.Name,
+			Container: s.ContainerName,
+			Enabled:   fpkgen.ShouldInstall(config.Labels),
+			App:       newLintApp(config),
+			Problems:  problems,
+		})
+	}
+	return results, nil
+}
+
+// hasWatchcowLabels reports whether any label is in the watchcow namespace.
+func hasWatchcowLabels(labels map[string]string) bool {
+	for k := range labels {
+		if strings.HasPrefix(k, "watchcow.") {
+			return true
+		}
+	}
+	return false
+}
+
+func newLintApp(config *fpkgen.AppConfig) lintApp {
+	a := lintApp{
+		AppName:     config.AppName,
+		DisplayName: config.DisplayName,
+		Version:     config.Version,
+		Description: config.Description,
+		Maintainer:  config.Maintainer,
+		Arch:        config.Arch,
+		Lifecycle:   config.Lifecycle,
+		TemplateSet: config.TemplateSet,
+		Manifest:    config.Manifest,
+	}
+	for _, e := range config.Entries {
+		a.Entries = append(a.Entries, lintEntry{
+			Name:      e.Name,
+			Title:     e.Title,
+			Protocol:  e.Protocol,
+			Port:      e.Port,
+			Path:      e.Path,
+			UIType:    e.UIType,
+			AllUsers:  e.AllUsers,
+			NoDisplay: e.NoDisplay,
+			FileTypes: e.FileTypes,
+			Redirect:  e.Redirect,
+			Icon:      e.Icon,
+		})
+	}
+	for _, s := range config.Shares {
+		a.Shares = append(a.Shares, lintShare{Name: s.Name, Source: s.Source})
+	}
+	return a
+}
+
+// lintHost stands in for the NAS address, which fnOS fills in when opening an entry.
+const lintHost = "localhost"
+
+// entryURL returns the URL an entry opens, with lintHost as the host.
+func entryURL(e lintEntry) string {
+	if e.Redirect != "" {
+		return e.Redirect
+	}
+	return fmt.Sprintf("%s://%s:%s%s", e.Protocol, lintHost, e.Port, e.Path)
+}
+
+// printLintTable prints one block per service: the app, its entries and the label problems.
+func printLintTable(w io.Writer, results []lintResult) {
+	if len(results) == 0 {
+		fmt.Fprintln(w, "No services with watchcow labels")
+		return
+	}
+
+	for i, r := range results {
+		if i > 0 {
+			fmt.Fprintln(w)
+		}
+		enabled := "enabled"
+		if !r.Enabled {
+			enabled = "not enabled (watchcow.enable is not true)"
+		}
+		fmt.Fprintf(w, "Service %s (container %s): %s\n", r.Service, r.Container, enabled)
+
+		tw := tabwriter.NewWriter(w, 0, 4, 2, ' ', 0)
+		fmt.Fprintf(tw, "  App:\t%s\n", r.App.AppName)
+		fmt.Fprintf(tw, "  Display name:\t%s\n", r.App.DisplayName)
+		fmt.Fprintf(tw, "  Version:\t%s\n", r.App.Version)
+		fmt.Fprintf(tw, "  Arch:\t%s\n", r.App.Arch)
+		fmt.Fprintf(tw, "  Lifecycle:\t%s\n", r.App.Lifecycle)
+		for _, s := range r.App.Shares {
+			fmt.Fprintf(tw, "  Share:\t%s -> %s\n", s.Name, s.Source)
+		}
+		tw.Flush()
+
+		fmt.Fprintln(w)
+		tw = tabwriter.NewWriter(w, 0, 4, 2, ' ', 0)
+		fmt.Fprintln(tw, "  ENTRY\tTITLE\tURL\tUI\tUSERS\tFLAGS")
+		for _, e := range r.App.Entries {
+			name := e.Name
+			if name == "" {
+				name = "(default)"
+			}
+			users := "all"
+			if !e.AllUsers {
+				users = "admin"
+			}
+			var flags []string
+			if e.NoDisplay {
+				flags = append(flags, "no_display")
+			}
+			if len(e.FileTypes) > 0 {
+				flags = append(flags, "file_types="+strings.Join(e.FileTypes, ","))
+			}
+			if e.Redirect != "" {
+				flags = append(flags, "redirect="+e.Redirect)
+			}
+			fmt.Fprintf(tw, "  %s\t%s\t%s\t%s\t%s\t%s\n", name, e.Title, entryURL(e), e.UIType, users, strings.Join(flags, " "))
+		}
+		tw.Flush()
+
+		if len(r.Problems) == 0 {
+			fmt.Fprintln(w, "\n  No label problems")
+			continue
+		}
+		fmt.Fprintln(w)
+		tw = tabwriter.NewWriter(w, 0, 4, 2, ' ', 0)
+		fmt.Fprintln(tw, "  SEVERITY\tLABEL\tVALUE\tPROBLEM")
+		for _, p := range r.Problems {
+			fmt.Fprintf(tw, "  %s\t%s\t%q\t%s\n", p.Severity, p.Label, p.Value, p.Message)
+		}
+		tw.Flush()
+	}
+}
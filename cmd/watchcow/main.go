@@ -27,9 +27,20 @@ func getDefaultSocketPath() string {
 }
 
 func main() {
+	// Subcommands that do not need the daemon
+	if len(os.Args) > 1 {
+		switch os.Args[1] {
+		case "lint":
+			os.Exit(runLintMode(os.Args[2:]))
+		case "generate":
+			os.Exit(runGenerateMode(os.Args[2:]))
+		}
+	}
+
 	// Define flags
-	mode := flag.String("mode", "server", "Run mode: server or cgi")
+	mode := flag.String("mode", "server", "Run mode: server, cgi or status")
 	socketPath := flag.String("socket", "", "Unix socket path (default: $TRIM_PKGVAR/watchcow.sock or /tmp/watchcow/watchcow.sock)")
+	containerName := flag.String("container", "", "Container name (status mode)")
 	debug := flag.Bool("debug", false, "Enable debug mode")
 	flag.Parse()
 
@@ -44,8 +55,10 @@ func main() {
 		runServerMode(actualSocketPath, *debug)
 	case "cgi":
 		runCGIMode(actualSocketPath)
+	case "status":
+		os.Exit(runStatusMode(actualSocketPath, *containerName))
 	default:
-		fmt.Fprintf(os.Stderr, "Unknown mode: %s (use 'server' or 'cgi')\n", *mode)
+		fmt.Fprintf(os.Stderr, "Unknown mode: %s (use 'server', 'cgi' or 'status')\n", *mode)
 		os.Exit(1)
 	}
 }
@@ -55,6 +68,39 @@ func runCGIMode(socketPath string) {
 	cgi.RunCGI(socketPath)
 }
 
+// Exit codes of status mode, following the fnOS cmd/main status convention
+const (
+	statusExitRunning     = 0 // Running and healthy
+	statusExitUnavailable = 1 // Daemon unreachable; caller should fall back to the docker CLI
+	statusExitStopped     = 3 // Not running
+	statusExitUnhealthy   = 4 // Running but unhealthy or restart-looping
+)
+
+// runStatusMode reports container health from the daemon for generated cmd/main scripts.
+func runStatusMode(socketPath, containerName string) int {
+	if containerName == "" {
+		fmt.Fprintln(os.Stderr, "status mode requires --container")
+		return statusExitUnavailable
+	}
+
+	health, err := server.QueryHealth(socketPath, containerName)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "WatchCow daemon unavailable: %v\n", err)
+		return statusExitUnavailable
+	}
+
+	fmt.Printf("%s: %s (state=%s health=%s restarts=%d)\n",
+		health.Container, health.Status, health.State, health.Health, health.RestartCount)
+	switch health.Status {
+	case docker.HealthStatusRunning:
+		return statusExitRunning
+	case docker.HealthStatusUnhealthy:
+		return statusExitUnhealthy
+	default:
+		return statusExitStopped
+	}
+}
+
 // runServerMode runs the Docker monitoring daemon with HTTP server
 func runServerMode(socketPath string, debug bool) {
 	// Configure slog
@@ -108,8 +154,11 @@ func runServerMode(socketPath string, debug bool) {
 		slog.Error("Failed to create dashboard handler", "error", err)
 		os.Exit(1)
 	}
+	dashboardHandler.SetStatusProvider(monitor)
+
+	healthHandler := server.NewHealthHandler(monitor)
 
-	router := server.NewRouter(redirectHandler, dashboardHandler)
+	router := server.NewRouter(redirectHandler, healthHandler, dashboardHandler)
 
 	// Step 4: Create server with monitor injected
 	srv := server.New(socketPath, router, monitor)
@@ -7,6 +7,7 @@ require (
 	github.com/docker/go-connections v0.4.0
 	github.com/go-chi/chi/v5 v5.2.4
 	golang.org/x/image v0.33.0
+	gopkg.in/yaml.v3 v3.0.1
 )
 
 require (
@@ -4,15 +4,29 @@ package app
 
 import "sync"
 
+// Lifecycle modes (watchcow.lifecycle label)
+const (
+	LifecycleDocker        = "docker"        // Docker drives fnOS; fnOS start/stop are no-ops
+	LifecycleBidirectional = "bidirectional" // fnOS start/stop also start/stop the container
+)
+
+// Package architectures (fnOS manifest arch)
+const (
+	ArchX86_64 = "x86_64"
+	ArchARM64  = "arm64"
+)
+
 // Status represents the current state of an app
 type Status string
 
 const (
-	StatusPending     Status = "pending"     // Waiting to be installed
-	StatusInstalled   Status = "installed"   // Installed but not running
-	StatusRunning     Status = "running"     // Running
-	StatusStopped     Status = "stopped"     // Stopped
-	StatusUninstalled Status = "uninstalled" // Uninstalled
+	StatusPending         Status = "pending"          // Waiting to be installed
+	StatusInstalled       Status = "installed"        // Installed but not running
+	StatusRunning         Status = "running"          // Running
+	StatusStopped         Status = "stopped"          // Stopped
+	StatusUninstalled     Status = "uninstalled"      // Uninstalled
+	StatusUninstallFailed Status = "uninstall_failed" // Uninstall failed, app still installed
+	StatusInstallFailed   Status = "install_failed"   // Not installed: invalid labels, container not ready or install failed
 )
 
 // EntryControl represents permission settings for an entry
@@ -63,6 +77,20 @@ type VolumeMapping struct {
 	Type        string // "bind" or "volume"
 }
 
+// DataShare is an fnOS data-share bound to a container folder
+type DataShare struct {
+	Name   string // Share name in config/resource
+	Source string // Bind mount source on the host, mounted onto the share
+}
+
+// WizardConfig holds the install and uninstall wizards of the fnOS package
+type WizardConfig struct {
+	InstallTips        string   // Notice shown by the install wizard
+	RemoveContainer    bool     // Uninstall wizard offers removing the container
+	ConfirmDataRemoval bool     // Uninstall wizard offers removing the container and its bind-mounted data
+	DataPaths          []string // Bind mount sources removed when data removal is confirmed
+}
+
 // App represents a watchcow-managed application.
 // This is the core model parsed from container labels, used for both
 // package generation (fpkgen) and runtime management (monitor/server).
@@ -91,6 +119,10 @@ type App struct {
 
 	// Volumes
 	Volumes []VolumeMapping
+	Shares  []DataShare // Volumes exposed as fnOS data-shares
+
+	// Install and uninstall wizards
+	Wizard WizardConfig
 
 	// Environment
 	Environment []string
@@ -98,6 +130,12 @@ type App struct {
 	// Metadata
 	Icon          string // Icon source: URL (file:// or http://) from labels, or base64 data from dashboard
 	RestartPolicy string
+	Lifecycle     string // LifecycleDocker or LifecycleBidirectional
+	Arch          string // ArchX86_64 or ArchARM64; empty means the host architecture
+	TemplateSet   string // Named template set from the override directory; empty means default
+
+	// Extra fnOS manifest fields (key -> value), see fpkgen.ManifestKeys
+	Manifest map[string]string
 
 	// Labels (original watchcow labels for reference)
 	Labels map[string]string
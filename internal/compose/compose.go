@@ -0,0 +1,399 @@
+// Package compose reads Docker Compose files and presents their services as
+// inspected containers, so package generation and label checks can run on a
+// compose file without a Docker daemon.
+package compose
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	dockercontainer "github.com/docker/docker/api/types/container"
+	"github.com/docker/docker/api/types/mount"
+	"github.com/docker/go-connections/nat"
+	"gopkg.in/yaml.v3"
+)
+
+// Labels Docker Compose adds to every container it creates
+const (
+	LabelProject    = "com.docker.compose.project"
+	LabelService    = "com.docker.compose.service"
+	LabelWorkingDir = "com.docker.compose.project.working_dir"
+)
+
+// ErrServiceNotFound is returned when a project has no service with the requested name.
+var ErrServiceNotFound = errors.New("service not found")
+
+// Project is a parsed compose file.
+type Project struct {
+	Name     string     // Project name: the top-level name, or the directory name
+	Dir      string     // Absolute directory of the compose file
+	Services []*Service // Sorted by name
+}
+
+// Service is a compose service with the settings package generation uses.
+type Service struct {
+	Name          string
+	ContainerName string // container_name, or "<project>-<service>-1" as Compose names it
+	Image         string
+	Restart       string
+	Labels        map[string]string
+	Environment   []string // KEY=value
+	ExposedPorts  nat.PortSet
+	PortBindings  nat.PortMap
+	Mounts        []dockercontainer.MountPoint // Bind sources are absolute
+}
+
+// composeFile is the subset of the compose file format WatchCow reads
+type composeFile struct {
+	Name     string                     `yaml:"name"`
+	Services map[string]*composeService `yaml:"services"`
+}
+
+type composeService struct {
+	Image         string        `yaml:"image"`
+	ContainerName string        `yaml:"container_name"`
+	Restart       string        `yaml:"restart"`
+	Labels        mappingOrList `yaml:"labels"`
+	Environment   mappingOrList `yaml:"environment"`
+	Ports         []portEntry   `yaml:"ports"`
+	Volumes       []volumeEntry `yaml:"volumes"`
+}
+
+// Load reads and parses a compose file.
+func Load(path string) (*Project, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	absPath, err := filepath.Abs(path)
+	if err != nil {
+		return nil, err
+	}
+	return Parse(data, filepath.Dir(absPath))
+}
+
+// Parse parses compose file contents. dir is the directory of the compose file:
+// relative bind mount sources are resolved against it, and variables are taken
+// from the shell environment and the .env file in it.
+func Parse(data []byte, dir string) (*Project, error) {
+	var root yaml.Node
+	if err := yaml.Unmarshal(data, &root); err != nil {
+		return nil, fmt.Errorf("invalid compose file: %w", err)
+	}
+	lookup, err := envLookup(dir)
+	if err != nil {
+		return nil, err
+	}
+	if err := interpolateNode(&root, lookup); err != nil {
+		return nil, fmt.Errorf("invalid compose file: %w", err)
+	}
+
+	var f composeFile
+	if err := root.Decode(&f); err != nil {
+		return nil, fmt.Errorf("invalid compose file: %w", err)
+	}
+	if len(f.Services) == 0 {
+		return nil, fmt.Errorf("invalid compose file: no services")
+	}
+
+	name := f.Name
+	if name == "" {
+		name = filepath.Base(dir)
+	}
+	p := &Project{Name: normalizeProjectName(name), Dir: dir}
+	if p.Name == "" {
+		return nil, fmt.Errorf("invalid project name: %q", name)
+	}
+
+	for serviceName, cs := range f.Services {
+		if cs == nil {
+			cs = &composeService{}
+		}
+		s, err := p.newService(serviceName, cs)
+		if err != nil {
+			return nil, fmt.Errorf("service %s: %w", serviceName, err)
+		}
+		p.Services = append(p.Services, s)
+	}
+	sort.Slice(p.Services, func(i, j int) bool {
+		return p.Services[i].Name < p.Services[j].Name
+	})
+	return p, nil
+}
+
+// newService converts a parsed compose service.
+func (p *Project) newService(name string, cs *composeService) (*Service, error) {
+	s := &Service{
+		Name:          name,
+		ContainerName: cs.ContainerName,
+		Image:         cs.Image,
+		Restart:       cs.Restart,
+		Labels:        make(map[string]string),
+	}
+	if s.ContainerName == "" {
+		s.ContainerName = p.Name + "-" + name + "-1"
+	}
+
+	for k, v := range cs.Labels {
+		if v != nil {
+			s.Labels[k] = *v
+		}
+	}
+
+	for k, v := range cs.Environment {
+		if v == nil {
+			// "KEY" without a value is taken from the shell, as Compose does
+			value, ok := os.LookupEnv(k)
+			if !ok {
+				continue
+			}
+			v = &value
+		}
+		s.Environment = append(s.Environment, k+"="+*v)
+	}
+	sort.Strings(s.Environment)
+
+	var specs []string
+	for _, port := range cs.Ports {
+		specs = append(specs, port.spec)
+	}
+	exposed, bindings, err := nat.ParsePortSpecs(specs)
+	if err != nil {
+		return nil, fmt.Errorf("invalid ports: %w", err)
+	}
+	s.ExposedPorts, s.PortBindings = exposed, bindings
+
+	for _, v := range cs.Volumes {
+		m, err := p.mountPoint(v)
+		if err != nil {
+			return nil, err
+		}
+		s.Mounts = append(s.Mounts, m)
+	}
+	return s, nil
+}
+
+// mountPoint converts a volume entry to the mount Docker would report.
+func (p *Project) mountPoint(v volumeEntry) (dockercontainer.MountPoint, error) {
+	if v.Target == "" {
+		return dockercontainer.MountPoint{}, fmt.Errorf("invalid volume: missing target")
+	}
+	m := dockercontainer.MountPoint{
+		Type:        mount.Type(v.Type),
+		Destination: v.Target,
+		RW:          !v.ReadOnly,
+	}
+	switch m.Type {
+	case mount.TypeBind:
+		source := v.Source
+		if home, ok := strings.CutPrefix(source, "~"); ok {
+			dir, err := os.UserHomeDir()
+			if err != nil {
+				return dockercontainer.MountPoint{}, err
+			}
+			source = dir + home
+		}
+		if !filepath.IsAbs(source) {
+			source = filepath.Join(p.Dir, source)
+		}
+		m.Source = filepath.Clean(source)
+	case mount.TypeVolume:
+		if v.Source != "" {
+			m.Name = p.Name + "_" + v.Source
+		}
+	}
+	return m, nil
+}
+
+// Service returns the service with the given service or container name.
+func (p *Project) Service(name string) (*Service, error) {
+	for _, s := range p.Services {
+		if s.Name == name || s.ContainerName == name {
+			return s, nil
+		}
+	}
+	return nil, fmt.Errorf("%w: %s", ErrServiceNotFound, name)
+}
+
+// ContainerInspect returns the container Docker Compose would create for a service,
+// as Docker would report it. containerID is a service or container name.
+// It lets a compose project stand in for the Docker client of fpkgen.Generator.
+func (p *Project) ContainerInspect(ctx context.Context, containerID string) (dockercontainer.InspectResponse, error) {
+	s, err := p.Service(containerID)
+	if err != nil {
+		return dockercontainer.InspectResponse{}, err
+	}
+	return p.inspect(s), nil
+}
+
+// inspect builds the inspect response of a service container.
+// The container ID is derived from the project and service name, so it is stable.
+func (p *Project) inspect(s *Service) dockercontainer.InspectResponse {
+	labels := make(map[string]string, len(s.Labels)+3)
+	for k, v := range s.Labels {
+		labels[k] = v
+	}
+	labels[LabelProject] = p.Name
+	labels[LabelService] = s.Name
+	labels[LabelWorkingDir] = p.Dir
+
+	id := sha256.Sum256([]byte(p.Name + "/" + s.Name))
+	return dockercontainer.InspectResponse{
+		ContainerJSONBase: &dockercontainer.ContainerJSONBase{
+			ID:    hex.EncodeToString(id[:]),
+			Name:  "/" + s.ContainerName,
+			Image: s.Image,
+			HostConfig: &dockercontainer.HostConfig{
+				PortBindings:  s.PortBindings,
+				RestartPolicy: dockercontainer.RestartPolicy{Name: dockercontainer.RestartPolicyMode(s.Restart)},
+			},
+		},
+		Mounts: s.Mounts,
+		Config: &dockercontainer.Config{
+			Image:        s.Image,
+			Labels:       labels,
+			Env:          s.Environment,
+			ExposedPorts: s.ExposedPorts,
+		},
+	}
+}
+
+// normalizeProjectName applies the Compose project name rules:
+// lowercase letters, digits, dashes and underscores, starting with a letter or digit.
+func normalizeProjectName(name string) string {
+	var b strings.Builder
+	for _, c := range strings.ToLower(name) {
+		if (c >= 'a' && c <= 'z') || (c >= '0' && c <= '9') || c == '-' || c == '_' {
+			b.WriteRune(c)
+		}
+	}
+	return strings.TrimLeft(b.String(), "-_")
+}
+
+// mappingOrList is a compose field given either as a mapping or as a list of KEY=value.
+// A key without a value maps to nil.
+type mappingOrList map[string]*string
+
+func (m *mappingOrList) UnmarshalYAML(node *yaml.Node) error {
+	result := make(mappingOrList)
+	switch node.Kind {
+	case yaml.MappingNode:
+		for i := 0; i+1 < len(node.Content); i += 2 {
+			key, value := node.Content[i], node.Content[i+1]
+			if value.Kind != yaml.ScalarNode {
+				return fmt.Errorf("line %d: value of %s must be a scalar", value.Line, key.Value)
+			}
+			if value.Tag == "!!null" {
+				result[key.Value] = nil
+				continue
+			}
+			v := value.Value
+			result[key.Value] = &v
+		}
+	case yaml.SequenceNode:
+		for _, item := range node.Content {
+			if item.Kind != yaml.ScalarNode {
+				return fmt.Errorf("line %d: list entries must be KEY=value", item.Line)
+			}
+			key, value, ok := strings.Cut(item.Value, "=")
+			if !ok {
+				result[key] = nil
+				continue
+			}
+			result[key] = &value
+		}
+	default:
+		return fmt.Errorf("line %d: must be a mapping or a list", node.Line)
+	}
+	*m = result
+	return nil
+}
+
+// portEntry is a ports entry in short ("8080:80/tcp") or long syntax,
+// converted to a docker run -p spec.
+type portEntry struct {
+	spec string
+}
+
+func (p *portEntry) UnmarshalYAML(node *yaml.Node) error {
+	if node.Kind == yaml.ScalarNode {
+		p.spec = node.Value
+		return nil
+	}
+
+	var long struct {
+		Target    string `yaml:"target"`
+		Published string `yaml:"published"`
+		HostIP    string `yaml:"host_ip"`
+		Protocol  string `yaml:"protocol"`
+	}
+	if err := node.Decode(&long); err != nil {
+		return err
+	}
+	if long.Target == "" {
+		return fmt.Errorf("line %d: port is missing target", node.Line)
+	}
+	spec := long.Target
+	if long.Published != "" {
+		spec = long.Published + ":" + spec
+		if long.HostIP != "" {
+			spec = long.HostIP + ":" + spec
+		}
+	}
+	if long.Protocol != "" {
+		spec += "/" + long.Protocol
+	}
+	p.spec = spec
+	return nil
+}
+
+// volumeEntry is a volumes entry in short ("./data:/data:ro") or long syntax.
+type volumeEntry struct {
+	Type     string `yaml:"type"`
+	Source   string `yaml:"source"`
+	Target   string `yaml:"target"`
+	ReadOnly bool   `yaml:"read_only"`
+}
+
+func (v *volumeEntry) UnmarshalYAML(node *yaml.Node) error {
+	if node.Kind != yaml.ScalarNode {
+		type plain volumeEntry
+		if err := node.Decode((*plain)(v)); err != nil {
+			return err
+		}
+		if v.Type == "" {
+			v.Type = string(mount.TypeVolume)
+		}
+		return nil
+	}
+
+	parts := strings.Split(node.Value, ":")
+	switch len(parts) {
+	case 1:
+		v.Target = parts[0]
+	case 2, 3:
+		v.Source, v.Target = parts[0], parts[1]
+		if len(parts) == 3 {
+			for _, opt := range strings.Split(parts[2], ",") {
+				if opt == "ro" {
+					v.ReadOnly = true
+				}
+			}
+		}
+	default:
+		return fmt.Errorf("line %d: invalid volume %q", node.Line, node.Value)
+	}
+
+	v.Type = string(mount.TypeVolume)
+	if strings.HasPrefix(v.Source, "/") || strings.HasPrefix(v.Source, ".") || strings.HasPrefix(v.Source, "~") {
+		v.Type = string(mount.TypeBind)
+	}
+	return nil
+}
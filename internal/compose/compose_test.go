@@ -0,0 +1,147 @@
+package compose
+
+import (
+	"context"
+	"errors"
+	"path/filepath"
+	"slices"
+	"testing"
+
+	"github.com/docker/docker/api/types/mount"
+
+	"watchcow/internal/fpkgen"
+)
+
+const testCompose = `
+services:
+  web:
+    image: nginx:latest
+    restart: always
+    ports:
+      - "127.0.0.1:8080:80/tcp"
+      - target: 443
+        published: 8443
+    volumes:
+      - ./html:/usr/share/nginx/html:ro
+      - cache:/var/cache/nginx
+      - type: bind
+        source: /srv/logs
+        target: /var/log/nginx
+    environment:
+      TZ: Asia/Shanghai
+      EMPTY:
+    labels:
+      watchcow.enable: true
+      watchcow.service_port: 8080
+      watchcow.share_binds: true
+  worker:
+    image: busybox
+    container_name: my-worker
+    environment:
+      - MODE=worker
+    labels:
+      - watchcow.enable=false
+`
+
+func TestParse(t *testing.T) {
+	p, err := Parse([]byte(testCompose), "/opt/My Stack")
+	if err != nil {
+		t.Fatalf("Parse() error = %v", err)
+	}
+	if p.Name != "mystack" {
+		t.Errorf("Name = %q, want mystack", p.Name)
+	}
+	if len(p.Services) != 2 || p.Services[0].Name != "web" || p.Services[1].Name != "worker" {
+		t.Fatalf("Services = %v, want web and worker", p.Services)
+	}
+
+	web := p.Services[0]
+	if web.ContainerName != "mystack-web-1" {
+		t.Errorf("ContainerName = %q, want mystack-web-1", web.ContainerName)
+	}
+	if web.Labels["watchcow.enable"] != "true" || web.Labels["watchcow.service_port"] != "8080" {
+		t.Errorf("Labels = %v", web.Labels)
+	}
+	if !slices.Equal(web.Environment, []string{"TZ=Asia/Shanghai"}) {
+		t.Errorf("Environment = %v, want only TZ", web.Environment)
+	}
+	if b := web.PortBindings["80/tcp"]; len(b) != 1 || b[0].HostIP != "127.0.0.1" || b[0].HostPort != "8080" {
+		t.Errorf("PortBindings[80/tcp] = %v", b)
+	}
+	if b := web.PortBindings["443/tcp"]; len(b) != 1 || b[0].HostPort != "8443" {
+		t.Errorf("PortBindings[443/tcp] = %v", b)
+	}
+
+	if len(web.Mounts) != 3 {
+		t.Fatalf("Mounts = %v, want 3", web.Mounts)
+	}
+	if m := web.Mounts[0]; m.Type != mount.TypeBind || m.Source != "/opt/My Stack/html" || m.RW {
+		t.Errorf("Mounts[0] = %+v, want read-only bind of /opt/My Stack/html", m)
+	}
+	if m := web.Mounts[1]; m.Type != mount.TypeVolume || m.Name != "mystack_cache" {
+		t.Errorf("Mounts[1] = %+v, want volume mystack_cache", m)
+	}
+	if m := web.Mounts[2]; m.Type != mount.TypeBind || m.Source != "/srv/logs" || !m.RW {
+		t.Errorf("Mounts[2] = %+v, want read-write bind of /srv/logs", m)
+	}
+
+	worker := p.Services[1]
+	if worker.ContainerName != "my-worker" || worker.Labels["watchcow.enable"] != "false" {
+		t.Errorf("worker = %+v", worker)
+	}
+	if !slices.Equal(worker.Environment, []string{"MODE=worker"}) {
+		t.Errorf("worker Environment = %v", worker.Environment)
+	}
+}
+
+func TestParse_Invalid(t *testing.T) {
+	for name, data := range map[string]string{
+		"no services":      "name: x\n",
+		"bad yaml":         "services: [",
+		"bad port":         "services:\n  web:\n    ports: [\"80:abc\"]\n",
+		"bad labels":       "services:\n  web:\n    labels: 1\n",
+		"volume no target": "services:\n  web:\n    volumes:\n      - type: bind\n        source: /x\n",
+	} {
+		if _, err := Parse([]byte(data), "/opt/app"); err == nil {
+			t.Errorf("%s: Parse() error = nil", name)
+		}
+	}
+}
+
+func TestContainerInspect_Extraction(t *testing.T) {
+	dir := t.TempDir()
+	p, err := Parse([]byte(testCompose), dir)
+	if err != nil {
+		t.Fatalf("Parse() error = %v", err)
+	}
+
+	if _, err := p.ContainerInspect(context.Background(), "missing"); !errors.Is(err, ErrServiceNotFound) {
+		t.Errorf("ContainerInspect(missing) error = %v, want ErrServiceNotFound", err)
+	}
+
+	g, err := fpkgen.NewGeneratorWithClient(p)
+	if err != nil {
+		t.Fatalf("NewGeneratorWithClient() error = %v", err)
+	}
+	config, err := g.ConfigFromContainer(context.Background(), "web")
+	if err != nil {
+		t.Fatalf("ConfigFromContainer() error = %v", err)
+	}
+
+	if config.ContainerName != p.Services[0].ContainerName || config.AppName != fpkgen.AppNameFromLabels(nil, config.ContainerName) {
+		t.Errorf("AppName = %q, ContainerName = %q", config.AppName, config.ContainerName)
+	}
+	if config.Port != "8080" || config.RestartPolicy != "always" || config.Image != "nginx:latest" {
+		t.Errorf("config = %+v", config)
+	}
+	if config.Labels[LabelWorkingDir] != dir || config.Labels[LabelService] != "web" {
+		t.Errorf("compose labels = %v", config.Labels)
+	}
+	wantShare := []fpkgen.DataShare{
+		{Name: "html", Source: filepath.Join(dir, "html")},
+		{Name: "logs", Source: "/srv/logs"},
+	}
+	if !slices.Equal(config.Shares, wantShare) {
+		t.Errorf("Shares = %v, want %v", config.Shares, wantShare)
+	}
+}
@@ -0,0 +1,109 @@
+package compose
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"watchcow/internal/fpkgen"
+)
+
+// GenerateOptions control where and how Generate writes packages.
+type GenerateOptions struct {
+	OutputDir string
+	Pack      bool   // Write .fpk files instead of app directories
+	Arch      string // Arch of services without watchcow.arch; empty means the host arch
+	Offline   bool   // Use the default icon instead of fetching http(s) icons
+}
+
+// Package is an fnOS package generated from a compose service.
+type Package struct {
+	Service string
+	Config  *fpkgen.AppConfig
+	Path    string // App directory, or .fpk file when packed
+}
+
+// Generate writes the fnOS package of every service enabled with watchcow.enable
+// into opts.OutputDir: the app tree as <OutputDir>/<appname>, or <OutputDir>/<appname>.fpk
+// if opts.Pack is set. Services run through the same extraction as containers the daemon
+// sees; relative file:// icons are resolved against the compose directory.
+//
+// Like the daemon, a service with label errors is not generated. The other services
+// are still generated; the returned error lists every failed service.
+func (p *Project) Generate(ctx context.Context, generator *fpkgen.Generator, opts GenerateOptions) ([]Package, error) {
+	var packages []Package
+	var errs []error
+	for _, s := range p.Services {
+		if !fpkgen.ShouldInstall(s.Labels) {
+			slog.Debug("Skipping service without watchcow.enable", "service", s.Name)
+			continue
+		}
+		pkg, err := p.generateService(ctx, generator, s, opts)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("service %s: %w", s.Name, err))
+			continue
+		}
+		packages = append(packages, *pkg)
+	}
+	return packages, errors.Join(errs...)
+}
+
+// generateService writes the package of one service.
+func (p *Project) generateService(ctx context.Context, generator *fpkgen.Generator, s *Service, opts GenerateOptions) (*Package, error) {
+	config, err := generator.ConfigFromContainer(ctx, s.Name)
+	if err != nil {
+		return nil, err
+	}
+	if err := fpkgen.ValidateLabels(config.Labels).Err(); err != nil {
+		return nil, err
+	}
+	if opts.Arch != "" && config.Labels["watchcow.arch"] == "" {
+		config.Arch = opts.Arch
+	}
+	if opts.Offline {
+		dropRemoteIcons(config)
+	}
+
+	pkg := &Package{Service: s.Name, Config: config}
+	if !opts.Pack {
+		pkg.Path = filepath.Join(opts.OutputDir, config.AppName)
+		if err := generator.GenerateFromConfig(config, pkg.Path); err != nil {
+			return nil, err
+		}
+		return pkg, nil
+	}
+
+	appDir, err := generator.GenerateToTempDir(config)
+	if err != nil {
+		return nil, err
+	}
+	defer os.RemoveAll(appDir)
+
+	pkg.Path = filepath.Join(opts.OutputDir, fpkgen.PackageFileName(config.AppName))
+	if err := fpkgen.WritePackage(appDir, pkg.Path); err != nil {
+		return nil, err
+	}
+	return pkg, nil
+}
+
+// dropRemoteIcons clears the http(s) icons of config, including the CDN icons derived
+// from the image name, so the default icon is used without network access.
+func dropRemoteIcons(config *fpkgen.AppConfig) {
+	isRemote := func(icon string) bool {
+		return strings.HasPrefix(icon, "http://") || strings.HasPrefix(icon, "https://")
+	}
+	if isRemote(config.Icon) {
+		slog.Info("Offline, using default icon", "app", config.AppName, "icon", config.Icon)
+		config.Icon = ""
+	}
+	for i, e := range config.Entries {
+		if isRemote(e.Icon) {
+			slog.Info("Offline, using default icon", "app", config.AppName, "entry", e.Name, "icon", e.Icon)
+			config.Entries[i].Icon = ""
+		}
+	}
+}
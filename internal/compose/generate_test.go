@@ -0,0 +1,146 @@
+package compose
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync/atomic"
+	"testing"
+
+	"watchcow/internal/fpkgen"
+)
+
+func TestGenerate(t *testing.T) {
+	dir := t.TempDir()
+	if err := os.Mkdir(filepath.Join(dir, "icons"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	icon, err := os.ReadFile(filepath.Join("..", "fpkgen", "testdata", "test.png"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, "icons", "web.png"), icon, 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	data := `
+name: demo
+services:
+  web:
+    image: nginx
+    ports: ["8080:80"]
+    labels:
+      watchcow.enable: "true"
+      watchcow.appname: watchcow.web
+      watchcow.icon: file://icons/web.png
+  broken:
+    image: nginx
+    labels:
+      watchcow.enable: "true"
+      watchcow.all_users: "yes"
+  db:
+    image: postgres
+    labels:
+      watchcow.enable: "false"
+`
+	p, err := Parse([]byte(data), dir)
+	if err != nil {
+		t.Fatalf("Parse() error = %v", err)
+	}
+	g, err := fpkgen.NewGeneratorWithClient(p)
+	if err != nil {
+		t.Fatalf("NewGeneratorWithClient() error = %v", err)
+	}
+
+	out := t.TempDir()
+	packages, err := p.Generate(context.Background(), g, GenerateOptions{OutputDir: out, Arch: "arm64"})
+	if err == nil || !strings.Contains(err.Error(), "service broken") {
+		t.Errorf("Generate() error = %v, want error for service broken", err)
+	}
+	if len(packages) != 1 || packages[0].Service != "web" {
+		t.Fatalf("Generate() = %v, want only web", packages)
+	}
+
+	appDir := filepath.Join(out, "watchcow.web")
+	if packages[0].Path != appDir {
+		t.Errorf("Path = %q, want %q", packages[0].Path, appDir)
+	}
+	manifest, err := os.ReadFile(filepath.Join(appDir, "manifest"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(string(manifest), "arch=arm64") {
+		t.Errorf("manifest = %s, want arch=arm64", manifest)
+	}
+
+	// The relative icon is resolved against the compose directory: without it the default icon is used
+	withIcon, err := os.ReadFile(filepath.Join(appDir, "ICON_256.PNG"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := os.RemoveAll(filepath.Join(dir, "icons")); err != nil {
+		t.Fatal(err)
+	}
+	fallbackOut := t.TempDir()
+	p.Generate(context.Background(), g, GenerateOptions{OutputDir: fallbackOut})
+	withoutIcon, err := os.ReadFile(filepath.Join(fallbackOut, "watchcow.web", "ICON_256.PNG"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(withIcon) == string(withoutIcon) {
+		t.Error("ICON_256.PNG is the default icon, want the icon from the compose directory")
+	}
+
+	packed, err := p.Generate(context.Background(), g, GenerateOptions{OutputDir: out, Pack: true})
+	if len(packed) != 1 || err == nil {
+		t.Fatalf("Generate(Pack) = %v, %v", packed, err)
+	}
+	if want := filepath.Join(out, "watchcow.web.fpk"); packed[0].Path != want {
+		t.Errorf("Path = %q, want %q", packed[0].Path, want)
+	}
+	if _, err := os.Stat(packed[0].Path); err != nil {
+		t.Errorf("package missing: %v", err)
+	}
+}
+
+func TestGenerate_OfflineSkipsRemoteIcons(t *testing.T) {
+	var requests atomic.Int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests.Add(1)
+		http.NotFound(w, r)
+	}))
+	defer srv.Close()
+
+	data := `
+services:
+  web:
+    image: nginx
+    labels:
+      watchcow.enable: "true"
+      watchcow.appname: watchcow.web
+      watchcow.icon: ` + srv.URL + `/web.png
+      watchcow.admin.port: "9000"
+`
+	p, err := Parse([]byte(data), t.TempDir())
+	if err != nil {
+		t.Fatalf("Parse() error = %v", err)
+	}
+	g, err := fpkgen.NewGeneratorWithClient(p)
+	if err != nil {
+		t.Fatalf("NewGeneratorWithClient() error = %v", err)
+	}
+
+	packages, err := p.Generate(context.Background(), g, GenerateOptions{OutputDir: t.TempDir(), Offline: true})
+	if err != nil || len(packages) != 1 {
+		t.Fatalf("Generate() = %v, %v", packages, err)
+	}
+	if n := requests.Load(); n != 0 {
+		t.Errorf("offline generate fetched %d icons", n)
+	}
+	if _, err := os.Stat(filepath.Join(packages[0].Path, "ICON_256.PNG")); err != nil {
+		t.Errorf("default icon missing: %v", err)
+	}
+}
@@ -0,0 +1,192 @@
+package compose
+
+import (
+	"bufio"
+	"fmt"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// envFileName is the file next to the compose file that provides interpolation variables
+const envFileName = ".env"
+
+// lookupFunc returns the value of a variable and whether it is set
+type lookupFunc func(name string) (string, bool)
+
+// envLookup returns a lookup over the shell environment and the .env file in dir.
+// Shell variables take precedence over the .env file, as in Compose.
+func envLookup(dir string) (lookupFunc, error) {
+	fileEnv, err := readEnvFile(filepath.Join(dir, envFileName))
+	if err != nil && !os.IsNotExist(err) {
+		return nil, fmt.Errorf("failed to read %s: %w", envFileName, err)
+	}
+	return func(name string) (string, bool) {
+		if v, ok := os.LookupEnv(name); ok {
+			return v, true
+		}
+		v, ok := fileEnv[name]
+		return v, ok
+	}, nil
+}
+
+// readEnvFile parses a .env file: KEY=value lines, optionally prefixed with
+// "export" and with the value in single or double quotes. # starts a comment line.
+func readEnvFile(path string) (map[string]string, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	env := make(map[string]string)
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		line = strings.TrimPrefix(line, "export ")
+		key, value, ok := strings.Cut(line, "=")
+		if !ok {
+			continue
+		}
+		value = strings.TrimSpace(value)
+		if len(value) >= 2 && (value[0] == '"' || value[0] == '\'') && value[len(value)-1] == value[0] {
+			value = value[1 : len(value)-1]
+		}
+		env[strings.TrimSpace(key)] = value
+	}
+	return env, scanner.Err()
+}
+
+// interpolateNode substitutes variables in all scalar values below node.
+// Mapping keys are left as they are.
+func interpolateNode(node *yaml.Node, lookup lookupFunc) error {
+	switch node.Kind {
+	case yaml.ScalarNode:
+		if !strings.Contains(node.Value, "$") {
+			return nil
+		}
+		v, err := interpolate(node.Value, lookup)
+		if err != nil {
+			return fmt.Errorf("line %d: %w", node.Line, err)
+		}
+		node.Value = v
+	case yaml.MappingNode:
+		for i := 1; i < len(node.Content); i += 2 {
+			if err := interpolateNode(node.Content[i], lookup); err != nil {
+				return err
+			}
+		}
+	default:
+		for _, child := range node.Content {
+			if err := interpolateNode(child, lookup); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// interpolate substitutes variables in s using the Compose syntax:
+// $VAR, ${VAR}, ${VAR:-default}, ${VAR-default}, ${VAR:?error}, ${VAR?error}
+// and $$ for a literal $. Unset variables without a default become empty.
+func interpolate(s string, lookup lookupFunc) (string, error) {
+	var b strings.Builder
+	for i := 0; i < len(s); i++ {
+		if s[i] != '$' || i+1 == len(s) {
+			b.WriteByte(s[i])
+			continue
+		}
+
+		next := s[i+1]
+		switch {
+		case next == '$':
+			b.WriteByte('$')
+			i++
+		case next == '{':
+			end := strings.IndexByte(s[i+2:], '}')
+			if end < 0 {
+				return "", fmt.Errorf("unterminated variable in %q", s)
+			}
+			v, err := expandBraced(s[i+2:i+2+end], lookup)
+			if err != nil {
+				return "", err
+			}
+			b.WriteString(v)
+			i += 2 + end
+		case isNameChar(next, true):
+			end := i + 1
+			for end < len(s) && isNameChar(s[end], false) {
+				end++
+			}
+			b.WriteString(lookupOrEmpty(s[i+1:end], lookup))
+			i = end - 1
+		default:
+			b.WriteByte('$')
+		}
+	}
+	return b.String(), nil
+}
+
+// expandBraced expands the contents of ${...}.
+func expandBraced(expr string, lookup lookupFunc) (string, error) {
+	name, op, arg := expr, "", ""
+	for i := 0; i < len(expr); i++ {
+		if isNameChar(expr[i], i == 0) {
+			continue
+		}
+		name, op = expr[:i], expr[i:i+1]
+		if op == ":" && i+1 < len(expr) {
+			op = expr[i : i+2]
+		}
+		arg = expr[i+len(op):]
+		break
+	}
+	if name == "" {
+		return "", fmt.Errorf("invalid variable ${%s}", expr)
+	}
+
+	value, set := lookup(name)
+	switch op {
+	case "":
+		return lookupOrEmpty(name, lookup), nil
+	case ":-":
+		if !set || value == "" {
+			return arg, nil
+		}
+	case "-":
+		if !set {
+			return arg, nil
+		}
+	case ":?":
+		if !set || value == "" {
+			return "", fmt.Errorf("required variable %s is missing a value: %s", name, arg)
+		}
+	case "?":
+		if !set {
+			return "", fmt.Errorf("required variable %s is missing a value: %s", name, arg)
+		}
+	default:
+		return "", fmt.Errorf("invalid variable ${%s}", expr)
+	}
+	return value, nil
+}
+
+// lookupOrEmpty returns the value of a variable, or "" with a warning if it is unset.
+func lookupOrEmpty(name string, lookup lookupFunc) string {
+	value, ok := lookup(name)
+	if !ok {
+		slog.Warn("Variable is not set, using an empty string", "variable", name)
+	}
+	return value
+}
+
+// isNameChar reports whether c can appear in a variable name; digits cannot start one.
+func isNameChar(c byte, first bool) bool {
+	return c == '_' || (c >= 'a' && c <= 'z') || (c >= 'A' && c <= 'Z') || (!first && c >= '0' && c <= '9')
+}
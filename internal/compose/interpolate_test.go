@@ -0,0 +1,86 @@
+package compose
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestInterpolate(t *testing.T) {
+	env := map[string]string{"PORT": "8080", "EMPTY": ""}
+	lookup := func(name string) (string, bool) {
+		v, ok := env[name]
+		return v, ok
+	}
+
+	tests := []struct {
+		in, want string
+	}{
+		{"plain", "plain"},
+		{"$PORT", "8080"},
+		{"${PORT}/tcp", "8080/tcp"},
+		{"$$PORT", "$PORT"},
+		{"${MISSING:-80}", "80"},
+		{"${EMPTY:-80}", "80"},
+		{"${EMPTY-80}", ""},
+		{"${PORT:-80}", "8080"},
+		{"${MISSING}", ""},
+		{"price: 5$", "price: 5$"},
+		{"${PORT:?port required}", "8080"},
+	}
+	for _, tt := range tests {
+		got, err := interpolate(tt.in, lookup)
+		if err != nil {
+			t.Errorf("interpolate(%q) error = %v", tt.in, err)
+			continue
+		}
+		if got != tt.want {
+			t.Errorf("interpolate(%q) = %q, want %q", tt.in, got, tt.want)
+		}
+	}
+
+	for _, in := range []string{"${MISSING:?port required}", "${EMPTY:?x}", "${PORT", "${}"} {
+		if _, err := interpolate(in, lookup); err == nil {
+			t.Errorf("interpolate(%q) error = nil", in)
+		}
+	}
+}
+
+func TestParse_Interpolation(t *testing.T) {
+	dir := t.TempDir()
+	envFile := "# ports\nexport WEB_PORT=8088\nTITLE=\"My Web\"\nIMAGE_TAG=from-file\n"
+	if err := os.WriteFile(filepath.Join(dir, envFileName), []byte(envFile), 0644); err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv("IMAGE_TAG", "from-shell")
+
+	data := `
+services:
+  web:
+    image: nginx:${IMAGE_TAG}
+    ports:
+      - "${WEB_PORT}:80"
+    labels:
+      watchcow.enable: "true"
+      watchcow.display_name: ${TITLE}
+      watchcow.service_port: ${WEB_PORT:-80}
+      watchcow.redirect: "$${HOST}"
+`
+	p, err := Parse([]byte(data), dir)
+	if err != nil {
+		t.Fatalf("Parse() error = %v", err)
+	}
+	s := p.Services[0]
+	if s.Image != "nginx:from-shell" {
+		t.Errorf("Image = %q, want the shell variable to win over .env", s.Image)
+	}
+	if s.Labels["watchcow.display_name"] != "My Web" || s.Labels["watchcow.service_port"] != "8088" {
+		t.Errorf("Labels = %v", s.Labels)
+	}
+	if s.Labels["watchcow.redirect"] != "${HOST}" {
+		t.Errorf("escaped label = %q, want ${HOST}", s.Labels["watchcow.redirect"])
+	}
+	if b := s.PortBindings["80/tcp"]; len(b) != 1 || b[0].HostPort != "8088" {
+		t.Errorf("PortBindings = %v", s.PortBindings)
+	}
+}
@@ -0,0 +1,225 @@
+package docker
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/docker/docker/api/types"
+	"github.com/docker/docker/api/types/container"
+	"github.com/docker/docker/api/types/events"
+	"github.com/docker/go-connections/nat"
+)
+
+// fakeDocker is a scriptable in-memory DockerClient.
+// Tests create, start, stop and remove containers; each change emits the
+// matching Docker event to subscribers and is kept in a log for replay.
+type fakeDocker struct {
+	mu          sync.Mutex
+	containers  map[string]*container.InspectResponse
+	log         []events.Message
+	subscribers []chan events.Message
+	pingErr     error
+	listErr     error
+	clock       time.Time
+}
+
+func newFakeDocker() *fakeDocker {
+	return &fakeDocker{
+		containers: make(map[string]*container.InspectResponse),
+		clock:      time.Now(),
+	}
+}
+
+// Create adds a stopped container. id should be 12 characters like Docker short IDs.
+func (f *fakeDocker) Create(id, name, image string, labels map[string]string, ports map[string]string) {
+	portMap := nat.PortMap{}
+	for containerPort, hostPort := range ports {
+		portMap[nat.Port(containerPort+"/tcp")] = []nat.PortBinding{{HostIP: "0.0.0.0", HostPort: hostPort}}
+	}
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.containers[id] = &container.InspectResponse{
+		ContainerJSONBase: &container.ContainerJSONBase{
+			ID:         id,
+			Name:       "/" + name,
+			State:      &container.State{Status: "created"},
+			HostConfig: &container.HostConfig{NetworkMode: "bridge"},
+		},
+		Config:          &container.Config{Image: image, Labels: labels},
+		NetworkSettings: &container.NetworkSettings{NetworkSettingsBase: container.NetworkSettingsBase{Ports: portMap}},
+	}
+}
+
+// SetListError makes ContainerList fail with err, or succeed again if err is nil.
+func (f *fakeDocker) SetListError(err error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.listErr = err
+}
+
+// Start marks a container running and emits a start event.
+func (f *fakeDocker) Start(id string) {
+	f.setState(id, "running", true)
+	f.emit(id, "start")
+}
+
+// Stop marks a container exited and emits die and stop events.
+func (f *fakeDocker) Stop(id string) {
+	f.setState(id, "exited", false)
+	f.emit(id, "die")
+	f.emit(id, "stop")
+}
+
+// Remove deletes a container and emits a destroy event.
+func (f *fakeDocker) Remove(id string) {
+	f.emit(id, "destroy")
+	f.mu.Lock()
+	delete(f.containers, id)
+	f.mu.Unlock()
+}
+
+// SetHealth sets the health check status and restart state of a container without emitting events.
+func (f *fakeDocker) SetHealth(id, health string, restartCount int, restarting bool) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if c, ok := f.containers[id]; ok {
+		if health != "" {
+			c.State.Health = &container.Health{Status: container.HealthStatus(health)}
+		}
+		c.RestartCount = restartCount
+		c.State.Restarting = restarting
+		c.State.StartedAt = time.Now().UTC().Format(time.RFC3339Nano)
+	}
+}
+
+func (f *fakeDocker) setState(id, status string, running bool) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if c, ok := f.containers[id]; ok {
+		c.State.Status = status
+		c.State.Running = running
+	}
+}
+
+func (f *fakeDocker) emit(id, action string) {
+	f.mu.Lock()
+
+	name := ""
+	if c, ok := f.containers[id]; ok {
+		name = c.Name[1:]
+	}
+
+	// Wall-clock timestamps, strictly increasing so replay is deterministic
+	now := time.Now()
+	if !now.After(f.clock) {
+		now = f.clock.Add(time.Microsecond)
+	}
+	f.clock = now
+	msg := events.Message{
+		Type:     events.ContainerEventType,
+		Action:   events.Action(action),
+		Actor:    events.Actor{ID: id, Attributes: map[string]string{"name": name}},
+		Time:     f.clock.Unix(),
+		TimeNano: f.clock.UnixNano(),
+	}
+	f.log = append(f.log, msg)
+	subscribers := slices.Clone(f.subscribers)
+	f.mu.Unlock()
+
+	// Deliver outside the lock: the monitor inspects containers while handling events
+	for _, sub := range subscribers {
+		sub <- msg
+	}
+}
+
+func (f *fakeDocker) Events(ctx context.Context, options events.ListOptions) (<-chan events.Message, <-chan error) {
+	ch := make(chan events.Message, 1000)
+	errCh := make(chan error, 1)
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	// Replay logged events after options.Since (seconds.nanoseconds)
+	var since int64
+	if options.Since != "" {
+		var secs, nanos int64
+		fmt.Sscanf(options.Since, "%d.%d", &secs, &nanos)
+		since = secs*int64(time.Second) + nanos
+	}
+	for _, msg := range f.log {
+		if msg.TimeNano > since {
+			ch <- msg
+		}
+	}
+
+	f.subscribers = append(f.subscribers, ch)
+	return ch, errCh
+}
+
+func (f *fakeDocker) ContainerInspect(ctx context.Context, containerID string) (container.InspectResponse, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	c, ok := f.containers[containerID]
+	if !ok {
+		return container.InspectResponse{}, fmt.Errorf("No such container: %s", containerID)
+	}
+	// Copy the mutable state so callers don't race with Start and Stop
+	inspect := *c
+	base := *c.ContainerJSONBase
+	state := *base.State
+	base.State = &state
+	inspect.ContainerJSONBase = &base
+	return inspect, nil
+}
+
+func (f *fakeDocker) ContainerList(ctx context.Context, options container.ListOptions) ([]container.Summary, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if f.listErr != nil {
+		return nil, f.listErr
+	}
+
+	var result []container.Summary
+	for id, c := range f.containers {
+		if !options.All && !c.State.Running {
+			continue
+		}
+		// Like Docker, only running containers list their published ports
+		var ports []container.Port
+		for port, bindings := range c.NetworkSettings.Ports {
+			if !c.State.Running {
+				break
+			}
+			for _, b := range bindings {
+				var public int
+				fmt.Sscanf(b.HostPort, "%d", &public)
+				ports = append(ports, container.Port{PrivatePort: uint16(port.Int()), PublicPort: uint16(public), Type: port.Proto()})
+			}
+		}
+		summary := container.Summary{
+			ID:     id,
+			Names:  []string{c.Name},
+			Image:  c.Config.Image,
+			State:  c.State.Status,
+			Ports:  ports,
+			Labels: c.Config.Labels,
+		}
+		summary.HostConfig.NetworkMode = string(c.HostConfig.NetworkMode)
+		result = append(result, summary)
+	}
+	return result, nil
+}
+
+func (f *fakeDocker) Ping(ctx context.Context) (types.Ping, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return types.Ping{}, f.pingErr
+}
+
+func (f *fakeDocker) Close() error {
+	return nil
+}
@@ -0,0 +1,137 @@
+package docker
+
+import (
+	"context"
+	"log/slog"
+	"os"
+	"sync"
+	"time"
+)
+
+// defaultDestroyGrace is used when WATCHCOW_DESTROY_GRACE is not set
+const defaultDestroyGrace = 60 * time.Second
+
+// pendingUninstall is an app whose container was destroyed and that is
+// uninstalled once the grace period expires unless a new container adopts it.
+type pendingUninstall struct {
+	timer    *time.Timer
+	deadline time.Time
+}
+
+// graceTracker holds apps waiting out the destroy grace period.
+type graceTracker struct {
+	mu   sync.Mutex
+	apps map[string]*pendingUninstall
+}
+
+// getDestroyGrace returns the destroy grace period from environment.
+// Accepts a Go duration ("90s", "5m") or a number of seconds; 0 uninstalls immediately.
+func getDestroyGrace() time.Duration {
+	v := os.Getenv("WATCHCOW_DESTROY_GRACE")
+	if v == "" {
+		return defaultDestroyGrace
+	}
+	d, err := parseDuration(v)
+	if err != nil {
+		slog.Warn("Invalid WATCHCOW_DESTROY_GRACE, using default", "value", v, "default", defaultDestroyGrace)
+		return defaultDestroyGrace
+	}
+	return d
+}
+
+// scheduleUninstall starts the grace period for an app whose container was destroyed.
+// The deadline is persisted, so the grace period survives a restart.
+func (m *Monitor) scheduleUninstall(appName string) {
+	slog.Info("Container destroyed, uninstalling app after grace period", "app", appName, "grace", m.destroyGrace)
+	deadline := time.Now().Add(m.destroyGrace)
+	m.state.SetGraceDeadline(appName, deadline)
+	m.startGraceTimer(appName, deadline)
+}
+
+// resumeGraceUninstalls restarts the grace periods persisted before a restart.
+// Periods that expired while WatchCow was down end right away; the uninstall is
+// still skipped if a container claims the app first.
+func (m *Monitor) resumeGraceUninstalls() {
+	for appName, deadline := range m.state.GraceDeadlines() {
+		slog.Info("Resuming destroy grace period", "app", appName, "remaining", max(time.Until(deadline), 0).Round(time.Second))
+		m.startGraceTimer(appName, deadline)
+	}
+}
+
+// startGraceTimer queues the grace_uninstall of an app at deadline.
+func (m *Monitor) startGraceTimer(appName string, deadline time.Time) {
+	m.pendingUninstalls.mu.Lock()
+	defer m.pendingUninstalls.mu.Unlock()
+
+	if p, exists := m.pendingUninstalls.apps[appName]; exists {
+		p.timer.Stop()
+	}
+	m.pendingUninstalls.apps[appName] = &pendingUninstall{
+		deadline: deadline,
+		timer: time.AfterFunc(time.Until(deadline), func() {
+			m.queueOperation(&AppOperation{Type: "grace_uninstall", AppName: appName})
+		}),
+	}
+}
+
+// stopGraceTimers stops the grace timers on shutdown. The persisted deadlines are
+// kept and resumed on the next start.
+func (m *Monitor) stopGraceTimers() {
+	m.pendingUninstalls.mu.Lock()
+	defer m.pendingUninstalls.mu.Unlock()
+
+	for _, p := range m.pendingUninstalls.apps {
+		p.timer.Stop()
+	}
+}
+
+// cancelUninstall stops a pending grace-period uninstall.
+// Returns true if the app was waiting to be uninstalled.
+func (m *Monitor) cancelUninstall(appName string) bool {
+	m.pendingUninstalls.mu.Lock()
+	defer m.pendingUninstalls.mu.Unlock()
+
+	p, exists := m.pendingUninstalls.apps[appName]
+	if !exists {
+		return false
+	}
+	p.timer.Stop()
+	delete(m.pendingUninstalls.apps, appName)
+	m.state.ClearGraceDeadline(appName)
+	return true
+}
+
+// isUninstallPending reports whether an app is inside its destroy grace period.
+func (m *Monitor) isUninstallPending(appName string) bool {
+	m.pendingUninstalls.mu.Lock()
+	defer m.pendingUninstalls.mu.Unlock()
+
+	_, exists := m.pendingUninstalls.apps[appName]
+	return exists
+}
+
+// processGraceUninstall uninstalls an app whose grace period expired without adoption.
+func (m *Monitor) processGraceUninstall(ctx context.Context, op *AppOperation) {
+	appName := op.AppName
+
+	m.pendingUninstalls.mu.Lock()
+	p, exists := m.pendingUninstalls.apps[appName]
+	if !exists || time.Now().Before(p.deadline) {
+		// Adopted by a new container, or rescheduled by a later destroy
+		m.pendingUninstalls.mu.Unlock()
+		return
+	}
+	delete(m.pendingUninstalls.apps, appName)
+	m.pendingUninstalls.mu.Unlock()
+	m.state.ClearGraceDeadline(appName)
+
+	// A container may have claimed the app without going through adoption
+	if m.isAppOwned(appName) {
+		return
+	}
+
+	if m.installer != nil {
+		slog.Info("Grace period expired, uninstalling fnOS app", "app", appName)
+		m.uninstallApp(ctx, appName, op.Attempt)
+	}
+}
@@ -0,0 +1,113 @@
+package docker
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+func TestGetDestroyGrace(t *testing.T) {
+	tests := []struct {
+		value string
+		want  time.Duration
+	}{
+		{"", defaultDestroyGrace},
+		{"0", 0},
+		{"30", 30 * time.Second},
+		{"5m", 5 * time.Minute},
+		{"invalid", defaultDestroyGrace},
+		{"-10", defaultDestroyGrace},
+	}
+
+	for _, tt := range tests {
+		t.Setenv("WATCHCOW_DESTROY_GRACE", tt.value)
+		if got := getDestroyGrace(); got != tt.want {
+			t.Errorf("getDestroyGrace() with %q = %v, want %v", tt.value, got, tt.want)
+		}
+	}
+}
+
+// newGraceTestMonitor creates a Monitor with a one hour destroy grace period and its own state file.
+func newGraceTestMonitor(t *testing.T) *Monitor {
+	t.Helper()
+	t.Setenv("TRIM_PKGVAR", t.TempDir())
+	return &Monitor{
+		destroyGrace:      time.Hour,
+		opQueue:           newOperationQueue(),
+		state:             newStateStore(),
+		pendingUninstalls: graceTracker{apps: make(map[string]*pendingUninstall)},
+	}
+}
+
+func TestScheduleAndCancelUninstall(t *testing.T) {
+	m := newGraceTestMonitor(t)
+
+	m.scheduleUninstall("watchcow.nginx")
+	if !m.isUninstallPending("watchcow.nginx") {
+		t.Fatal("isUninstallPending() = false after scheduleUninstall")
+	}
+
+	if !m.cancelUninstall("watchcow.nginx") {
+		t.Error("cancelUninstall() = false, want true for pending app")
+	}
+	if m.isUninstallPending("watchcow.nginx") {
+		t.Error("isUninstallPending() = true after cancelUninstall")
+	}
+	if m.cancelUninstall("watchcow.nginx") {
+		t.Error("cancelUninstall() = true, want false for app without pending uninstall")
+	}
+	if deadlines := newStateStore().GraceDeadlines(); len(deadlines) != 0 {
+		t.Errorf("persisted GraceDeadlines() = %v after cancel, want empty", deadlines)
+	}
+}
+
+func TestResumeGraceUninstalls(t *testing.T) {
+	m := newGraceTestMonitor(t)
+	m.scheduleUninstall("watchcow.nginx")
+	m.state.SetGraceDeadline("watchcow.expired", time.Now().Add(-time.Minute))
+	m.stopGraceTimers()
+
+	// A restarted monitor continues the grace periods from the persisted deadlines
+	restarted := &Monitor{
+		opQueue:           newOperationQueue(),
+		state:             newStateStore(),
+		pendingUninstalls: graceTracker{apps: make(map[string]*pendingUninstall)},
+	}
+	restarted.resumeGraceUninstalls()
+	defer restarted.stopGraceTimers()
+
+	if !restarted.isUninstallPending("watchcow.nginx") || !restarted.isUninstallPending("watchcow.expired") {
+		t.Fatal("resumeGraceUninstalls() did not restore the pending uninstalls")
+	}
+	remaining := time.Until(restarted.pendingUninstalls.apps["watchcow.nginx"].deadline)
+	if remaining <= 59*time.Minute || remaining > time.Hour {
+		t.Errorf("remaining grace = %v, want the original deadline", remaining)
+	}
+
+	// The expired period ends right away
+	waitFor(t, "grace_uninstall", func() bool { return restarted.QueueDepth() == 1 })
+	op, _ := restarted.opQueue.pop()
+	if op.Type != "grace_uninstall" || op.AppName != "watchcow.expired" {
+		t.Errorf("queued operation = %+v, want grace_uninstall of watchcow.expired", op)
+	}
+	restarted.processGraceUninstall(context.Background(), op)
+	if restarted.isUninstallPending("watchcow.expired") {
+		t.Error("expired grace period still pending after grace_uninstall")
+	}
+	if _, ok := newStateStore().GraceDeadlines()["watchcow.expired"]; ok {
+		t.Error("persisted deadline kept after the grace period ended")
+	}
+}
+
+func TestProcessGraceUninstall_SkipsRescheduled(t *testing.T) {
+	m := newGraceTestMonitor(t)
+
+	// A stale timer fires after a later destroy restarted the grace period
+	m.scheduleUninstall("watchcow.nginx")
+	m.processGraceUninstall(context.Background(), &AppOperation{Type: "grace_uninstall", AppName: "watchcow.nginx"})
+
+	if !m.isUninstallPending("watchcow.nginx") {
+		t.Error("processGraceUninstall() dropped an uninstall whose grace period has not expired")
+	}
+	m.cancelUninstall("watchcow.nginx")
+}
@@ -0,0 +1,94 @@
+package docker
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+)
+
+// Container health summaries reported to the status command of generated cmd/main
+const (
+	HealthStatusRunning   = "running"   // Running and not failing its health check
+	HealthStatusUnhealthy = "unhealthy" // Running but unhealthy or restart-looping
+	HealthStatusStopped   = "stopped"   // Not running
+)
+
+const (
+	// restartLoopThreshold is the restart count from which a recent restart counts as a loop
+	restartLoopThreshold = 3
+
+	// restartLoopWindow is how recently the container must have started to be in a loop
+	restartLoopWindow = time.Minute
+)
+
+// ErrContainerNotTracked is returned for containers the monitor does not know.
+var ErrContainerNotTracked = errors.New("container not tracked")
+
+// ContainerHealth is the runtime health of a managed container.
+type ContainerHealth struct {
+	Container    string `json:"container"`
+	State        string `json:"state"`  // Docker state: running, exited, restarting, ...
+	Health       string `json:"health"` // Docker health check status; empty without HEALTHCHECK
+	RestartCount int    `json:"restart_count"`
+	Status       string `json:"status"` // HealthStatusRunning, HealthStatusUnhealthy or HealthStatusStopped
+}
+
+// ContainerHealth inspects a tracked container by name and summarizes its health.
+// A running container is unhealthy if its health check fails, Docker is restarting
+// it, or it restarted at least restartLoopThreshold times and started again recently.
+func (m *Monitor) ContainerHealth(ctx context.Context, containerName string) (*ContainerHealth, error) {
+	containerID := ""
+	m.containers.Range(func(key, value any) bool {
+		if value.(*ContainerState).ContainerName == containerName {
+			containerID = key.(string)
+			return false
+		}
+		return true
+	})
+	if containerID == "" {
+		return nil, ErrContainerNotTracked
+	}
+
+	info, err := m.cli.ContainerInspect(ctx, containerID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to inspect container: %w", err)
+	}
+
+	h := &ContainerHealth{
+		Container:    containerName,
+		RestartCount: info.RestartCount,
+		Status:       HealthStatusStopped,
+	}
+	state := info.State
+	if state == nil {
+		return h, nil
+	}
+	h.State = string(state.Status)
+	if state.Health != nil {
+		h.Health = string(state.Health.Status)
+	}
+
+	switch {
+	case state.Restarting:
+		h.Status = HealthStatusUnhealthy
+	case !state.Running:
+		h.Status = HealthStatusStopped
+	case h.Health == "unhealthy":
+		h.Status = HealthStatusUnhealthy
+	case info.RestartCount >= restartLoopThreshold && startedWithin(state.StartedAt, restartLoopWindow):
+		h.Status = HealthStatusUnhealthy
+	default:
+		h.Status = HealthStatusRunning
+	}
+	return h, nil
+}
+
+// startedWithin reports whether a Docker StartedAt timestamp is within d of now.
+func startedWithin(startedAt string, d time.Duration) bool {
+	t, err := time.Parse(time.RFC3339Nano, startedAt)
+	if err != nil {
+		return false
+	}
+	return time.Since(t) < d
+}
@@ -0,0 +1,55 @@
+package docker
+
+import (
+	"context"
+	"errors"
+	"testing"
+)
+
+func TestContainerHealth(t *testing.T) {
+	tests := []struct {
+		name         string
+		running      bool
+		health       string
+		restartCount int
+		restarting   bool
+		want         string
+	}{
+		{"running", true, "", 0, false, HealthStatusRunning},
+		{"healthy", true, "healthy", 0, false, HealthStatusRunning},
+		{"unhealthy", true, "unhealthy", 0, false, HealthStatusUnhealthy},
+		{"restarting", false, "", 5, true, HealthStatusUnhealthy},
+		{"restart loop", true, "", restartLoopThreshold, false, HealthStatusUnhealthy},
+		{"single restart", true, "", 1, false, HealthStatusRunning},
+		{"stopped", false, "", 0, false, HealthStatusStopped},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cli := newFakeDocker()
+			cli.Create("abc123def456", "nginx", "nginx:latest", nil, nil)
+			if tt.running {
+				cli.setState("abc123def456", "running", true)
+			}
+			cli.SetHealth("abc123def456", tt.health, tt.restartCount, tt.restarting)
+			m := newTestMonitor(t, cli, newFakeInstaller())
+			m.containers.Store("abc123def456", &ContainerState{ContainerID: "abc123def456", ContainerName: "nginx"})
+
+			h, err := m.ContainerHealth(context.Background(), "nginx")
+			if err != nil {
+				t.Fatalf("ContainerHealth() error = %v", err)
+			}
+			if h.Status != tt.want {
+				t.Errorf("ContainerHealth() status = %q, want %q (%+v)", h.Status, tt.want, h)
+			}
+		})
+	}
+}
+
+func TestContainerHealth_NotTracked(t *testing.T) {
+	m := newTestMonitor(t, newFakeDocker(), newFakeInstaller())
+
+	if _, err := m.ContainerHealth(context.Background(), "gone"); !errors.Is(err, ErrContainerNotTracked) {
+		t.Errorf("ContainerHealth() error = %v, want ErrContainerNotTracked", err)
+	}
+}
@@ -0,0 +1,152 @@
+package docker
+
+import (
+	"context"
+	"log/slog"
+	"sort"
+	"sync"
+	"time"
+
+	"watchcow/internal/fpkgen"
+)
+
+// InstalledApp is an app installed in fnOS, as listed by appcenter-cli.
+type InstalledApp = fpkgen.InstalledApp
+
+// installedRefreshInterval is how often the installed app cache is refreshed
+const installedRefreshInterval = 5 * time.Minute
+
+// installedCache caches the parsed appcenter-cli list output so container
+// starts don't each run appcenter-cli list.
+type installedCache struct {
+	mu          sync.RWMutex
+	apps        map[string]InstalledApp
+	loaded      bool
+	refreshedAt time.Time
+}
+
+// refreshInstalled reloads the installed app cache from appcenter-cli.
+// On failure the previous contents are kept.
+func (m *Monitor) refreshInstalled(ctx context.Context) error {
+	if m.installer == nil {
+		return nil
+	}
+
+	apps, err := m.installer.ListInstalled(ctx)
+	if err != nil {
+		slog.Warn("Failed to refresh installed apps", "error", err)
+		return err
+	}
+	m.storeInstalled(apps)
+	return nil
+}
+
+// storeInstalled replaces the installed app cache with a fresh appcenter-cli list.
+func (m *Monitor) storeInstalled(apps []InstalledApp) {
+	byName := make(map[string]InstalledApp, len(apps))
+	for _, a := range apps {
+		byName[a.Name] = a
+	}
+
+	m.installed.mu.Lock()
+	m.installed.apps = byName
+	m.installed.loaded = true
+	m.installed.refreshedAt = time.Now()
+	m.installed.mu.Unlock()
+
+	slog.Debug("Refreshed installed apps", "count", len(apps))
+}
+
+// isAppInstalled reports whether an app is installed, loading the cache on first use.
+func (m *Monitor) isAppInstalled(ctx context.Context, appName string) bool {
+	if m.installer == nil {
+		return false
+	}
+
+	m.installed.mu.RLock()
+	loaded := m.installed.loaded
+	m.installed.mu.RUnlock()
+	if !loaded {
+		if err := m.refreshInstalled(ctx); err != nil {
+			return false
+		}
+	}
+
+	m.installed.mu.RLock()
+	defer m.installed.mu.RUnlock()
+	_, ok := m.installed.apps[appName]
+	return ok
+}
+
+// markInstalled records an install, upgrade or status change in the cache
+// until the next refresh confirms it.
+func (m *Monitor) markInstalled(appName, version, status string) {
+	m.installed.mu.Lock()
+	defer m.installed.mu.Unlock()
+	if m.installed.apps == nil {
+		m.installed.apps = make(map[string]InstalledApp)
+	}
+	a := m.installed.apps[appName]
+	a.Name = appName
+	if version != "" {
+		a.Version = version
+	}
+	if status != "" {
+		a.Status = status
+	}
+	m.installed.apps[appName] = a
+}
+
+// markUninstalled removes an app from the cache.
+func (m *Monitor) markUninstalled(appName string) {
+	m.installed.mu.Lock()
+	defer m.installed.mu.Unlock()
+	delete(m.installed.apps, appName)
+}
+
+// queueInstalledRefresh queues a cache refresh; pending refreshes coalesce.
+func (m *Monitor) queueInstalledRefresh() {
+	if m.installer == nil {
+		return
+	}
+	m.queueOperation(&AppOperation{Type: "refresh_installed"})
+}
+
+// runInstalledRefresh refreshes the installed app cache periodically.
+func (m *Monitor) runInstalledRefresh(ctx context.Context) {
+	ticker := time.NewTicker(installedRefreshInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-m.stopCh:
+			return
+		case <-ticker.C:
+			m.queueInstalledRefresh()
+		}
+	}
+}
+
+// InstalledApps returns the cached installed apps, sorted by name.
+func (m *Monitor) InstalledApps() []InstalledApp {
+	m.installed.mu.RLock()
+	defer m.installed.mu.RUnlock()
+
+	result := make([]InstalledApp, 0, len(m.installed.apps))
+	for _, a := range m.installed.apps {
+		result = append(result, a)
+	}
+	sort.Slice(result, func(i, j int) bool {
+		return result[i].Name < result[j].Name
+	})
+	return result
+}
+
+// InstalledRefreshedAt returns when the installed app cache was last refreshed.
+func (m *Monitor) InstalledRefreshedAt() time.Time {
+	m.installed.mu.RLock()
+	defer m.installed.mu.RUnlock()
+	return m.installed.refreshedAt
+}
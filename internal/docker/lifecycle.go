@@ -0,0 +1,52 @@
+package docker
+
+import (
+	"log/slog"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// lifecycleMarkerTTL bounds how long a marker written by cmd/main suppresses events
+const lifecycleMarkerTTL = 2 * time.Minute
+
+// getLifecycleMarkerDir returns where cmd/main of bidirectional apps writes markers.
+// If TRIM_PKGVAR is set, uses ${TRIM_PKGVAR}/lifecycle.
+// Otherwise uses /tmp/watchcow/lifecycle.
+func getLifecycleMarkerDir() string {
+	if pkgVar := os.Getenv("TRIM_PKGVAR"); pkgVar != "" {
+		return filepath.Join(pkgVar, "lifecycle")
+	}
+	return "/tmp/watchcow/lifecycle"
+}
+
+// consumeLifecycleMarker reports whether a container event with the given action
+// ("start" or "stop") was caused by fnOS through a bidirectional cmd/main, in which
+// case the monitor must not call appcenter-cli for it again.
+//
+// A marker stays valid for lifecycleMarkerTTL so the "die" and "stop" events of one
+// stop are both suppressed. The marker of the opposite action is removed: the
+// container changed state since, so it no longer applies.
+func (m *Monitor) consumeLifecycleMarker(containerName, action string) bool {
+	if containerName == "" {
+		return false
+	}
+
+	opposite := "stop"
+	if action == "stop" {
+		opposite = "start"
+	}
+	os.Remove(filepath.Join(m.lifecycleDir, containerName+"."+opposite))
+
+	marker := filepath.Join(m.lifecycleDir, containerName+"."+action)
+	info, err := os.Stat(marker)
+	if err != nil {
+		return false
+	}
+	if time.Since(info.ModTime()) > lifecycleMarkerTTL {
+		slog.Debug("Removing expired lifecycle marker", "container", containerName, "action", action)
+		os.Remove(marker)
+		return false
+	}
+	return true
+}
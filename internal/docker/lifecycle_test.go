@@ -0,0 +1,63 @@
+package docker
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+// writeLifecycleMarker writes a marker as cmd/main of a bidirectional app would.
+func writeLifecycleMarker(t *testing.T, m *Monitor, containerName, action string) string {
+	t.Helper()
+	if err := os.MkdirAll(m.lifecycleDir, 0755); err != nil {
+		t.Fatal(err)
+	}
+	path := filepath.Join(m.lifecycleDir, containerName+"."+action)
+	if err := os.WriteFile(path, nil, 0644); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestProcessStop_FnOSStopSkipsAppcenter(t *testing.T) {
+	installer := newFakeInstaller("watchcow.nginx")
+	m := newTestMonitor(t, newFakeDocker(), installer)
+	trackInstalled(m, "abc", "watchcow.nginx")
+	writeLifecycleMarker(t, m, "abc", "stop")
+
+	// "die" and "stop" events of one fnOS stop
+	m.processStop(context.Background(), &AppOperation{Type: "stop", ContainerID: "abc"})
+	m.processStop(context.Background(), &AppOperation{Type: "stop", ContainerID: "abc"})
+
+	if n := installer.Count("stop watchcow.nginx"); n != 0 {
+		t.Errorf("appcenter-cli stop called %d times for fnOS-initiated stop", n)
+	}
+}
+
+func TestConsumeLifecycleMarker(t *testing.T) {
+	m := newTestMonitor(t, newFakeDocker(), newFakeInstaller())
+
+	if m.consumeLifecycleMarker("nginx", "stop") {
+		t.Error("consumeLifecycleMarker() = true without marker")
+	}
+
+	// A start clears a leftover stop marker
+	stop := writeLifecycleMarker(t, m, "nginx", "stop")
+	writeLifecycleMarker(t, m, "nginx", "start")
+	if !m.consumeLifecycleMarker("nginx", "start") {
+		t.Error("consumeLifecycleMarker(start) = false with marker")
+	}
+	if _, err := os.Stat(stop); !os.IsNotExist(err) {
+		t.Error("stop marker should be removed on start")
+	}
+
+	// Expired markers are ignored
+	expired := writeLifecycleMarker(t, m, "nginx", "stop")
+	old := time.Now().Add(-lifecycleMarkerTTL - time.Minute)
+	os.Chtimes(expired, old, old)
+	if m.consumeLifecycleMarker("nginx", "stop") {
+		t.Error("consumeLifecycleMarker() = true for expired marker")
+	}
+}
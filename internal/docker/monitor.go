@@ -2,14 +2,17 @@ package docker
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"log/slog"
 	"os"
 	"sort"
 	"strings"
 	"sync"
+	"sync/atomic"
 	"time"
 
+	"github.com/docker/docker/api/types"
 	"github.com/docker/docker/api/types/container"
 	"github.com/docker/docker/api/types/events"
 	"github.com/docker/docker/api/types/filters"
@@ -25,6 +28,35 @@ import (
 type ConfigProvider interface {
 	// GetByKey returns the stored config for a container key, or nil if not found.
 	GetByKey(key string) *StoredConfig
+	// AppNamesByImage returns the app names of all stored configs for an image.
+	AppNamesByImage(image string) []string
+}
+
+// DockerClient is the subset of the Docker API used by Monitor.
+// Implemented by *client.Client; tests use a scriptable in-memory fake.
+type DockerClient interface {
+	Events(ctx context.Context, options events.ListOptions) (<-chan events.Message, <-chan error)
+	ContainerInspect(ctx context.Context, containerID string) (container.InspectResponse, error)
+	ContainerList(ctx context.Context, options container.ListOptions) ([]container.Summary, error)
+	Ping(ctx context.Context) (types.Ping, error)
+	Close() error
+}
+
+// AppInstaller manages fnOS apps through appcenter-cli.
+// Implemented by fpkgen.Installer.
+type AppInstaller interface {
+	// InstallLocal installs the app package in appDir.
+	InstallLocal(ctx context.Context, appDir string) error
+	// Upgrade upgrades an installed app in place from the app package in appDir.
+	Upgrade(ctx context.Context, appDir string) error
+	// Uninstall uninstalls an app by name.
+	Uninstall(ctx context.Context, appName string) (*fpkgen.UninstallResult, error)
+	// StartApp starts an installed app.
+	StartApp(ctx context.Context, appName string) error
+	// StopApp stops an installed app.
+	StopApp(ctx context.Context, appName string) error
+	// ListInstalled returns all installed apps with version and status.
+	ListInstalled(ctx context.Context) ([]fpkgen.InstalledApp, error)
 }
 
 // StoredConfig represents a saved container configuration (from dashboard).
@@ -36,6 +68,7 @@ type StoredConfig struct {
 	Maintainer  string
 	Entries     []StoredEntry
 	IconBase64  string
+	Manifest    map[string]string // Extra fnOS manifest fields, see fpkgen.ManifestKeys
 }
 
 // StoredEntry represents a saved entry configuration.
@@ -55,32 +88,69 @@ type StoredEntry struct {
 
 // AppOperation represents an operation to be processed serially
 type AppOperation struct {
-	Type          string // "install", "start", "stop", "destroy", "container_start"
+	Type          string // "install", "upgrade", "start", "stop", "destroy", "container_start"
 	AppName       string
 	AppDir        string
 	ContainerID   string
 	ContainerName string
 	Labels        map[string]string
 	StoredConfig  *StoredConfig // Config from dashboard storage (if no labels)
+	PackageID     string        // Stored package to install (package_install)
 	ResultCh      chan error
+	Attempt       int    // Number of failed attempts so far
+	seq           uint64 // Queue sequence of the operation, used to drop superseded retries
 }
 
 // Monitor watches Docker containers and manages fnOS app installation
 type Monitor struct {
-	cli            *client.Client
+	cli            DockerClient
 	generator      *fpkgen.Generator
-	installer      *fpkgen.Installer
+	installer      AppInstaller // nil if appcenter-cli is not available
 	configProvider ConfigProvider
 	stopCh         chan struct{}
 
 	// Track all container states
 	containers sync.Map // map[containerID]*ContainerState
 
+	// Set once containers were listed; until then the containers map may be
+	// incomplete and no installed app is treated as orphaned
+	listed atomic.Bool
+
 	// App registry for runtime app info lookup
 	registry *app.Registry
 
 	// Operation queue for serializing all state changes and appcenter-cli calls
-	opQueue chan *AppOperation
+	opQueue *operationQueue
+
+	// Containers currently waiting for readiness before install
+	readinessWaits sync.Map // map[containerID]struct{}
+
+	// Containers not installed because they did not become ready in time
+	notReady sync.Map // map[containerID]*NotReadyApp
+
+	// Reconciliation of installed apps without containers
+	orphanPolicy string
+	orphans      orphanTracker
+	state        *stateStore // Persisted package fingerprints
+
+	// Cached appcenter-cli list output
+	installed installedCache
+
+	// Backoff timers of failed operations waiting to be retried
+	retries retryTracker
+
+	// Apps whose container was destroyed, uninstalled after the grace period
+	destroyGrace      time.Duration
+	pendingUninstalls graceTracker
+
+	// Generated packages kept for download and rollback
+	packages *packageStore
+
+	// Markers written by cmd/main of bidirectional apps on fnOS start/stop
+	lifecycleDir string
+
+	// Label problems of label-configured containers, shown on the dashboard
+	labelReports sync.Map // map[containerID]*LabelReport
 }
 
 // ContainerState tracks the state of a container
@@ -113,22 +183,38 @@ func NewMonitor() (*Monitor, error) {
 	}
 
 	// Try to create installer (may fail if appcenter-cli not available)
-	installer, err := fpkgen.NewInstaller()
-	if err != nil {
+	var installer AppInstaller
+	if inst, err := fpkgen.NewInstaller(); err != nil {
 		slog.Warn("appcenter-cli not available, will only generate app packages", "error", err)
 		// Continue without installer - useful for development/testing
 	} else {
+		installer = inst
 		slog.Info("Installer ready, apps will be auto-installed via appcenter-cli")
 	}
 
+	return newMonitor(cli, generator, installer), nil
+}
+
+// newMonitor creates a monitor from its dependencies.
+// installer may be nil when appcenter-cli is not available.
+func newMonitor(cli DockerClient, generator *fpkgen.Generator, installer AppInstaller) *Monitor {
 	return &Monitor{
-		cli:       cli,
-		generator: generator,
-		installer: installer,
-		stopCh:    make(chan struct{}),
-		registry:  app.NewRegistry(),
-		opQueue:   make(chan *AppOperation, 100),
-	}, nil
+		cli:          cli,
+		generator:    generator,
+		installer:    installer,
+		stopCh:       make(chan struct{}),
+		registry:     app.NewRegistry(),
+		opQueue:      newOperationQueue(),
+		orphanPolicy: getOrphanPolicy(),
+		orphans:      orphanTracker{apps: make(map[string]*OrphanApp)},
+		state:        newStateStore(),
+		packages:     newPackageStore(),
+		lifecycleDir: getLifecycleMarkerDir(),
+		destroyGrace: getDestroyGrace(),
+		pendingUninstalls: graceTracker{
+			apps: make(map[string]*pendingUninstall),
+		},
+	}
 }
 
 // SetConfigProvider sets the config provider for dashboard storage lookup.
@@ -225,24 +311,66 @@ func (m *Monitor) runOperationWorker(ctx context.Context) {
 			return
 		case <-m.stopCh:
 			return
-		case op := <-m.opQueue:
-			switch op.Type {
-			case "container_start", "dashboard_install":
-				m.processContainerStart(ctx, op)
+		case <-m.opQueue.notify:
+		}
+
+		for {
+			if ctx.Err() != nil {
+				return
+			}
+			op, ok := m.opQueue.pop()
+			if !ok {
+				break
+			}
+			m.processOperation(ctx, op)
+		}
+	}
+}
 
-			case "dashboard_reinstall":
-				m.processDashboardReinstall(ctx, op)
+// processOperation dispatches a single operation to its handler
+func (m *Monitor) processOperation(ctx context.Context, op *AppOperation) {
+	switch op.Type {
+	case "container_start", "dashboard_install":
+		m.processContainerStart(ctx, op)
 
-			case "stop":
-				m.processStop(op)
+	case "install":
+		m.processInstall(ctx, op)
 
-			case "destroy":
-				m.processDestroy(op)
+	case "not_ready":
+		m.processNotReady(op)
 
-			case "dashboard_uninstall":
-				m.processDashboardUninstall(op)
-			}
-		}
+	case "ready_timeout":
+		m.processReadyTimeout(op)
+
+	case "upgrade":
+		m.processUpgrade(ctx, op)
+
+	case "dashboard_reinstall":
+		m.processDashboardReinstall(ctx, op)
+
+	case "stop":
+		m.processStop(ctx, op)
+
+	case "destroy":
+		m.processDestroy(ctx, op)
+
+	case "dashboard_uninstall":
+		m.processDashboardUninstall(ctx, op)
+
+	case "grace_uninstall":
+		m.processGraceUninstall(ctx, op)
+
+	case "uninstall":
+		m.processUninstall(ctx, op)
+
+	case "package_install":
+		m.processPackageInstall(ctx, op)
+
+	case "reconcile":
+		m.processReconcile(ctx)
+
+	case "refresh_installed":
+		m.refreshInstalled(ctx)
 	}
 }
 
@@ -257,7 +385,7 @@ func (m *Monitor) processContainerStart(ctx context.Context, op *AppOperation) {
 	}
 
 	// Check if already installed in fnOS
-	if m.installer != nil && m.installer.IsAppInstalled(appName) {
+	if m.isAppInstalled(ctx, appName) {
 		// Already installed, transfer ownership to this new container.
 		// Clear the app association from any previous container so that when the
 		// old container is later destroyed it does not uninstall the live app.
@@ -272,6 +400,10 @@ func (m *Monitor) processContainerStart(ctx context.Context, op *AppOperation) {
 			}
 			return true
 		})
+		if m.cancelUninstall(appName) {
+			slog.Info("New container adopted app within grace period", "app", appName, "container", op.ContainerName)
+		}
+		m.state.ClearUninstallFailure(appName)
 		slog.Info("App already installed, starting", "app", appName)
 		if v, ok := m.containers.Load(op.ContainerID); ok {
 			state := v.(*ContainerState)
@@ -284,37 +416,97 @@ func (m *Monitor) processContainerStart(ctx context.Context, op *AppOperation) {
 		} else {
 			m.registerAppFromLabels(appName, op.ContainerID, op.ContainerName, op.Labels)
 		}
+		// Upgrade in place if labels, image or stored config changed since install
+		if err := m.upgradeIfChanged(ctx, op, appName); err != nil {
+			upgrade := &AppOperation{
+				Type:          "upgrade",
+				AppName:       appName,
+				ContainerID:   op.ContainerID,
+				ContainerName: op.ContainerName,
+				Labels:        op.Labels,
+				StoredConfig:  op.StoredConfig,
+			}
+			m.opQueue.stamp(upgrade)
+			m.retryOperation(upgrade, err)
+		}
+		if m.consumeLifecycleMarker(op.ContainerName, "start") {
+			slog.Info("Container started from fnOS, skipping appcenter-cli start", "app", appName)
+			m.markInstalled(appName, "", "running")
+			return
+		}
 		if m.installer != nil {
-			m.installer.StartApp(appName)
+			if err := m.installer.StartApp(ctx, appName); err != nil {
+				m.retryOperation(op, err)
+			} else {
+				m.markInstalled(appName, "", "running")
+			}
 		}
 		return
 	}
 
 	// Not installed, update state as pending
-	if v, ok := m.containers.Load(op.ContainerID); ok {
-		state := v.(*ContainerState)
-		state.AppName = appName
-		state.Installed = false
+	v, ok := m.containers.Load(op.ContainerID)
+	if !ok {
+		slog.Debug("Container not tracked, skipping install", "id", op.ContainerID)
+		return
 	}
+	state := v.(*ContainerState)
+	state.AppName = appName
+	state.Installed = false
 
-	// Generate app package
-	time.Sleep(2 * time.Second)
-
-	var config *fpkgen.AppConfig
-	var appDir string
-	var err error
-
+	// Register as pending so the app is visible while waiting for the container
 	if op.StoredConfig != nil {
-		// Generate from stored config
-		config, appDir, err = m.generateFromStoredConfig(ctx, op.ContainerID, op.StoredConfig)
+		m.registerAppFromStoredConfig(op.StoredConfig, op.ContainerID, op.ContainerName)
 	} else {
-		// Generate from container labels
-		config, appDir, err = m.generator.GenerateFromContainer(ctx, op.ContainerID)
+		m.registerAppFromLabels(appName, op.ContainerID, op.ContainerName, op.Labels)
+	}
+
+	// Check labels before waiting, so a misconfigured container is reported at once
+	if op.StoredConfig == nil {
+		if err := m.checkLabels(op); err != nil {
+			slog.Error("Not installing fnOS app, fix the container labels", "container", op.ContainerName, "error", err)
+			m.registry.UpdateStatus(appName, app.StatusInstallFailed)
+			return
+		}
+	}
+	m.registry.UpdateStatus(appName, app.StatusPending)
+	m.notReady.Delete(op.ContainerID)
+
+	// Wait for the container to become ready outside the worker, then queue the install
+	m.startReadinessWait(ctx, op, state)
+}
+
+// processInstall generates the app package and installs it once the container is ready
+func (m *Monitor) processInstall(ctx context.Context, op *AppOperation) {
+	v, exists := m.containers.Load(op.ContainerID)
+	if !exists {
+		slog.Info("Container destroyed while waiting, skipping install", "container", op.ContainerName)
+		return
+	}
+	state := v.(*ContainerState)
+	if state.Installed {
+		slog.Debug("App already installed, skipping install", "container", op.ContainerName)
+		return
 	}
 
+	// Generate app package. On failure the container stays tracked, so stop and
+	// destroy are still handled and the next start tries again.
+	config, err := m.buildAppConfig(ctx, op)
+	if errors.Is(err, fpkgen.ErrInvalidLabels) {
+		slog.Error("Not installing fnOS app, fix the container labels", "container", op.ContainerName, "error", err)
+		m.registry.UpdateStatus(state.AppName, app.StatusInstallFailed)
+		return
+	}
 	if err != nil {
 		slog.Error("Failed to generate fnOS app", "container", op.ContainerName, "error", err)
-		m.containers.Delete(op.ContainerID)
+		m.registry.UpdateStatus(state.AppName, app.StatusInstallFailed)
+		return
+	}
+
+	appDir, err := m.generator.GenerateToTempDir(config)
+	if err != nil {
+		slog.Error("Failed to generate fnOS app", "container", op.ContainerName, "error", err)
+		m.registry.UpdateStatus(state.AppName, app.StatusInstallFailed)
 		return
 	}
 
@@ -328,14 +520,22 @@ func (m *Monitor) processContainerStart(ctx context.Context, op *AppOperation) {
 	// Install
 	slog.Info("Installing fnOS app", "app", config.AppName)
 	if m.installer != nil {
-		if err := m.installer.InstallLocal(appDir); err != nil {
+		if err := m.installer.InstallLocal(ctx, appDir); err != nil {
 			slog.Error("Failed to install fnOS app", "app", config.AppName, "error", err)
+			m.registry.UpdateStatus(config.AppName, app.StatusInstallFailed)
+			m.retryOperation(op, err)
 		} else {
 			if v, exists := m.containers.Load(op.ContainerID); exists {
 				state := v.(*ContainerState)
 				state.Installed = true
 				state.AppName = config.AppName
 			}
+			fingerprint := fpkgen.Fingerprint(config)
+			m.state.SetFingerprint(config.AppName, fingerprint)
+			m.state.Unpin(config.AppName)
+			m.storePackage(config, fingerprint, appDir)
+			m.markInstalled(config.AppName, config.Version, "running")
+			m.queueInstalledRefresh()
 			// Register app in registry
 			m.registerAppFromConfig(config, op.ContainerID, op.ContainerName)
 			slog.Info("Successfully installed fnOS app", "app", config.AppName)
@@ -344,12 +544,90 @@ func (m *Monitor) processContainerStart(ctx context.Context, op *AppOperation) {
 	os.RemoveAll(appDir)
 }
 
-// generateFromStoredConfig generates an app package from stored config.
-func (m *Monitor) generateFromStoredConfig(ctx context.Context, containerID string, storedCfg *StoredConfig) (*fpkgen.AppConfig, string, error) {
+// processUpgrade retries a failed upgrade, unless the container gave up the app since.
+func (m *Monitor) processUpgrade(ctx context.Context, op *AppOperation) {
+	v, exists := m.containers.Load(op.ContainerID)
+	if !exists {
+		slog.Info("Container destroyed, skipping upgrade", "app", op.AppName)
+		return
+	}
+	if state := v.(*ContainerState); !state.Installed || state.AppName != op.AppName {
+		slog.Info("App no longer owned by container, skipping upgrade", "app", op.AppName, "container", op.ContainerName)
+		return
+	}
+	if err := m.upgradeIfChanged(ctx, op, op.AppName); err != nil {
+		m.retryOperation(op, err)
+	}
+}
+
+// upgradeIfChanged regenerates the package of an installed app and upgrades it in place
+// when its fingerprint differs from the installed one. Apps installed before fingerprints
+// were recorded are adopted as-is. Returns an error if the upgrade should be retried.
+func (m *Monitor) upgradeIfChanged(ctx context.Context, op *AppOperation, appName string) error {
+	if m.installer == nil {
+		return nil
+	}
+
+	config, err := m.buildAppConfig(ctx, op)
+	if err != nil {
+		slog.Warn("Failed to build app config, skipping upgrade check", "app", appName, "error", err)
+		return nil
+	}
+
+	fingerprint := fpkgen.Fingerprint(config)
+	installed := m.state.Fingerprint(appName)
+	if installed == "" {
+		m.state.SetFingerprint(appName, fingerprint)
+		return nil
+	}
+	if installed == fingerprint {
+		return nil
+	}
+	if m.state.Pinned(appName) == fingerprint {
+		slog.Debug("App was rolled back from this config, skipping upgrade", "app", appName)
+		return nil
+	}
+
+	slog.Info("App config changed, upgrading fnOS app", "app", appName)
+	appDir, err := m.generator.GenerateToTempDir(config)
+	if err != nil {
+		slog.Error("Failed to generate fnOS app for upgrade", "app", appName, "error", err)
+		return err
+	}
+	defer os.RemoveAll(appDir)
+
+	if err := m.installer.Upgrade(ctx, appDir); err != nil {
+		slog.Error("Failed to upgrade fnOS app", "app", appName, "error", err)
+		return err
+	}
+
+	m.state.SetFingerprint(appName, fingerprint)
+	m.state.Unpin(appName)
+	m.storePackage(config, fingerprint, appDir)
+	m.markInstalled(appName, config.Version, "")
+	m.queueInstalledRefresh()
+	m.registerAppFromConfig(config, op.ContainerID, op.ContainerName)
+	slog.Info("Successfully upgraded fnOS app", "app", appName)
+	return nil
+}
+
+// buildAppConfig builds the app config for an operation from stored config or container labels.
+func (m *Monitor) buildAppConfig(ctx context.Context, op *AppOperation) (*fpkgen.AppConfig, error) {
+	if op.StoredConfig != nil {
+		return m.configFromStoredConfig(ctx, op.ContainerID, op.StoredConfig)
+	}
+	if err := m.checkLabels(op); err != nil {
+		return nil, err
+	}
+	return m.generator.ConfigFromContainer(ctx, op.ContainerID)
+}
+
+// configFromStoredConfig builds an app config from stored config.
+func (m *Monitor) configFromStoredConfig(ctx context.Context, containerID string, storedCfg *StoredConfig) (*fpkgen.AppConfig, error) {
 	// Inspect container for runtime info
 	info, err := m.cli.ContainerInspect(ctx, containerID)
 	if err != nil {
-		return nil, "", fmt.Errorf("failed to inspect container: %w", err)
+		return nil, fmt.Errorf("failed to inspect container: %w", err)
 	}
 
 	// Convert StoredConfig to fpkgen.AppConfig
@@ -363,6 +641,10 @@ func (m *Monitor) generateFromStoredConfig(ctx context.Context, containerID stri
 		ContainerName: strings.TrimPrefix(info.Name, "/"),
 		Image:         info.Config.Image,
 		Icon:          storedCfg.IconBase64, // Base64 data from dashboard upload → Base64IconSource
+		Lifecycle:     fpkgen.LifecycleFromLabels(info.Config.Labels),
+		Arch:          m.generator.ResolveArch(ctx, info.Config.Labels, info.Image),
+		Manifest:      storedCfg.Manifest,
+		TemplateSet:   info.Config.Labels["watchcow.template_set"],
 		Entries:       make([]fpkgen.Entry, 0, len(storedCfg.Entries)),
 	}
 
@@ -384,24 +666,16 @@ func (m *Monitor) generateFromStoredConfig(ctx context.Context, containerID stri
 		config.Entries = append(config.Entries, entry)
 	}
 
+	volumes := fpkgen.VolumesFromMounts(info.Mounts)
+	config.Shares = fpkgen.SharesFromLabels(info.Config.Labels, volumes)
+	config.Wizard = fpkgen.WizardFromLabels(info.Config.Labels, volumes)
+
 	// Set default entry title if empty
 	if len(config.Entries) > 0 && config.Entries[0].Title == "" {
 		config.Entries[0].Title = config.DisplayName
 	}
 
-	// Create temp directory for app package
-	appDir, err := os.MkdirTemp("", "watchcow-app-*")
-	if err != nil {
-		return nil, "", fmt.Errorf("failed to create temp dir: %w", err)
-	}
-
-	// Generate package files
-	if err := m.generator.GenerateFromConfig(config, appDir); err != nil {
-		os.RemoveAll(appDir)
-		return nil, "", fmt.Errorf("failed to generate package: %w", err)
-	}
-
-	return config, appDir, nil
+	return config, nil
 }
 
 // registerAppFromStoredConfig creates and registers an App instance from stored config.
@@ -439,7 +713,7 @@ func (m *Monitor) registerAppFromStoredConfig(storedCfg *StoredConfig, container
 }
 
 // processStop handles stop operation
-func (m *Monitor) processStop(op *AppOperation) {
+func (m *Monitor) processStop(ctx context.Context, op *AppOperation) {
 	v, exists := m.containers.Load(op.ContainerID)
 	if !exists {
 		slog.Debug("Container not tracked, skipping stop", "id", op.ContainerID)
@@ -450,14 +724,26 @@ func (m *Monitor) processStop(op *AppOperation) {
 		slog.Debug("Container not installed, skipping stop", "id", op.ContainerID)
 		return
 	}
+	if m.consumeLifecycleMarker(state.ContainerName, "stop") {
+		slog.Info("Container stopped from fnOS, skipping appcenter-cli stop", "app", state.AppName)
+		m.markInstalled(state.AppName, "", "stopped")
+		return
+	}
 	slog.Info("Stopping fnOS app", "app", state.AppName)
 	if m.installer != nil {
-		m.installer.StopApp(state.AppName)
+		if err := m.installer.StopApp(ctx, state.AppName); err != nil {
+			m.retryOperation(op, err)
+		} else {
+			m.markInstalled(state.AppName, "", "stopped")
+		}
 	}
 }
 
 // processDestroy handles destroy operation
-func (m *Monitor) processDestroy(op *AppOperation) {
+func (m *Monitor) processDestroy(ctx context.Context, op *AppOperation) {
+	m.labelReports.Delete(op.ContainerID)
+	m.notReady.Delete(op.ContainerID)
+
 	v, exists := m.containers.Load(op.ContainerID)
 	if !exists {
 		slog.Debug("Container not tracked, skipping destroy", "id", op.ContainerID)
@@ -475,20 +761,32 @@ func (m *Monitor) processDestroy(op *AppOperation) {
 	m.registry.Unregister(appName)
 	slog.Debug("Unregistered app from registry", "app", appName)
 
-	// Uninstall if was installed
+	// Uninstall if was installed, giving a replacement container time to adopt the app
 	if wasInstalled && m.installer != nil {
+		if m.destroyGrace > 0 {
+			m.scheduleUninstall(appName)
+			return
+		}
 		slog.Info("Uninstalling fnOS app", "app", appName)
-		m.installer.Uninstall(appName)
+		m.uninstallApp(ctx, appName, 0)
 	}
 }
 
-// queueOperation sends an operation to the worker (fire and forget, no wait)
+// queueOperation sends an operation to the worker (fire and forget, no wait).
+// Pending operations for the same container or app are coalesced; none are dropped.
 func (m *Monitor) queueOperation(op *AppOperation) {
-	select {
-	case m.opQueue <- op:
-	default:
-		slog.Warn("Operation queue full, dropping operation", "type", op.Type, "app", op.AppName)
-	}
+	m.opQueue.push(op)
+
+	// A newer operation for the same container or app replaces an earlier failure
+	// and a retry still waiting for its backoff
+	key := operationKey(op)
+	m.cancelRetry(key)
+	m.state.RemoveFailedOperation(key)
+}
+
+// QueueDepth returns the number of operations waiting for the worker.
+func (m *Monitor) QueueDepth() int {
+	return m.opQueue.len()
 }
 
 // Start starts monitoring Docker containers
@@ -498,15 +796,81 @@ func (m *Monitor) Start(ctx context.Context) {
 	// Start operation worker for serializing all state changes
 	go m.runOperationWorker(ctx)
 
-	// Initial scan to process existing containers
-	m.scanContainers(ctx)
+	// Events that happen while scanning are replayed once the stream is up
+	subscribeFrom := time.Now()
+
+	// Initial scan to process existing containers. If Docker is not up yet,
+	// the event listener lists them again once the daemon answers.
+	if err := m.scanContainers(ctx); err != nil {
+		slog.Warn("Deferring reconciliation until containers can be listed", "error", err)
+	} else {
+		m.startReconciling()
+	}
 
 	// Start listening to Docker events for real-time updates
-	go m.listenToDockerEvents(ctx)
+	go m.listenToDockerEvents(ctx, subscribeFrom)
+
+	// Keep the installed app cache fresh for the dashboard
+	go m.runInstalledRefresh(ctx)
+}
+
+const (
+	// eventReconnectDelay is the wait between event stream reconnect attempts
+	eventReconnectDelay = 5 * time.Second
+
+	// maxEventReplayGap is the longest stream outage bridged by replaying events.
+	// Docker keeps only a small in-memory event buffer, so longer outages
+	// (or a daemon restart, which empties the buffer) need a full resync.
+	maxEventReplayGap = 5 * time.Minute
+)
+
+// listenToDockerEvents listens to Docker daemon events, starting from the given time.
+// On stream errors it reconnects and replays events since the last processed one,
+// or resyncs all containers when the gap cannot be bridged by replay.
+func (m *Monitor) listenToDockerEvents(ctx context.Context, since time.Time) {
+	cursor := since
+	for !m.listed.Load() {
+		// The startup scan failed; list the containers once the daemon answers
+		if _, ok := m.waitForDaemon(ctx); !ok {
+			return
+		}
+		cursor = time.Now()
+		m.resyncContainers(ctx)
+	}
+
+	for {
+		lastEvent, err := m.consumeDockerEvents(ctx, cursor)
+		if err == nil {
+			return // context cancelled or monitor stopped
+		}
+		if lastEvent.After(cursor) {
+			cursor = lastEvent
+		}
+
+		disconnectedAt := time.Now()
+		slog.Warn("Docker event stream error, reconnecting...", "error", err)
+
+		daemonLost, ok := m.waitForDaemon(ctx)
+		if !ok {
+			return
+		}
+
+		gap := time.Since(disconnectedAt)
+		if daemonLost || gap > maxEventReplayGap {
+			slog.Info("Event gap cannot be replayed, resyncing containers", "gap", gap.Round(time.Second), "daemon_lost", daemonLost)
+			cursor = time.Now()
+			m.resyncContainers(ctx)
+			continue
+		}
+
+		slog.Info("Replaying Docker events", "since", cursor.Format(time.RFC3339Nano))
+	}
 }
 
-// listenToDockerEvents listens to Docker daemon events
-func (m *Monitor) listenToDockerEvents(ctx context.Context) {
+// consumeDockerEvents subscribes to container events since the given time and handles them
+// until the stream fails. Returns the time of the last handled event and the stream error,
+// or a nil error if the context was cancelled or the monitor stopped.
+func (m *Monitor) consumeDockerEvents(ctx context.Context, since time.Time) (time.Time, error) {
 	// Set up event filters
 	eventFilters := filters.NewArgs()
 	eventFilters.Add("type", "container")
@@ -516,28 +880,66 @@ func (m *Monitor) listenToDockerEvents(ctx context.Context) {
 	eventFilters.Add("event", "destroy")
 
 	eventChan, errChan := m.cli.Events(ctx, events.ListOptions{
+		Since:   formatEventTime(since),
 		Filters: eventFilters,
 	})
 
+	lastEvent := since
 	for {
 		select {
 		case <-ctx.Done():
-			return
+			return lastEvent, nil
 		case <-m.stopCh:
-			return
+			return lastEvent, nil
 		case err := <-errChan:
-			if err != nil {
-				slog.Warn("Docker event stream error, reconnecting...", "error", err)
-				time.Sleep(5 * time.Second)
-				go m.listenToDockerEvents(ctx)
-				return
+			if err == nil || ctx.Err() != nil {
+				return lastEvent, nil
 			}
+			return lastEvent, err
 		case event := <-eventChan:
+			eventTime := time.Unix(0, event.TimeNano)
+			// Replayed events up to the cursor were already handled
+			if event.TimeNano != 0 && !eventTime.After(since) {
+				continue
+			}
 			m.handleDockerEvent(ctx, event)
+			if eventTime.After(lastEvent) {
+				lastEvent = eventTime
+			}
 		}
 	}
 }
 
+// waitForDaemon blocks until the Docker daemon answers a ping.
+// Returns daemonLost=true if the daemon was unreachable at least once (e.g. restarted),
+// and ok=false if the context was cancelled or the monitor stopped while waiting.
+func (m *Monitor) waitForDaemon(ctx context.Context) (daemonLost bool, ok bool) {
+	for {
+		select {
+		case <-ctx.Done():
+			return daemonLost, false
+		case <-m.stopCh:
+			return daemonLost, false
+		case <-time.After(eventReconnectDelay):
+		}
+
+		if _, err := m.cli.Ping(ctx); err != nil {
+			slog.Debug("Docker daemon not reachable", "error", err)
+			daemonLost = true
+			continue
+		}
+		return daemonLost, true
+	}
+}
+
+// formatEventTime formats a time as a Docker events "since" filter (seconds.nanoseconds)
+func formatEventTime(t time.Time) string {
+	if t.IsZero() {
+		return ""
+	}
+	return fmt.Sprintf("%d.%09d", t.Unix(), t.Nanosecond())
+}
+
 // handleDockerEvent processes a Docker event
 func (m *Monitor) handleDockerEvent(ctx context.Context, event events.Message) {
 	containerName := event.Actor.Attributes["name"]
@@ -578,26 +980,7 @@ func (m *Monitor) handleDockerEvent(ctx context.Context, event events.Message) {
 		state.NetworkMode = string(info.HostConfig.NetworkMode)
 		m.containers.Store(containerID, state)
 
-		// Check if should install: either has label config or has stored config
-		hasLabelConfig := shouldInstall(info.Config.Labels)
-		storedConfig := m.getStoredConfig(info.Config.Image, ports)
-
-		if hasLabelConfig {
-			m.queueOperation(&AppOperation{
-				Type:          "container_start",
-				ContainerID:   containerID,
-				ContainerName: containerName,
-				Labels:        info.Config.Labels,
-			})
-		} else if storedConfig != nil {
-			m.queueOperation(&AppOperation{
-				Type:          "container_start",
-				ContainerID:   containerID,
-				ContainerName: containerName,
-				Labels:        info.Config.Labels,
-				StoredConfig:  storedConfig,
-			})
-		}
+		m.queueContainerStart(state)
 
 	case "stop", "die":
 		slog.Info("Container stopped", "container", containerName, "id", containerID)
@@ -625,6 +1008,35 @@ func (m *Monitor) handleDockerEvent(ctx context.Context, event events.Message) {
 	}
 }
 
+// queueContainerStart queues a container_start operation if the container
+// is configured by labels or has a stored dashboard config.
+// Returns false if the container is not managed by WatchCow.
+func (m *Monitor) queueContainerStart(state *ContainerState) bool {
+	// Check if should install: either has label config or has stored config
+	if shouldInstall(state.Labels) {
+		m.queueOperation(&AppOperation{
+			Type:          "container_start",
+			ContainerID:   state.ContainerID,
+			ContainerName: state.ContainerName,
+			Labels:        state.Labels,
+		})
+		return true
+	}
+
+	if storedConfig := m.getStoredConfig(state.Image, state.Ports); storedConfig != nil {
+		m.queueOperation(&AppOperation{
+			Type:          "container_start",
+			ContainerID:   state.ContainerID,
+			ContainerName: state.ContainerName,
+			Labels:        state.Labels,
+			StoredConfig:  storedConfig,
+		})
+		return true
+	}
+
+	return false
+}
+
 // extractPorts extracts port mappings from container network settings
 func extractPorts(portMap nat.PortMap) map[string]string {
 	ports := make(map[string]string)
@@ -638,94 +1050,145 @@ func extractPorts(portMap nat.PortMap) map[string]string {
 	return ports
 }
 
-// getAppNameFromLabels extracts appName from labels
+// getAppNameFromLabels extracts appName from labels.
+// Must match the name fpkgen generates, otherwise installed apps are not recognized.
 func getAppNameFromLabels(labels map[string]string, containerName string) string {
-	appName := labels["watchcow.appname"]
-	if appName == "" {
-		appName = "watchcow." + containerName
-	}
-	return appName
+	return fpkgen.AppNameFromLabels(labels, containerName)
 }
 
 // shouldInstall checks if a container should be installed as fnOS app
 func shouldInstall(labels map[string]string) bool {
-	// Check watchcow.enable label
-	if labels["watchcow.enable"] != "true" {
-		return false
-	}
-
-	// Check watchcow.install label (default to "fnos" if enable is true)
-	installMode := labels["watchcow.install"]
-	return installMode == "fnos" || installMode == "true" || installMode == ""
+	return fpkgen.ShouldInstall(labels)
 }
 
 // scanContainers scans all containers and populates the state map
-func (m *Monitor) scanContainers(ctx context.Context) {
+func (m *Monitor) scanContainers(ctx context.Context) error {
 	containers, err := m.cli.ContainerList(ctx, container.ListOptions{All: true})
 	if err != nil {
-		slog.Error("Failed to list containers", "error", err)
-		return
+		return fmt.Errorf("list containers: %w", err)
 	}
 
 	slog.Info("Scanning existing containers...", "count", len(containers))
 
 	for _, ctr := range containers {
-		containerID := ctr.ID[:12]
-		containerName := strings.TrimPrefix(ctr.Names[0], "/")
-
-		// Extract port mappings
-		ports := make(map[string]string)
-		for _, p := range ctr.Ports {
-			if p.PublicPort > 0 {
-				containerPort := fmt.Sprintf("%d", p.PrivatePort)
-				hostPort := fmt.Sprintf("%d", p.PublicPort)
-				ports[containerPort] = hostPort
-			}
-		}
+		state := containerStateFromSummary(ctr)
 
 		// Add to state map
-		m.containers.Store(containerID, &ContainerState{
-			ContainerID:   containerID,
-			ContainerName: containerName,
-			Image:         ctr.Image,
-			State:         ctr.State,
-			Ports:         ports,
-			Labels:        ctr.Labels,
-		})
+		m.containers.Store(state.ContainerID, state)
 
 		// Only process running containers
-		if ctr.State != "running" {
+		if state.State != "running" {
 			continue
 		}
 
-		// Check if should install: either has label config or has stored config
-		hasLabelConfig := shouldInstall(ctr.Labels)
-		storedConfig := m.getStoredConfig(ctr.Image, ports)
+		if m.queueContainerStart(state) {
+			slog.Info("Found WatchCow container", "container", state.ContainerName)
+		}
+	}
+	return nil
+}
 
-		if hasLabelConfig {
-			slog.Info("Found label-configured container", "container", containerName)
+// startReconciling resumes the grace periods from before a restart and queues the
+// first reconciliation. Called once, after containers were first listed, so both
+// see the app names claimed by existing containers.
+func (m *Monitor) startReconciling() {
+	if m.listed.Swap(true) {
+		return
+	}
+
+	// Continue grace periods; containers found by the scan are already queued
+	// for adoption before expired periods end
+	m.resumeGraceUninstalls()
+
+	// Clean up apps whose containers were removed while WatchCow was down
+	m.TriggerReconcile()
+}
+
+// resyncContainers diffs the tracked state against a fresh container list and
+// queues the start, stop and destroy operations for changes that were missed.
+func (m *Monitor) resyncContainers(ctx context.Context) {
+	containers, err := m.cli.ContainerList(ctx, container.ListOptions{All: true})
+	if err != nil {
+		slog.Error("Failed to list containers for resync", "error", err)
+		return
+	}
+
+	seen := make(map[string]bool, len(containers))
+	for _, ctr := range containers {
+		fresh := containerStateFromSummary(ctr)
+		seen[fresh.ContainerID] = true
+
+		v, tracked := m.containers.Load(fresh.ContainerID)
+		if !tracked {
+			m.containers.Store(fresh.ContainerID, fresh)
+			if fresh.State == "running" && m.queueContainerStart(fresh) {
+				slog.Info("Resync: found new container", "container", fresh.ContainerName)
+			}
+			continue
+		}
+
+		state := v.(*ContainerState)
+		wasRunning := state.State == "running"
+		state.State = fresh.State
+
+		switch {
+		case fresh.State == "running" && !wasRunning:
+			slog.Info("Resync: container started while disconnected", "container", state.ContainerName)
+			m.queueContainerStart(state)
+		case fresh.State != "running" && wasRunning:
+			slog.Info("Resync: container stopped while disconnected", "container", state.ContainerName)
 			m.queueOperation(&AppOperation{
-				Type:          "container_start",
-				ContainerID:   containerID,
-				ContainerName: containerName,
-				Labels:        ctr.Labels,
+				Type:        "stop",
+				ContainerID: state.ContainerID,
 			})
-		} else if storedConfig != nil {
-			slog.Info("Found storage-configured container", "container", containerName)
+		}
+	}
+
+	// Containers that disappeared were destroyed during the gap
+	m.containers.Range(func(key, value any) bool {
+		containerID := key.(string)
+		if !seen[containerID] {
+			slog.Info("Resync: container destroyed while disconnected", "container", value.(*ContainerState).ContainerName)
 			m.queueOperation(&AppOperation{
-				Type:          "container_start",
-				ContainerID:   containerID,
-				ContainerName: containerName,
-				Labels:        ctr.Labels,
-				StoredConfig:  storedConfig,
+				Type:        "destroy",
+				ContainerID: containerID,
 			})
 		}
+		return true
+	})
+
+	slog.Info("Resync completed", "containers", len(containers))
+	m.startReconciling()
+}
+
+// containerStateFromSummary builds a ContainerState from a container list entry
+func containerStateFromSummary(ctr container.Summary) *ContainerState {
+	// Extract port mappings
+	ports := make(map[string]string)
+	for _, p := range ctr.Ports {
+		if p.PublicPort > 0 {
+			containerPort := fmt.Sprintf("%d", p.PrivatePort)
+			hostPort := fmt.Sprintf("%d", p.PublicPort)
+			ports[containerPort] = hostPort
+		}
+	}
+
+	return &ContainerState{
+		ContainerID:   ctr.ID[:12],
+		ContainerName: strings.TrimPrefix(ctr.Names[0], "/"),
+		Image:         ctr.Image,
+		State:         ctr.State,
+		Ports:         ports,
+		Labels:        ctr.Labels,
+		NetworkMode:   ctr.HostConfig.NetworkMode,
 	}
 }
 
 // Stop stops the monitor
 func (m *Monitor) Stop() {
 	close(m.stopCh)
+	m.stopRetries()
+	m.stopGraceTimers()
 
 	if m.generator != nil {
 		m.generator.Close()
@@ -854,7 +1317,7 @@ func (m *Monitor) TriggerUninstall(appName string) {
 }
 
 // processDashboardUninstall handles uninstall triggered from dashboard.
-func (m *Monitor) processDashboardUninstall(op *AppOperation) {
+func (m *Monitor) processDashboardUninstall(ctx context.Context, op *AppOperation) {
 	appName := op.AppName
 	if appName == "" {
 		return
@@ -862,13 +1325,23 @@ func (m *Monitor) processDashboardUninstall(op *AppOperation) {
 
 	slog.Info("Processing dashboard uninstall", "app", appName)
 
+	// Uninstall from fnOS. On failure the app stays registered (marked as failed) and
+	// keeps its container association; the dashboard uninstall itself is retried.
+	m.cancelUninstall(appName)
+	if m.installer != nil {
+		if err := m.tryUninstall(ctx, appName, op.Attempt); err != nil {
+			m.retryOperation(op, err)
+			return
+		}
+	}
+
 	// Unregister from app registry
 	m.registry.Unregister(appName)
 
-	// Uninstall from fnOS
-	if m.installer != nil {
-		m.installer.Uninstall(appName)
-	}
+	// No longer awaiting review if it was an orphan
+	m.orphans.mu.Lock()
+	delete(m.orphans.apps, appName)
+	m.orphans.mu.Unlock()
 
 	// Clear installed state for any container with this app name
 	m.containers.Range(func(key, value any) bool {
@@ -883,25 +1356,27 @@ func (m *Monitor) processDashboardUninstall(op *AppOperation) {
 	slog.Info("Dashboard uninstall completed", "app", appName)
 }
 
-// processDashboardReinstall handles config update: uninstall old app, then install with new config.
+// processDashboardReinstall handles config update. If the app name is unchanged the app is
+// upgraded in place; otherwise the old app is uninstalled and the new one installed.
 func (m *Monitor) processDashboardReinstall(ctx context.Context, op *AppOperation) {
 	oldAppName := op.AppName
 
-	// Step 1: Uninstall the old app
-	slog.Info("Uninstalling old app for reinstall", "app", oldAppName)
-	m.registry.Unregister(oldAppName)
-	if m.installer != nil {
-		m.installer.Uninstall(oldAppName)
-	}
+	if op.StoredConfig == nil || op.StoredConfig.AppName != oldAppName {
+		// Step 1: Uninstall the old app
+		// A failed uninstall is tracked and retried; the new app is installed regardless.
+		slog.Info("Uninstalling old app for reinstall", "app", oldAppName)
+		m.registry.Unregister(oldAppName)
+		m.uninstallApp(ctx, oldAppName, 0)
 
-	// Clear installed state
-	if v, ok := m.containers.Load(op.ContainerID); ok {
-		state := v.(*ContainerState)
-		state.AppName = ""
-		state.Installed = false
+		// Clear installed state
+		if v, ok := m.containers.Load(op.ContainerID); ok {
+			state := v.(*ContainerState)
+			state.AppName = ""
+			state.Installed = false
+		}
 	}
 
-	// Step 2: Install with new config (reuse processContainerStart logic)
-	slog.Info("Installing app with new config", "container", op.ContainerName)
+	// Step 2: Install or upgrade with new config (reuse processContainerStart logic)
+	slog.Info("Applying new config", "container", op.ContainerName)
 	m.processContainerStart(ctx, op)
 }
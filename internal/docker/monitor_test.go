@@ -0,0 +1,581 @@
+package docker
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"watchcow/internal/app"
+	"watchcow/internal/fpkgen"
+)
+
+// fakeInstaller is an in-memory AppInstaller that records calls.
+type fakeInstaller struct {
+	mu        sync.Mutex
+	installed map[string]bool
+	calls     []string
+	failOn    map[string]error // method -> error to return
+}
+
+func newFakeInstaller(apps ...string) *fakeInstaller {
+	f := &fakeInstaller{installed: make(map[string]bool), failOn: make(map[string]error)}
+	for _, a := range apps {
+		f.installed[a] = true
+	}
+	return f
+}
+
+func (f *fakeInstaller) record(call string) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.calls = append(f.calls, call)
+	method, _, _ := strings.Cut(call, " ")
+	return f.failOn[method]
+}
+
+func (f *fakeInstaller) StartApp(ctx context.Context, appName string) error {
+	return f.record("start " + appName)
+}
+func (f *fakeInstaller) StopApp(ctx context.Context, appName string) error {
+	return f.record("stop " + appName)
+}
+
+func (f *fakeInstaller) InstallLocal(ctx context.Context, appDir string) error {
+	appName := manifestAppName(appDir)
+	if err := f.record("install-local " + appName); err != nil {
+		return err
+	}
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.installed[appName] = true
+	return nil
+}
+
+func (f *fakeInstaller) Upgrade(ctx context.Context, appDir string) error {
+	return f.record("upgrade " + manifestAppName(appDir))
+}
+
+func (f *fakeInstaller) Uninstall(ctx context.Context, appName string) (*fpkgen.UninstallResult, error) {
+	result := &fpkgen.UninstallResult{AppName: appName}
+	if err := f.record("uninstall " + appName); err != nil {
+		return result, err
+	}
+	f.mu.Lock()
+	delete(f.installed, appName)
+	f.mu.Unlock()
+	result.Verified = true
+	for _, name := range f.Names() {
+		result.Installed = append(result.Installed, InstalledApp{Name: name, Version: "1.0.0", Status: "running"})
+	}
+	return result, nil
+}
+
+func (f *fakeInstaller) ListInstalled(ctx context.Context) ([]InstalledApp, error) {
+	if err := f.record("list"); err != nil {
+		return nil, err
+	}
+	var apps []InstalledApp
+	for _, name := range f.Names() {
+		apps = append(apps, InstalledApp{Name: name, Version: "1.0.0", Status: "running"})
+	}
+	return apps, nil
+}
+
+// Names returns the installed app names, sorted.
+func (f *fakeInstaller) Names() []string {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	var apps []string
+	for a := range f.installed {
+		apps = append(apps, a)
+	}
+	slices.Sort(apps)
+	return apps
+}
+
+// Has reports whether an app is installed without recording a call.
+func (f *fakeInstaller) Has(appName string) bool {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.installed[appName]
+}
+
+func (f *fakeInstaller) Calls() []string {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return slices.Clone(f.calls)
+}
+
+// Count returns how many times a call was made.
+func (f *fakeInstaller) Count(call string) int {
+	n := 0
+	for _, c := range f.Calls() {
+		if c == call {
+			n++
+		}
+	}
+	return n
+}
+
+// manifestAppName reads the appname from a generated app directory
+func manifestAppName(appDir string) string {
+	data, err := os.ReadFile(filepath.Join(appDir, "manifest"))
+	if err != nil {
+		return ""
+	}
+	for _, line := range strings.Split(string(data), "\n") {
+		if value, ok := strings.CutPrefix(line, "appname="); ok {
+			return strings.TrimSpace(value)
+		}
+	}
+	return ""
+}
+
+// fakeConfigProvider is an in-memory ConfigProvider keyed like the dashboard storage.
+type fakeConfigProvider map[string]*StoredConfig
+
+func (f fakeConfigProvider) GetByKey(key string) *StoredConfig {
+	return f[key]
+}
+
+func (f fakeConfigProvider) AppNamesByImage(image string) []string {
+	var names []string
+	for key, cfg := range f {
+		if strings.SplitN(key, "|", 2)[0] == image {
+			names = append(names, cfg.AppName)
+		}
+	}
+	return names
+}
+
+// newTestMonitor creates a Monitor backed by a fake Docker daemon and installer.
+// The destroy grace period is disabled; tests that need it set m.destroyGrace.
+func newTestMonitor(t *testing.T, cli *fakeDocker, installer AppInstaller) *Monitor {
+	t.Helper()
+	t.Setenv("TRIM_PKGVAR", t.TempDir())
+	t.Setenv("WATCHCOW_DESTROY_GRACE", "0")
+
+	generator, err := fpkgen.NewGeneratorWithClient(cli)
+	if err != nil {
+		t.Fatalf("NewGeneratorWithClient() error = %v", err)
+	}
+	return newMonitor(cli, generator, installer)
+}
+
+// startTestMonitor starts m and stops it when the test ends.
+func startTestMonitor(t *testing.T, m *Monitor) {
+	t.Helper()
+	ctx, cancel := context.WithCancel(context.Background())
+	m.Start(ctx)
+	t.Cleanup(func() {
+		cancel()
+		m.Stop()
+	})
+}
+
+// waitFor polls cond until it holds or the test times out.
+func waitFor(t *testing.T, what string, cond func() bool) {
+	t.Helper()
+	deadline := time.Now().Add(30 * time.Second)
+	for !cond() {
+		if time.Now().After(deadline) {
+			t.Fatalf("timed out waiting for %s", what)
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+// waitIdle waits until the operation queue is drained and in-flight work settled.
+func waitIdle(t *testing.T, m *Monitor) {
+	t.Helper()
+	waitFor(t, "idle operation queue", func() bool { return m.QueueDepth() == 0 })
+	time.Sleep(50 * time.Millisecond)
+}
+
+// syncWorker waits for the worker to list apps in a reconcile, so registry changes
+// it made before are visible to the test without a data race.
+func syncWorker(t *testing.T, m *Monitor, installer *fakeInstaller) {
+	t.Helper()
+	lists := installer.Count("list")
+	m.TriggerReconcile()
+	waitFor(t, "reconcile", func() bool { return installer.Count("list") > lists })
+}
+
+// testLabels returns labels for a WatchCow-managed container that installs immediately.
+func testLabels(appName string) map[string]string {
+	return map[string]string{
+		"watchcow.enable":       "true",
+		"watchcow.appname":      appName,
+		"watchcow.service_port": "8080",
+		"watchcow.wait_for":     "none",
+		"watchcow.icon":         "file:///nonexistent/icon.png",
+	}
+}
+
+// trackInstalled adds a running container that owns an installed app.
+func trackInstalled(m *Monitor, containerID, appName string) {
+	m.containers.Store(containerID, &ContainerState{
+		ContainerID:   containerID,
+		ContainerName: containerID,
+		State:         "running",
+		Labels:        map[string]string{"watchcow.enable": "true", "watchcow.appname": appName},
+		AppName:       appName,
+		Installed:     true,
+	})
+	m.registry.Register(&app.App{AppName: appName, ContainerID: containerID})
+}
+
+func TestProcessDestroy_UninstallsImmediatelyWithoutGrace(t *testing.T) {
+	installer := newFakeInstaller("watchcow.nginx")
+	m := newTestMonitor(t, newFakeDocker(), installer)
+	trackInstalled(m, "abc", "watchcow.nginx")
+
+	m.processDestroy(context.Background(), &AppOperation{Type: "destroy", ContainerID: "abc"})
+
+	if installer.Has("watchcow.nginx") {
+		t.Error("app still installed after destroy")
+	}
+	if m.registry.Get("watchcow.nginx") != nil {
+		t.Error("app still registered after destroy")
+	}
+}
+
+func TestProcessDestroy_UninstallUpdatesInstalledCache(t *testing.T) {
+	installer := newFakeInstaller("watchcow.nginx", "watchcow.redis")
+	m := newTestMonitor(t, newFakeDocker(), installer)
+	trackInstalled(m, "abc", "watchcow.nginx")
+
+	m.processDestroy(context.Background(), &AppOperation{Type: "destroy", ContainerID: "abc"})
+
+	// The verifying list of the uninstall fills the cache, appcenter-cli list is not run again
+	if n := installer.Count("list"); n != 0 {
+		t.Errorf("appcenter-cli list called %d times, want 0", n)
+	}
+	if m.QueueDepth() != 0 {
+		t.Errorf("QueueDepth() = %d, want no installed refresh queued", m.QueueDepth())
+	}
+	if apps := m.InstalledApps(); len(apps) != 1 || apps[0].Name != "watchcow.redis" {
+		t.Errorf("InstalledApps() = %v, want [watchcow.redis]", apps)
+	}
+}
+
+func TestProcessDestroy_GracePeriodDefersUninstall(t *testing.T) {
+	installer := newFakeInstaller("watchcow.nginx")
+	m := newTestMonitor(t, newFakeDocker(), installer)
+	m.destroyGrace = time.Hour
+	trackInstalled(m, "abc", "watchcow.nginx")
+
+	m.processDestroy(context.Background(), &AppOperation{Type: "destroy", ContainerID: "abc"})
+
+	if !installer.Has("watchcow.nginx") {
+		t.Error("app uninstalled during grace period")
+	}
+	if !m.isUninstallPending("watchcow.nginx") {
+		t.Error("uninstall not scheduled after destroy")
+	}
+	m.cancelUninstall("watchcow.nginx")
+}
+
+func TestProcessStop_FailureIsRetried(t *testing.T) {
+	installer := newFakeInstaller("watchcow.nginx")
+	installer.failOn["stop"] = errors.New("exit status 1")
+	m := newTestMonitor(t, newFakeDocker(), installer)
+	trackInstalled(m, "abc", "watchcow.nginx")
+
+	op := &AppOperation{Type: "stop", ContainerID: "abc", Attempt: maxOperationAttempts - 1}
+	m.processStop(context.Background(), op)
+
+	failed := m.FailedOperations()
+	if len(failed) != 1 || failed[0].Operation.Type != "stop" {
+		t.Errorf("FailedOperations() = %+v, want one stop operation", failed)
+	}
+}
+
+func TestProcessUpgrade_FailureIsRetried(t *testing.T) {
+	cli := newFakeDocker()
+	labels := testLabels("watchcow.nginx")
+	cli.Create("aaaaaaaaaaaa", "nginx", "nginx:1.25", labels, map[string]string{"80": "8080"})
+	installer := newFakeInstaller("watchcow.nginx")
+	installer.failOn["upgrade"] = errors.New("exit status 1")
+	m := newTestMonitor(t, cli, installer)
+	trackInstalled(m, "aaaaaaaaaaaa", "watchcow.nginx")
+	m.state.SetFingerprint("watchcow.nginx", "fp-old")
+
+	op := &AppOperation{Type: "upgrade", AppName: "watchcow.nginx", ContainerID: "aaaaaaaaaaaa", ContainerName: "nginx", Labels: labels, Attempt: maxOperationAttempts - 1}
+	m.processUpgrade(context.Background(), op)
+
+	if installer.Count("upgrade watchcow.nginx") != 1 {
+		t.Errorf("calls = %v, want one upgrade", installer.Calls())
+	}
+	failed := m.FailedOperations()
+	if len(failed) != 1 || failed[0].Operation.Type != "upgrade" || failed[0].Key != "app:watchcow.nginx" {
+		t.Errorf("FailedOperations() = %+v, want one upgrade operation", failed)
+	}
+	if fp := m.state.Fingerprint("watchcow.nginx"); fp != "fp-old" {
+		t.Errorf("Fingerprint() = %q, want fp-old after a failed upgrade", fp)
+	}
+}
+
+func TestProcessDashboardUninstall(t *testing.T) {
+	installer := newFakeInstaller("watchcow.nginx")
+	m := newTestMonitor(t, newFakeDocker(), installer)
+	trackInstalled(m, "abc", "watchcow.nginx")
+
+	m.processDashboardUninstall(context.Background(), &AppOperation{Type: "dashboard_uninstall", AppName: "watchcow.nginx"})
+
+	if installer.Has("watchcow.nginx") {
+		t.Error("app still installed after dashboard uninstall")
+	}
+	v, _ := m.containers.Load("abc")
+	if state := v.(*ContainerState); state.Installed || state.AppName != "" {
+		t.Errorf("container state not cleared: %+v", state)
+	}
+}
+
+func TestProcessDashboardUninstall_FailureKeepsApp(t *testing.T) {
+	installer := newFakeInstaller("watchcow.nginx")
+	installer.failOn["uninstall"] = fpkgen.ErrStillInstalled
+	m := newTestMonitor(t, newFakeDocker(), installer)
+	trackInstalled(m, "abc", "watchcow.nginx")
+
+	op := &AppOperation{Type: "dashboard_uninstall", AppName: "watchcow.nginx", Attempt: maxOperationAttempts - 1}
+	m.processDashboardUninstall(context.Background(), op)
+
+	v, _ := m.containers.Load("abc")
+	if state := v.(*ContainerState); !state.Installed || state.AppName != "watchcow.nginx" {
+		t.Errorf("container state cleared after failed uninstall: %+v", state)
+	}
+	if a := m.registry.Get("watchcow.nginx"); a == nil || a.Status != app.StatusUninstallFailed {
+		t.Errorf("registry app = %+v, want status %q", a, app.StatusUninstallFailed)
+	}
+	failures := m.UninstallFailures()
+	if len(failures) != 1 || failures[0].AppName != "watchcow.nginx" || failures[0].Attempts != maxOperationAttempts {
+		t.Errorf("UninstallFailures() = %+v, want watchcow.nginx after %d attempts", failures, maxOperationAttempts)
+	}
+	failed := m.FailedOperations()
+	if len(failed) != 1 || failed[0].Operation.Type != "dashboard_uninstall" {
+		t.Errorf("FailedOperations() = %+v, want one dashboard_uninstall operation", failed)
+	}
+
+	// A later successful uninstall clears the failure
+	delete(installer.failOn, "uninstall")
+	m.processDashboardUninstall(context.Background(), &AppOperation{Type: "dashboard_uninstall", AppName: "watchcow.nginx"})
+	if failures := m.UninstallFailures(); len(failures) != 0 {
+		t.Errorf("UninstallFailures() = %+v after successful uninstall, want none", failures)
+	}
+}
+
+func TestProcessReconcile_UninstallsOrphans(t *testing.T) {
+	installer := newFakeInstaller("watchcow", "watchcow.nginx", "watchcow.gone", "other.app")
+	m := newTestMonitor(t, newFakeDocker(), installer)
+	trackInstalled(m, "abc", "watchcow.nginx")
+	m.listed.Store(true)
+
+	m.processReconcile(context.Background())
+
+	apps := installer.Names()
+	want := []string{"other.app", "watchcow", "watchcow.nginx"}
+	if !slices.Equal(apps, want) {
+		t.Errorf("installed apps = %v, want %v", apps, want)
+	}
+}
+
+func TestMonitor_DashboardUninstallRequiresListedApp(t *testing.T) {
+	installer := newFakeInstaller("watchcow", "watchcow.nginx", "watchcow.gone", "watchcow.stuck", "other.app")
+	m := newTestMonitor(t, newFakeDocker(), installer)
+	m.orphanPolicy = OrphanPolicyReview
+	trackInstalled(m, "abc", "watchcow.nginx")
+	m.listed.Store(true)
+	m.processReconcile(context.Background())
+	m.state.SetUninstallFailure(UninstallFailure{AppName: "watchcow.stuck", Error: "exit status 1"})
+
+	for _, appName := range []string{"watchcow.nginx", "watchcow", "other.app"} {
+		if m.UninstallOrphan(appName) {
+			t.Errorf("UninstallOrphan(%s) = true, want false", appName)
+		}
+	}
+	for _, appName := range []string{"watchcow.nginx", "watchcow", "other.app", "watchcow.gone"} {
+		if m.RetryUninstall(appName) {
+			t.Errorf("RetryUninstall(%s) = true, want false", appName)
+		}
+	}
+	if depth := m.QueueDepth(); depth != 0 {
+		t.Fatalf("QueueDepth() = %d, want 0 after rejected requests", depth)
+	}
+
+	if !m.UninstallOrphan("watchcow.gone") || !m.RetryUninstall("watchcow.stuck") {
+		t.Fatal("listed apps should be queued for uninstall")
+	}
+	for range 2 {
+		op, _ := m.opQueue.pop()
+		m.processOperation(context.Background(), op)
+	}
+	want := []string{"other.app", "watchcow", "watchcow.nginx"}
+	if apps := installer.Names(); !slices.Equal(apps, want) {
+		t.Errorf("installed apps = %v, want %v", apps, want)
+	}
+	if len(m.Orphans()) != 0 || len(m.UninstallFailures()) != 0 {
+		t.Errorf("Orphans() = %v, UninstallFailures() = %v, want both empty", m.Orphans(), m.UninstallFailures())
+	}
+}
+
+func TestMonitor_ListFailureSkipsReconcile(t *testing.T) {
+	cli := newFakeDocker()
+	cli.SetListError(errors.New("Cannot connect to the Docker daemon"))
+	installer := newFakeInstaller("watchcow.nginx")
+	m := newTestMonitor(t, cli, installer)
+
+	startTestMonitor(t, m)
+	m.TriggerReconcile()
+	waitIdle(t, m)
+
+	if !installer.Has("watchcow.nginx") {
+		t.Fatalf("app uninstalled while containers could not be listed, calls = %v", installer.Calls())
+	}
+
+	// The first successful list starts reconciling
+	cli.SetListError(nil)
+	m.resyncContainers(context.Background())
+	waitFor(t, "orphan uninstall", func() bool { return !installer.Has("watchcow.nginx") })
+}
+
+func TestMonitor_StartupKeepsStoppedDashboardApp(t *testing.T) {
+	cli := newFakeDocker()
+	cli.Create("aaaaaaaaaaaa", "nginx", "nginx:1.25", nil, map[string]string{"80": "8080"})
+	installer := newFakeInstaller("watchcow.nginx")
+	m := newTestMonitor(t, cli, installer)
+	m.SetConfigProvider(fakeConfigProvider{
+		makeContainerKey("nginx:1.25", map[string]string{"80": "8080"}): {AppName: "watchcow.nginx"},
+	})
+
+	startTestMonitor(t, m)
+	waitIdle(t, m)
+
+	if !installer.Has("watchcow.nginx") {
+		t.Errorf("app of stopped dashboard container was uninstalled, calls = %v", installer.Calls())
+	}
+}
+
+func TestMonitor_InstallsOnStartupScan(t *testing.T) {
+	cli := newFakeDocker()
+	cli.Create("aaaaaaaaaaaa", "nginx", "nginx:1.25", testLabels("watchcow.nginx"), map[string]string{"80": "8080"})
+	cli.Start("aaaaaaaaaaaa")
+	installer := newFakeInstaller()
+	m := newTestMonitor(t, cli, installer)
+
+	startTestMonitor(t, m)
+
+	waitFor(t, "install", func() bool { return installer.Has("watchcow.nginx") })
+	waitFor(t, "registry", func() bool { return m.Registry().Get("watchcow.nginx") != nil })
+}
+
+func TestMonitor_EventLifecycle(t *testing.T) {
+	cli := newFakeDocker()
+	installer := newFakeInstaller()
+	m := newTestMonitor(t, cli, installer)
+	startTestMonitor(t, m)
+	waitIdle(t, m)
+
+	cli.Create("bbbbbbbbbbbb", "whoami", "traefik/whoami", testLabels("watchcow.whoami"), map[string]string{"80": "8081"})
+	cli.Start("bbbbbbbbbbbb")
+	waitFor(t, "install", func() bool { return installer.Has("watchcow.whoami") })
+
+	cli.Stop("bbbbbbbbbbbb")
+	waitFor(t, "stop", func() bool { return installer.Count("stop watchcow.whoami") > 0 })
+
+	cli.Start("bbbbbbbbbbbb")
+	waitFor(t, "start", func() bool { return installer.Count("start watchcow.whoami") > 0 })
+
+	cli.Stop("bbbbbbbbbbbb")
+	cli.Remove("bbbbbbbbbbbb")
+	waitFor(t, "uninstall", func() bool { return !installer.Has("watchcow.whoami") })
+
+	if n := installer.Count("install-local watchcow.whoami"); n != 1 {
+		t.Errorf("install-local called %d times, want 1", n)
+	}
+}
+
+func TestMonitor_RecreateWithinGraceAdoptsApp(t *testing.T) {
+	cli := newFakeDocker()
+	cli.Create("cccccccccccc", "nginx", "nginx:1.25", testLabels("watchcow.nginx"), map[string]string{"80": "8080"})
+	cli.Start("cccccccccccc")
+	installer := newFakeInstaller()
+	m := newTestMonitor(t, cli, installer)
+	m.destroyGrace = time.Hour
+	startTestMonitor(t, m)
+	waitFor(t, "install", func() bool { return installer.Has("watchcow.nginx") })
+	waitIdle(t, m)
+
+	// docker compose up -d with a new image: old container removed, new one started
+	cli.Stop("cccccccccccc")
+	cli.Remove("cccccccccccc")
+	cli.Create("dddddddddddd", "nginx", "nginx:1.27", testLabels("watchcow.nginx"), map[string]string{"80": "8080"})
+	cli.Start("dddddddddddd")
+
+	waitFor(t, "upgrade", func() bool { return installer.Count("upgrade watchcow.nginx") == 1 })
+	waitIdle(t, m)
+
+	if installer.Count("uninstall watchcow.nginx") != 0 {
+		t.Error("app uninstalled although replacement container adopted it")
+	}
+	if m.isUninstallPending("watchcow.nginx") {
+		t.Error("uninstall still pending after adoption")
+	}
+	if a := m.Registry().Get("watchcow.nginx"); a == nil || a.ContainerID != "dddddddddddd" {
+		t.Errorf("registry app = %+v, want owned by new container", a)
+	}
+}
+
+func TestMonitor_RestartLoopCoalesces(t *testing.T) {
+	cli := newFakeDocker()
+	cli.Create("eeeeeeeeeeee", "flappy", "busybox", testLabels("watchcow.flappy"), nil)
+	installer := newFakeInstaller()
+	m := newTestMonitor(t, cli, installer)
+	startTestMonitor(t, m)
+	waitIdle(t, m)
+
+	for i := 0; i < 50; i++ {
+		cli.Start("eeeeeeeeeeee")
+		cli.Stop("eeeeeeeeeeee")
+	}
+	cli.Remove("eeeeeeeeeeee")
+
+	waitIdle(t, m)
+	if _, tracked := m.containers.Load("eeeeeeeeeeee"); tracked {
+		t.Error("destroyed container still tracked")
+	}
+	if installer.Has("watchcow.flappy") {
+		t.Error("app of destroyed container still installed")
+	}
+}
+
+func TestMonitor_InstalledCacheAvoidsRepeatedList(t *testing.T) {
+	cli := newFakeDocker()
+	const containers = 10
+	for i := 0; i < containers; i++ {
+		id := fmt.Sprintf("%012d", i)
+		name := fmt.Sprintf("app%d", i)
+		cli.Create(id, name, "nginx", testLabels("watchcow."+name), nil)
+		cli.Start(id)
+	}
+	installer := newFakeInstaller()
+	m := newTestMonitor(t, cli, installer)
+
+	startTestMonitor(t, m)
+	waitFor(t, "all installs", func() bool { return len(installer.Names()) == containers })
+	waitIdle(t, m)
+
+	if n := installer.Count("list"); n >= containers {
+		t.Errorf("appcenter-cli list called %d times for %d containers", n, containers)
+	}
+	if got := len(m.InstalledApps()); got != containers {
+		t.Errorf("InstalledApps() len = %d, want %d", got, containers)
+	}
+}
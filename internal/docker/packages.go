@@ -0,0 +1,334 @@
+package docker
+
+import (
+	"context"
+	"encoding/gob"
+	"errors"
+	"fmt"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"watchcow/internal/fpkgen"
+)
+
+// defaultPackageRetention is used when WATCHCOW_PACKAGE_RETENTION is not set
+const defaultPackageRetention = 5
+
+// packageInfoFile holds the metadata of a stored package, next to the .fpk file
+const packageInfoFile = "info.gob"
+
+// ErrPackageNotFound is returned when a stored package does not exist.
+var ErrPackageNotFound = errors.New("package not found")
+
+// StoredPackage is a generated package kept in the package store.
+// Each installed or upgraded generation of an app is stored once, identified by
+// its version and fingerprint, so it can be downloaded or installed again.
+type StoredPackage struct {
+	AppName     string
+	ID          string // "<version>-<fingerprint prefix>", the directory name
+	Version     string
+	Fingerprint string
+	CreatedAt   time.Time
+	Config      *fpkgen.AppConfig // Config the package was generated from
+
+	Path    string // Path of the .fpk file, set when read from the store
+	Current bool   // Package is the installed generation, set by Monitor.Packages
+}
+
+// packageStore keeps generated packages under <root>/<appname>/<id>/.
+// If TRIM_PKGVAR is set, root is ${TRIM_PKGVAR}/packages.
+// Otherwise uses /tmp/watchcow/packages.
+type packageStore struct {
+	root      string
+	retention int // Packages kept per app; 0 disables the store
+}
+
+// newPackageStore creates a package store with retention from environment.
+func newPackageStore() *packageStore {
+	root := "/tmp/watchcow/packages"
+	if pkgVar := os.Getenv("TRIM_PKGVAR"); pkgVar != "" {
+		root = filepath.Join(pkgVar, "packages")
+	}
+	return &packageStore{root: root, retention: getPackageRetention()}
+}
+
+// getPackageRetention returns the number of packages kept per app from environment.
+func getPackageRetention() int {
+	v := os.Getenv("WATCHCOW_PACKAGE_RETENTION")
+	if v == "" {
+		return defaultPackageRetention
+	}
+	n, err := strconv.Atoi(v)
+	if err != nil || n < 0 {
+		slog.Warn("Invalid WATCHCOW_PACKAGE_RETENTION, using default", "value", v, "default", defaultPackageRetention)
+		return defaultPackageRetention
+	}
+	return n
+}
+
+// packageID returns the store directory name for a package generation.
+func packageID(version, fingerprint string) string {
+	var b strings.Builder
+	for _, c := range version {
+		if (c >= 'a' && c <= 'z') || (c >= 'A' && c <= 'Z') || (c >= '0' && c <= '9') || c == '.' || c == '-' {
+			b.WriteRune(c)
+		} else {
+			b.WriteRune('_')
+		}
+	}
+	if len(fingerprint) > 12 {
+		fingerprint = fingerprint[:12]
+	}
+	return b.String() + "-" + fingerprint
+}
+
+// validName reports whether name is a single path element that is safe to join.
+func validName(name string) bool {
+	return name != "" && !strings.HasPrefix(name, ".") && !strings.ContainsAny(name, `/\`)
+}
+
+// Save packs appDir into the store and prunes old packages beyond the retention limit.
+// Saving a generation that is already stored refreshes it and makes it the newest.
+func (s *packageStore) Save(config *fpkgen.AppConfig, fingerprint, appDir string) (*StoredPackage, error) {
+	if s.retention == 0 {
+		return nil, nil
+	}
+	if !validName(config.AppName) {
+		return nil, fmt.Errorf("invalid app name: %q", config.AppName)
+	}
+
+	pkg := &StoredPackage{
+		AppName:     config.AppName,
+		ID:          packageID(config.Version, fingerprint),
+		Version:     config.Version,
+		Fingerprint: fingerprint,
+		CreatedAt:   time.Now(),
+		Config:      config,
+	}
+	dir := filepath.Join(s.root, pkg.AppName, pkg.ID)
+	pkg.Path = filepath.Join(dir, fpkgen.PackageFileName(pkg.AppName))
+
+	if err := fpkgen.WritePackage(appDir, pkg.Path); err != nil {
+		return nil, err
+	}
+	if err := writePackageInfo(filepath.Join(dir, packageInfoFile), pkg); err != nil {
+		return nil, err
+	}
+
+	s.prune(pkg.AppName)
+	return pkg, nil
+}
+
+// writePackageInfo writes package metadata using atomic write (write-to-temp + rename).
+func writePackageInfo(path string, pkg *StoredPackage) error {
+	tmpPath := path + ".tmp"
+	f, err := os.Create(tmpPath)
+	if err != nil {
+		return err
+	}
+	if err := gob.NewEncoder(f).Encode(pkg); err != nil {
+		f.Close()
+		os.Remove(tmpPath)
+		return err
+	}
+	if err := f.Close(); err != nil {
+		os.Remove(tmpPath)
+		return err
+	}
+	return os.Rename(tmpPath, path)
+}
+
+// Get returns a stored package.
+func (s *packageStore) Get(appName, id string) (*StoredPackage, error) {
+	if !validName(appName) || !validName(id) {
+		return nil, ErrPackageNotFound
+	}
+
+	dir := filepath.Join(s.root, appName, id)
+	f, err := os.Open(filepath.Join(dir, packageInfoFile))
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, ErrPackageNotFound
+		}
+		return nil, err
+	}
+	defer f.Close()
+
+	var pkg StoredPackage
+	if err := gob.NewDecoder(f).Decode(&pkg); err != nil {
+		return nil, fmt.Errorf("failed to read package info: %w", err)
+	}
+	pkg.Path = filepath.Join(dir, fpkgen.PackageFileName(appName))
+	if _, err := os.Stat(pkg.Path); err != nil {
+		return nil, ErrPackageNotFound
+	}
+	return &pkg, nil
+}
+
+// List returns the stored packages of an app, newest first.
+// Unreadable entries are skipped.
+func (s *packageStore) List(appName string) []*StoredPackage {
+	if !validName(appName) {
+		return nil
+	}
+	entries, err := os.ReadDir(filepath.Join(s.root, appName))
+	if err != nil {
+		return nil
+	}
+
+	var result []*StoredPackage
+	for _, e := range entries {
+		if !e.IsDir() {
+			continue
+		}
+		pkg, err := s.Get(appName, e.Name())
+		if err != nil {
+			slog.Debug("Skipping unreadable stored package", "app", appName, "id", e.Name(), "error", err)
+			continue
+		}
+		result = append(result, pkg)
+	}
+	sort.Slice(result, func(i, j int) bool {
+		return result[i].CreatedAt.After(result[j].CreatedAt)
+	})
+	return result
+}
+
+// Apps returns the names of apps with stored packages, sorted.
+func (s *packageStore) Apps() []string {
+	entries, err := os.ReadDir(s.root)
+	if err != nil {
+		return nil
+	}
+	var apps []string
+	for _, e := range entries {
+		if e.IsDir() {
+			apps = append(apps, e.Name())
+		}
+	}
+	return apps
+}
+
+// prune removes the oldest packages of an app beyond the retention limit.
+func (s *packageStore) prune(appName string) {
+	packages := s.List(appName)
+	if len(packages) <= s.retention {
+		return
+	}
+	for _, pkg := range packages[s.retention:] {
+		if err := os.RemoveAll(filepath.Dir(pkg.Path)); err != nil {
+			slog.Warn("Failed to remove old package", "app", appName, "id", pkg.ID, "error", err)
+			continue
+		}
+		slog.Debug("Removed old package", "app", appName, "id", pkg.ID)
+	}
+}
+
+// storePackage keeps the package of an installed generation in the package store.
+// Failures are logged; they never fail the install.
+func (m *Monitor) storePackage(config *fpkgen.AppConfig, fingerprint, appDir string) {
+	pkg, err := m.packages.Save(config, fingerprint, appDir)
+	if err != nil {
+		slog.Warn("Failed to store package", "app", config.AppName, "error", err)
+		return
+	}
+	if pkg != nil {
+		slog.Debug("Stored package", "app", pkg.AppName, "id", pkg.ID)
+	}
+}
+
+// Packages returns all stored packages, grouped by app and newest first.
+// The installed generation of each app is marked as Current.
+func (m *Monitor) Packages() []StoredPackage {
+	var result []StoredPackage
+	for _, appName := range m.packages.Apps() {
+		installed := m.state.Fingerprint(appName)
+		for _, pkg := range m.packages.List(appName) {
+			pkg.Current = installed != "" && pkg.Fingerprint == installed
+			result = append(result, *pkg)
+		}
+	}
+	return result
+}
+
+// PackageFile returns the path of a stored .fpk file.
+func (m *Monitor) PackageFile(appName, id string) (string, error) {
+	pkg, err := m.packages.Get(appName, id)
+	if err != nil {
+		return "", err
+	}
+	return pkg.Path, nil
+}
+
+// TriggerPackageInstall queues re-installing a stored package over the installed app,
+// rolling back to (or forward to) that generation. Returns false if no such package exists.
+func (m *Monitor) TriggerPackageInstall(appName, id string) bool {
+	if _, err := m.packages.Get(appName, id); err != nil {
+		return false
+	}
+
+	slog.Info("Queueing stored package install from dashboard", "app", appName, "package", id)
+	m.queueOperation(&AppOperation{
+		Type:      "package_install",
+		AppName:   appName,
+		PackageID: id,
+	})
+	return true
+}
+
+// processPackageInstall installs a stored package over an installed app.
+// The labels or config that produced the replaced generation are pinned: the app
+// is not upgraded back to it until its config changes again.
+func (m *Monitor) processPackageInstall(ctx context.Context, op *AppOperation) {
+	if m.installer == nil {
+		return
+	}
+
+	pkg, err := m.packages.Get(op.AppName, op.PackageID)
+	if err != nil {
+		slog.Error("Stored package not available", "app", op.AppName, "package", op.PackageID, "error", err)
+		return
+	}
+	if !m.isAppInstalled(ctx, op.AppName) {
+		slog.Error("App not installed, cannot install stored package over it", "app", op.AppName, "package", op.PackageID)
+		return
+	}
+
+	appDir, err := os.MkdirTemp("", "watchcow-"+op.AppName+"-")
+	if err != nil {
+		slog.Error("Failed to create temp directory", "error", err)
+		return
+	}
+	defer os.RemoveAll(appDir)
+
+	if err := fpkgen.Unpack(pkg.Path, appDir); err != nil {
+		slog.Error("Failed to unpack stored package", "app", op.AppName, "package", op.PackageID, "error", err)
+		return
+	}
+
+	slog.Info("Installing stored package", "app", op.AppName, "package", op.PackageID)
+	if err := m.installer.Upgrade(ctx, appDir); err != nil {
+		slog.Error("Failed to install stored package", "app", op.AppName, "package", op.PackageID, "error", err)
+		m.retryOperation(op, err)
+		return
+	}
+
+	if previous := m.state.Fingerprint(op.AppName); previous != "" && previous != pkg.Fingerprint {
+		m.state.SetPinned(op.AppName, previous)
+	}
+	m.state.SetFingerprint(op.AppName, pkg.Fingerprint)
+	m.markInstalled(op.AppName, pkg.Version, "")
+	m.queueInstalledRefresh()
+
+	if pkg.Config != nil {
+		if a := m.registry.Get(op.AppName); a != nil {
+			m.registerAppFromConfig(pkg.Config, a.ContainerID, a.ContainerName)
+		}
+	}
+	slog.Info("Successfully installed stored package", "app", op.AppName, "package", op.PackageID)
+}
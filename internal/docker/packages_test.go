@@ -0,0 +1,98 @@
+package docker
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"watchcow/internal/fpkgen"
+)
+
+// writeGeneratedApp writes a minimal generated app tree and returns its directory.
+func writeGeneratedApp(t *testing.T, appName, version string) string {
+	t.Helper()
+	dir := t.TempDir()
+	if err := os.MkdirAll(filepath.Join(dir, "app", "ui"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	manifest := "appname=" + appName + "\nversion=" + version + "\n"
+	if err := os.WriteFile(filepath.Join(dir, "manifest"), []byte(manifest), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, "app", "ui", "config"), []byte("{}"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	return dir
+}
+
+func TestPackageStore_Retention(t *testing.T) {
+	s := &packageStore{root: t.TempDir(), retention: 2}
+
+	for _, v := range []string{"1.0.0", "1.1.0", "1.2.0"} {
+		config := &fpkgen.AppConfig{AppName: "watchcow.nginx", Version: v}
+		if _, err := s.Save(config, "fp-"+v, writeGeneratedApp(t, config.AppName, v)); err != nil {
+			t.Fatalf("Save(%s) error = %v", v, err)
+		}
+	}
+
+	packages := s.List("watchcow.nginx")
+	if len(packages) != 2 {
+		t.Fatalf("List() returned %d packages, want 2", len(packages))
+	}
+	if packages[0].Version != "1.2.0" || packages[1].Version != "1.1.0" {
+		t.Errorf("List() versions = %s, %s; want newest first", packages[0].Version, packages[1].Version)
+	}
+	if _, err := os.Stat(packages[0].Path); err != nil {
+		t.Errorf("stored .fpk missing: %v", err)
+	}
+}
+
+func TestPackageStore_GetInvalid(t *testing.T) {
+	s := &packageStore{root: t.TempDir(), retention: 2}
+
+	for _, tt := range []struct{ app, id string }{
+		{"watchcow.nginx", "missing"},
+		{"..", "x"},
+		{"watchcow.nginx", "../../etc"},
+	} {
+		if _, err := s.Get(tt.app, tt.id); !errors.Is(err, ErrPackageNotFound) {
+			t.Errorf("Get(%q, %q) error = %v, want ErrPackageNotFound", tt.app, tt.id, err)
+		}
+	}
+}
+
+func TestProcessPackageInstall_RollsBack(t *testing.T) {
+	installer := newFakeInstaller("watchcow.nginx")
+	m := newTestMonitor(t, newFakeDocker(), installer)
+
+	old, err := m.packages.Save(&fpkgen.AppConfig{AppName: "watchcow.nginx", Version: "1.0.0"}, "fp-old", writeGeneratedApp(t, "watchcow.nginx", "1.0.0"))
+	if err != nil {
+		t.Fatalf("Save() error = %v", err)
+	}
+	if _, err := m.packages.Save(&fpkgen.AppConfig{AppName: "watchcow.nginx", Version: "1.1.0"}, "fp-new", writeGeneratedApp(t, "watchcow.nginx", "1.1.0")); err != nil {
+		t.Fatalf("Save() error = %v", err)
+	}
+	m.state.SetFingerprint("watchcow.nginx", "fp-new")
+
+	if !m.TriggerPackageInstall("watchcow.nginx", old.ID) {
+		t.Fatal("TriggerPackageInstall() = false for stored package")
+	}
+	m.processPackageInstall(context.Background(), &AppOperation{Type: "package_install", AppName: "watchcow.nginx", PackageID: old.ID})
+
+	if installer.Count("upgrade watchcow.nginx") != 1 {
+		t.Errorf("calls = %v, want one upgrade", installer.Calls())
+	}
+	if fp := m.state.Fingerprint("watchcow.nginx"); fp != "fp-old" {
+		t.Errorf("Fingerprint() = %q, want fp-old", fp)
+	}
+	if pinned := m.state.Pinned("watchcow.nginx"); pinned != "fp-new" {
+		t.Errorf("Pinned() = %q, want fp-new", pinned)
+	}
+	for _, pkg := range m.Packages() {
+		if pkg.Current != (pkg.ID == old.ID) {
+			t.Errorf("package %s Current = %v", pkg.ID, pkg.Current)
+		}
+	}
+}
@@ -0,0 +1,126 @@
+package docker
+
+import (
+	"log/slog"
+	"sync"
+)
+
+// operationQueue is an unbounded FIFO of operations keyed by the container or app
+// they act on. Queueing an operation whose key is already pending replaces the
+// pending one in place, so superseded operations collapse to the latest desired
+// state (start→stop→start becomes start) and nothing is ever dropped.
+type operationQueue struct {
+	mu      sync.Mutex
+	order   []string                 // keys in first-queued order
+	pending map[string]*AppOperation // key -> latest operation
+	seqs    map[string]uint64        // key -> sequence of the latest queued operation
+	notify  chan struct{}            // signalled when operations are available
+}
+
+// newOperationQueue creates an empty operation queue.
+func newOperationQueue() *operationQueue {
+	return &operationQueue{
+		pending: make(map[string]*AppOperation),
+		seqs:    make(map[string]uint64),
+		notify:  make(chan struct{}, 1),
+	}
+}
+
+// operationKey returns the coalescing key of an operation.
+// Container lifecycle operations share one key per container, dashboard config
+// changes another, so a container stop never discards a pending config update.
+func operationKey(op *AppOperation) string {
+	switch op.Type {
+	case "container_start", "install", "stop", "destroy":
+		return "container:" + op.ContainerID
+	case "not_ready", "ready_timeout":
+		// Never supersedes a lifecycle operation queued since the wait started
+		return "ready:" + op.ContainerID
+	case "dashboard_install", "dashboard_reinstall":
+		return "dashboard:" + op.ContainerID
+	case "dashboard_uninstall", "grace_uninstall", "uninstall", "package_install", "upgrade":
+		return "app:" + op.AppName
+	default:
+		return op.Type
+	}
+}
+
+// push queues an operation, replacing a pending operation with the same key.
+// The operation becomes the latest desired state for its key.
+func (q *operationQueue) push(op *AppOperation) {
+	q.mu.Lock()
+	key := operationKey(op)
+	q.seqs[key]++
+	op.seq = q.seqs[key]
+	q.enqueue(key, op)
+	q.mu.Unlock()
+
+	q.signal()
+}
+
+// pushRetry queues a retry of an operation unless a newer operation with the
+// same key was queued since. Returns false if the retry was superseded.
+func (q *operationQueue) pushRetry(op *AppOperation) bool {
+	q.mu.Lock()
+	key := operationKey(op)
+	if q.seqs[key] != op.seq {
+		q.mu.Unlock()
+		return false
+	}
+	q.enqueue(key, op)
+	q.mu.Unlock()
+
+	q.signal()
+	return true
+}
+
+// stamp marks op as the latest desired state for its key without queueing it,
+// so a later retry of op is dropped once a newer operation arrives.
+func (q *operationQueue) stamp(op *AppOperation) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	op.seq = q.seqs[operationKey(op)]
+}
+
+// enqueue stores op under key. Caller must hold q.mu.
+func (q *operationQueue) enqueue(key string, op *AppOperation) {
+	if prev, exists := q.pending[key]; exists {
+		slog.Debug("Coalescing operation", "key", key, "superseded", prev.Type, "type", op.Type)
+	} else {
+		q.order = append(q.order, key)
+	}
+	q.pending[key] = op
+}
+
+// signal wakes the worker if it is idle
+func (q *operationQueue) signal() {
+	select {
+	case q.notify <- struct{}{}:
+	default:
+	}
+}
+
+// pop removes and returns the oldest pending operation.
+// Returns false if the queue is empty.
+func (q *operationQueue) pop() (*AppOperation, bool) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	if len(q.order) == 0 {
+		return nil, false
+	}
+	key := q.order[0]
+	q.order[0] = ""
+	q.order = q.order[1:]
+
+	op := q.pending[key]
+	delete(q.pending, key)
+	return op, true
+}
+
+// len returns the number of pending operations.
+func (q *operationQueue) len() int {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	return len(q.order)
+}
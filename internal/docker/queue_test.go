@@ -0,0 +1,84 @@
+package docker
+
+import "testing"
+
+func TestOperationQueue_CoalescesPerContainer(t *testing.T) {
+	q := newOperationQueue()
+	q.push(&AppOperation{Type: "container_start", ContainerID: "a"})
+	q.push(&AppOperation{Type: "container_start", ContainerID: "b"})
+	q.push(&AppOperation{Type: "stop", ContainerID: "a"})
+	q.push(&AppOperation{Type: "container_start", ContainerID: "a"})
+
+	if got := q.len(); got != 2 {
+		t.Fatalf("len() = %d, want 2", got)
+	}
+
+	// Coalesced operation keeps its original position with the latest type
+	want := []struct{ id, typ string }{
+		{"a", "container_start"},
+		{"b", "container_start"},
+	}
+	for _, w := range want {
+		op, ok := q.pop()
+		if !ok {
+			t.Fatal("pop() returned empty queue")
+		}
+		if op.ContainerID != w.id || op.Type != w.typ {
+			t.Errorf("pop() = %s/%s, want %s/%s", op.ContainerID, op.Type, w.id, w.typ)
+		}
+	}
+	if _, ok := q.pop(); ok {
+		t.Error("pop() on empty queue returned an operation")
+	}
+}
+
+func TestOperationQueue_DestroyNotLost(t *testing.T) {
+	q := newOperationQueue()
+	for i := 0; i < 500; i++ {
+		q.push(&AppOperation{Type: "container_start", ContainerID: "flappy"})
+		q.push(&AppOperation{Type: "stop", ContainerID: "flappy"})
+	}
+	q.push(&AppOperation{Type: "destroy", ContainerID: "real"})
+
+	if got := q.len(); got != 2 {
+		t.Fatalf("len() = %d, want 2", got)
+	}
+	q.pop()
+	op, _ := q.pop()
+	if op.Type != "destroy" || op.ContainerID != "real" {
+		t.Errorf("pop() = %s/%s, want real/destroy", op.ContainerID, op.Type)
+	}
+}
+
+func TestOperationQueue_DashboardNotSupersededByLifecycle(t *testing.T) {
+	q := newOperationQueue()
+	q.push(&AppOperation{Type: "dashboard_reinstall", ContainerID: "a"})
+	q.push(&AppOperation{Type: "stop", ContainerID: "a"})
+
+	if got := q.len(); got != 2 {
+		t.Errorf("len() = %d, want 2", got)
+	}
+}
+
+func TestOperationQueue_PushRetrySuperseded(t *testing.T) {
+	q := newOperationQueue()
+	q.push(&AppOperation{Type: "install", ContainerID: "a"})
+	op, _ := q.pop()
+
+	// Retry of the latest operation is accepted
+	retry := *op
+	retry.Attempt = 1
+	if !q.pushRetry(&retry) {
+		t.Fatal("pushRetry() = false, want true for latest operation")
+	}
+	q.pop()
+
+	// A newer operation for the same container supersedes the retry
+	q.push(&AppOperation{Type: "stop", ContainerID: "a"})
+	if q.pushRetry(&retry) {
+		t.Error("pushRetry() = true, want false after newer operation")
+	}
+	if got := q.len(); got != 1 {
+		t.Errorf("len() = %d, want 1", got)
+	}
+}
@@ -0,0 +1,309 @@
+package docker
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"watchcow/internal/app"
+)
+
+// Readiness modes for watchcow.wait_for
+const (
+	WaitForHealthy = "healthy" // Wait for Docker HEALTHCHECK to report healthy
+	WaitForPort    = "port"    // Wait for the service port to accept TCP connections
+	WaitForNone    = "none"    // Install immediately
+)
+
+const (
+	// defaultWaitTimeout is used when watchcow.wait_timeout is not set
+	defaultWaitTimeout = 2 * time.Minute
+
+	// readinessPollInterval is the interval between readiness checks
+	readinessPollInterval = 2 * time.Second
+
+	// portDialTimeout bounds a single TCP connect attempt
+	portDialTimeout = 2 * time.Second
+)
+
+// errReadinessTimeout is returned by waitForReady if the container did not become ready in time
+var errReadinessTimeout = errors.New("timed out waiting for readiness")
+
+// readinessSpec describes how to wait for a container before installing its app
+type readinessSpec struct {
+	Mode    string
+	Port    string
+	Timeout time.Duration
+}
+
+// NotReadyApp is an app that was not installed because its container did not
+// become ready within the wait timeout. Restarting the container waits again.
+type NotReadyApp struct {
+	ContainerID   string
+	ContainerName string
+	AppName       string
+	WaitFor       string
+	Timeout       time.Duration
+	TimedOutAt    time.Time
+}
+
+// startReadinessWait waits in the background for the container to become ready
+// and then queues the install operation. At most one wait runs per container.
+func (m *Monitor) startReadinessWait(ctx context.Context, op *AppOperation, state *ContainerState) {
+	if _, waiting := m.readinessWaits.LoadOrStore(op.ContainerID, struct{}{}); waiting {
+		slog.Debug("Already waiting for container readiness", "container", op.ContainerName)
+		return
+	}
+
+	spec := m.readinessSpecFor(ctx, op, state)
+
+	go func() {
+		defer m.readinessWaits.Delete(op.ContainerID)
+
+		if err := m.waitForReady(ctx, op.ContainerID, spec); err != nil {
+			slog.Info("Container not ready, skipping install", "container", op.ContainerName, "reason", err)
+			notReady := &AppOperation{
+				Type:          "not_ready",
+				ContainerID:   op.ContainerID,
+				ContainerName: op.ContainerName,
+			}
+			if errors.Is(err, errReadinessTimeout) {
+				m.notReady.Store(op.ContainerID, &NotReadyApp{
+					ContainerID:   op.ContainerID,
+					ContainerName: op.ContainerName,
+					AppName:       state.AppName,
+					WaitFor:       spec.Mode,
+					Timeout:       spec.Timeout,
+					TimedOutAt:    time.Now(),
+				})
+				notReady.Type = "ready_timeout"
+			}
+			// Done waiting before queueing, so a restart can start a new wait meanwhile
+			m.readinessWaits.Delete(op.ContainerID)
+			m.queueOperation(notReady)
+			return
+		}
+
+		install := *op
+		install.Type = "install"
+		m.queueOperation(&install)
+	}()
+}
+
+// processNotReady clears the pending app of a container whose readiness wait ended
+// without an install, so it does not stay pending. Nothing is cleared if the container
+// was restarted and a new wait owns the app, or if the app was installed meanwhile.
+func (m *Monitor) processNotReady(op *AppOperation) {
+	if _, waiting := m.readinessWaits.Load(op.ContainerID); waiting {
+		return
+	}
+	if v, ok := m.containers.Load(op.ContainerID); ok {
+		if state := v.(*ContainerState); !state.Installed {
+			state.AppName = ""
+		}
+	}
+	a := m.registry.GetByContainerID(op.ContainerID)
+	if a == nil || a.Status != app.StatusPending {
+		return
+	}
+	slog.Info("Unregistering app of container that did not become ready", "app", a.AppName, "container", op.ContainerName)
+	m.registry.Unregister(a.AppName)
+}
+
+// processReadyTimeout marks the pending app of a container that did not become ready
+// within the wait timeout as failed; it is listed on the status page until the container
+// is restarted. Nothing changes if a new wait owns the app or the app was installed meanwhile.
+func (m *Monitor) processReadyTimeout(op *AppOperation) {
+	if _, waiting := m.readinessWaits.Load(op.ContainerID); waiting {
+		return
+	}
+	a := m.registry.GetByContainerID(op.ContainerID)
+	if a == nil || a.Status != app.StatusPending {
+		m.notReady.Delete(op.ContainerID)
+		return
+	}
+	slog.Warn("Container did not become ready in time, not installing", "app", a.AppName, "container", op.ContainerName)
+	m.registry.UpdateStatus(a.AppName, app.StatusInstallFailed)
+}
+
+// NotReadyApps returns the apps not installed because their container did not
+// become ready in time, sorted by container name.
+func (m *Monitor) NotReadyApps() []NotReadyApp {
+	var result []NotReadyApp
+	m.notReady.Range(func(_, value any) bool {
+		result = append(result, *value.(*NotReadyApp))
+		return true
+	})
+	sort.Slice(result, func(i, j int) bool {
+		return result[i].ContainerName < result[j].ContainerName
+	})
+	return result
+}
+
+// readinessSpecFor resolves the wait mode, port and timeout for a container.
+// Without watchcow.wait_for, containers with a HEALTHCHECK wait for healthy,
+// others wait for their service port, and containers without a port install immediately.
+func (m *Monitor) readinessSpecFor(ctx context.Context, op *AppOperation, state *ContainerState) readinessSpec {
+	spec := readinessSpec{
+		Mode:    op.Labels["watchcow.wait_for"],
+		Timeout: defaultWaitTimeout,
+	}
+
+	// Dashboard configs carry the port in the entry, labels in service_port labels
+	if op.StoredConfig != nil && len(op.StoredConfig.Entries) > 0 {
+		spec.Port = op.StoredConfig.Entries[0].Port
+	} else {
+		spec.Port = readinessPort(op.Labels, state.Ports)
+	}
+
+	if v := op.Labels["watchcow.wait_timeout"]; v != "" {
+		if d, err := parseDuration(v); err == nil {
+			spec.Timeout = d
+		} else {
+			slog.Warn("Invalid watchcow.wait_timeout, using default", "value", v, "default", defaultWaitTimeout)
+		}
+	}
+
+	switch spec.Mode {
+	case WaitForHealthy, WaitForPort, WaitForNone:
+		return spec
+	case "":
+	default:
+		slog.Warn("Unknown watchcow.wait_for, using automatic detection", "value", spec.Mode)
+	}
+
+	info, err := m.cli.ContainerInspect(ctx, op.ContainerID)
+	switch {
+	case err == nil && info.Config != nil && info.Config.Healthcheck != nil &&
+		len(info.Config.Healthcheck.Test) > 0 && info.Config.Healthcheck.Test[0] != "NONE":
+		spec.Mode = WaitForHealthy
+	case spec.Port != "":
+		spec.Mode = WaitForPort
+	default:
+		spec.Mode = WaitForNone
+	}
+	return spec
+}
+
+// waitForReady polls the container until the readiness condition holds.
+// Returns an error if the container stops or disappears while waiting, and
+// errReadinessTimeout if it is not ready within the timeout.
+func (m *Monitor) waitForReady(ctx context.Context, containerID string, spec readinessSpec) error {
+	if spec.Mode == WaitForNone {
+		return nil
+	}
+
+	slog.Info("Waiting for container to become ready", "id", containerID, "wait_for", spec.Mode, "port", spec.Port, "timeout", spec.Timeout)
+
+	deadline := time.Now().Add(spec.Timeout)
+	for {
+		ready, err := m.checkReady(ctx, containerID, spec)
+		if err != nil {
+			return err
+		}
+		if ready {
+			slog.Info("Container ready", "id", containerID, "wait_for", spec.Mode)
+			return nil
+		}
+
+		if time.Now().After(deadline) {
+			return fmt.Errorf("%w (%s after %s)", errReadinessTimeout, spec.Mode, spec.Timeout)
+		}
+
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-m.stopCh:
+			return fmt.Errorf("monitor stopped")
+		case <-time.After(readinessPollInterval):
+		}
+	}
+}
+
+// checkReady performs a single readiness check
+func (m *Monitor) checkReady(ctx context.Context, containerID string, spec readinessSpec) (bool, error) {
+	info, err := m.cli.ContainerInspect(ctx, containerID)
+	if err != nil {
+		return false, fmt.Errorf("failed to inspect container: %w", err)
+	}
+	if info.State == nil || !info.State.Running {
+		return false, fmt.Errorf("container is no longer running")
+	}
+
+	switch spec.Mode {
+	case WaitForHealthy:
+		if info.State.Health == nil {
+			slog.Warn("Container has no healthcheck, treating as ready", "id", containerID)
+			return true, nil
+		}
+		return info.State.Health.Status == "healthy", nil
+
+	case WaitForPort:
+		if spec.Port == "" {
+			slog.Warn("No service port to wait for, treating as ready", "id", containerID)
+			return true, nil
+		}
+		conn, err := net.DialTimeout("tcp", net.JoinHostPort("127.0.0.1", spec.Port), portDialTimeout)
+		if err != nil {
+			return false, nil
+		}
+		conn.Close()
+		return true, nil
+	}
+
+	return true, nil
+}
+
+// readinessPort picks the port to probe: watchcow.service_port, then the first
+// named entry's service_port, then the first published host port.
+func readinessPort(labels map[string]string, ports map[string]string) string {
+	if port := labels["watchcow.service_port"]; port != "" {
+		return port
+	}
+
+	var entryPorts []string
+	for key, value := range labels {
+		if strings.HasPrefix(key, "watchcow.") && strings.HasSuffix(key, ".service_port") && value != "" {
+			entryPorts = append(entryPorts, key)
+		}
+	}
+	if len(entryPorts) > 0 {
+		sort.Strings(entryPorts)
+		return labels[entryPorts[0]]
+	}
+
+	var hostPorts []string
+	for _, hostPort := range ports {
+		hostPorts = append(hostPorts, hostPort)
+	}
+	if len(hostPorts) > 0 {
+		sort.Strings(hostPorts)
+		return hostPorts[0]
+	}
+
+	return ""
+}
+
+// parseDuration parses a Go duration ("90s", "2m") or a plain number of seconds
+func parseDuration(v string) (time.Duration, error) {
+	if secs, err := strconv.Atoi(v); err == nil {
+		if secs < 0 {
+			return 0, fmt.Errorf("negative duration: %s", v)
+		}
+		return time.Duration(secs) * time.Second, nil
+	}
+	d, err := time.ParseDuration(v)
+	if err != nil {
+		return 0, err
+	}
+	if d < 0 {
+		return 0, fmt.Errorf("negative duration: %s", v)
+	}
+	return d, nil
+}
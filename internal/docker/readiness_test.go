@@ -0,0 +1,161 @@
+package docker
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"watchcow/internal/app"
+)
+
+func TestReadinessPort(t *testing.T) {
+	tests := []struct {
+		name   string
+		labels map[string]string
+		ports  map[string]string
+		want   string
+	}{
+		{
+			name:   "default entry port",
+			labels: map[string]string{"watchcow.service_port": "8080", "watchcow.admin.service_port": "9090"},
+			want:   "8080",
+		},
+		{
+			name:   "named entry port",
+			labels: map[string]string{"watchcow.web.service_port": "3000", "watchcow.admin.service_port": "9090"},
+			want:   "9090",
+		},
+		{
+			name:  "published host port",
+			ports: map[string]string{"80": "8081", "443": "8443"},
+			want:  "8081",
+		},
+		{
+			name: "no port",
+			want: "",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := readinessPort(tt.labels, tt.ports); got != tt.want {
+				t.Errorf("readinessPort() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseDuration(t *testing.T) {
+	tests := []struct {
+		value   string
+		want    time.Duration
+		wantErr bool
+	}{
+		{"90", 90 * time.Second, false},
+		{"90s", 90 * time.Second, false},
+		{"5m", 5 * time.Minute, false},
+		{"-1", 0, true},
+		{"soon", 0, true},
+	}
+
+	for _, tt := range tests {
+		got, err := parseDuration(tt.value)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("parseDuration(%q) error = %v, wantErr %v", tt.value, err, tt.wantErr)
+			continue
+		}
+		if got != tt.want {
+			t.Errorf("parseDuration(%q) = %v, want %v", tt.value, got, tt.want)
+		}
+	}
+}
+
+// TestMonitor_StopWhileWaitingClearsPendingApp tests that a container stopping
+// before it becomes ready does not leave its app pending
+func TestMonitor_StopWhileWaitingClearsPendingApp(t *testing.T) {
+	cli := newFakeDocker()
+	labels := testLabels("watchcow.nginx")
+	labels["watchcow.wait_for"] = "healthy"
+	labels["watchcow.wait_timeout"] = "10m"
+	cli.Create("aaaaaaaaaaaa", "nginx", "nginx:1.25", labels, map[string]string{"80": "8080"})
+	cli.SetHealth("aaaaaaaaaaaa", "starting", 0, false)
+	cli.Start("aaaaaaaaaaaa")
+	installer := newFakeInstaller()
+	m := newTestMonitor(t, cli, installer)
+
+	startTestMonitor(t, m)
+
+	waitFor(t, "readiness wait", func() bool {
+		_, waiting := m.readinessWaits.Load("aaaaaaaaaaaa")
+		return waiting
+	})
+	if a := m.Registry().GetByContainerID("aaaaaaaaaaaa"); a == nil {
+		t.Fatal("app should be registered while waiting")
+	}
+
+	cli.Stop("aaaaaaaaaaaa")
+	waitFor(t, "pending app cleared", func() bool { return m.Registry().Get("watchcow.nginx") == nil })
+	waitIdle(t, m)
+	if installer.Has("watchcow.nginx") {
+		t.Error("stopped container should not be installed")
+	}
+}
+
+// TestMonitor_ReadinessTimeoutBlocksInstall tests that a container that does not
+// become healthy in time is not installed and is reported as not ready
+func TestMonitor_ReadinessTimeoutBlocksInstall(t *testing.T) {
+	cli := newFakeDocker()
+	labels := testLabels("watchcow.nginx")
+	labels["watchcow.wait_for"] = "healthy"
+	labels["watchcow.wait_timeout"] = "0"
+	cli.Create("aaaaaaaaaaaa", "nginx", "nginx:1.25", labels, map[string]string{"80": "8080"})
+	cli.SetHealth("aaaaaaaaaaaa", "starting", 0, false)
+	cli.Start("aaaaaaaaaaaa")
+	installer := newFakeInstaller()
+	m := newTestMonitor(t, cli, installer)
+
+	startTestMonitor(t, m)
+
+	waitFor(t, "not ready report", func() bool { return len(m.NotReadyApps()) == 1 })
+	waitIdle(t, m)
+	syncWorker(t, m, installer)
+	if a := m.Registry().Get("watchcow.nginx"); a == nil || a.Status != app.StatusInstallFailed {
+		t.Errorf("registry app = %+v, want status %s", a, app.StatusInstallFailed)
+	}
+	if installer.Has("watchcow.nginx") {
+		t.Error("container that did not become ready should not be installed")
+	}
+	if r := m.NotReadyApps()[0]; r.AppName != "watchcow.nginx" || r.WaitFor != WaitForHealthy {
+		t.Errorf("NotReadyApps() = %+v", r)
+	}
+
+	// A restart waits again and installs once the container is healthy
+	cli.SetHealth("aaaaaaaaaaaa", "healthy", 0, false)
+	cli.Stop("aaaaaaaaaaaa")
+	cli.Start("aaaaaaaaaaaa")
+	waitFor(t, "install", func() bool { return installer.Has("watchcow.nginx") })
+	waitIdle(t, m)
+	if reports := m.NotReadyApps(); len(reports) != 0 {
+		t.Errorf("NotReadyApps() = %+v after restart, want none", reports)
+	}
+}
+
+// TestMonitor_InstallFailureIsNotPending tests that a failed install marks the
+// app as failed instead of pending
+func TestMonitor_InstallFailureIsNotPending(t *testing.T) {
+	cli := newFakeDocker()
+	cli.Create("aaaaaaaaaaaa", "nginx", "nginx:1.25", testLabels("watchcow.nginx"), map[string]string{"80": "8080"})
+	cli.Start("aaaaaaaaaaaa")
+	installer := newFakeInstaller()
+	installer.failOn["install-local"] = errors.New("exit status 1")
+	m := newTestMonitor(t, cli, installer)
+
+	startTestMonitor(t, m)
+
+	waitFor(t, "install attempt", func() bool { return installer.Count("install-local watchcow.nginx") == 1 })
+	syncWorker(t, m, installer)
+	a := m.Registry().Get("watchcow.nginx")
+	if a == nil || a.Status != app.StatusInstallFailed {
+		t.Errorf("registry app = %+v, want status %s", a, app.StatusInstallFailed)
+	}
+}
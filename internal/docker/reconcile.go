@@ -0,0 +1,169 @@
+package docker
+
+import (
+	"context"
+	"log/slog"
+	"os"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// managedAppPrefix is the app name prefix of apps generated by WatchCow.
+// WatchCow itself is installed as "watchcow" (no dot) and is never matched.
+const managedAppPrefix = "watchcow."
+
+// Orphan handling policies (WATCHCOW_ORPHAN_POLICY)
+const (
+	OrphanPolicyUninstall = "uninstall" // Uninstall orphaned apps automatically
+	OrphanPolicyReview    = "review"    // Keep orphaned apps and list them for review
+)
+
+// OrphanApp describes an installed WatchCow app whose container no longer exists.
+type OrphanApp struct {
+	AppName    string
+	DetectedAt time.Time
+}
+
+// orphanTracker holds orphans found by the last reconciliation pass (review policy).
+type orphanTracker struct {
+	mu   sync.Mutex
+	apps map[string]*OrphanApp
+}
+
+// getOrphanPolicy returns the orphan policy from environment, defaulting to uninstall.
+func getOrphanPolicy() string {
+	switch policy := os.Getenv("WATCHCOW_ORPHAN_POLICY"); policy {
+	case OrphanPolicyReview:
+		return OrphanPolicyReview
+	case "", OrphanPolicyUninstall:
+		return OrphanPolicyUninstall
+	default:
+		slog.Warn("Unknown orphan policy, using default", "policy", policy, "default", OrphanPolicyUninstall)
+		return OrphanPolicyUninstall
+	}
+}
+
+// TriggerReconcile queues a reconciliation pass between installed fnOS apps and tracked containers.
+func (m *Monitor) TriggerReconcile() {
+	slog.Info("Queueing reconciliation")
+	m.queueOperation(&AppOperation{Type: "reconcile"})
+}
+
+// Orphans returns the orphaned apps awaiting review, sorted by app name.
+func (m *Monitor) Orphans() []OrphanApp {
+	m.orphans.mu.Lock()
+	defer m.orphans.mu.Unlock()
+
+	result := make([]OrphanApp, 0, len(m.orphans.apps))
+	for _, o := range m.orphans.apps {
+		result = append(result, *o)
+	}
+	sort.Slice(result, func(i, j int) bool {
+		return result[i].AppName < result[j].AppName
+	})
+	return result
+}
+
+// UninstallOrphan queues the uninstall of an orphaned app awaiting review.
+// Returns false if the app is not a WatchCow app listed as an orphan.
+func (m *Monitor) UninstallOrphan(appName string) bool {
+	if !strings.HasPrefix(appName, managedAppPrefix) {
+		return false
+	}
+	m.orphans.mu.Lock()
+	_, ok := m.orphans.apps[appName]
+	m.orphans.mu.Unlock()
+	if !ok {
+		return false
+	}
+
+	slog.Info("Queueing orphan uninstall from dashboard", "app", appName)
+	m.queueOperation(&AppOperation{
+		Type:    "dashboard_uninstall",
+		AppName: appName,
+	})
+	return true
+}
+
+// processReconcile diffs installed fnOS apps against tracked containers.
+// Installed watchcow.* apps that no container maps to are orphans: they are
+// uninstalled or recorded for review depending on the orphan policy.
+func (m *Monitor) processReconcile(ctx context.Context) {
+	if m.installer == nil {
+		return
+	}
+	if !m.listed.Load() {
+		slog.Warn("Skipping reconciliation, containers have not been listed yet")
+		return
+	}
+
+	if err := m.refreshInstalled(ctx); err != nil {
+		slog.Error("Reconciliation failed to list installed apps", "error", err)
+		return
+	}
+	installed := m.InstalledApps()
+
+	expected := m.expectedAppNames()
+	found := make(map[string]bool)
+
+	for _, a := range installed {
+		appName := a.Name
+		if !strings.HasPrefix(appName, managedAppPrefix) || expected[appName] || m.isUninstallPending(appName) {
+			continue
+		}
+		found[appName] = true
+
+		if m.orphanPolicy == OrphanPolicyUninstall {
+			slog.Info("Uninstalling orphaned fnOS app", "app", appName)
+			if m.uninstallApp(ctx, appName, 0) {
+				m.registry.Unregister(appName)
+			}
+			continue
+		}
+
+		m.orphans.mu.Lock()
+		if _, exists := m.orphans.apps[appName]; !exists {
+			slog.Warn("Found orphaned fnOS app, marked for review", "app", appName)
+			m.orphans.apps[appName] = &OrphanApp{AppName: appName, DetectedAt: time.Now()}
+		}
+		m.orphans.mu.Unlock()
+	}
+
+	// Drop orphans that were resolved since the last pass
+	m.orphans.mu.Lock()
+	for appName := range m.orphans.apps {
+		if !found[appName] || m.orphanPolicy == OrphanPolicyUninstall {
+			delete(m.orphans.apps, appName)
+		}
+	}
+	m.orphans.mu.Unlock()
+
+	slog.Info("Reconciliation completed", "installed", len(installed), "orphans", len(found))
+}
+
+// expectedAppNames returns the app names that tracked containers own or would install,
+// including stopped containers whose app stays installed while they are down.
+func (m *Monitor) expectedAppNames() map[string]bool {
+	expected := make(map[string]bool)
+	m.containers.Range(func(key, value any) bool {
+		state := value.(*ContainerState)
+		if state.AppName != "" {
+			expected[state.AppName] = true
+		}
+		if shouldInstall(state.Labels) {
+			expected[getAppNameFromLabels(state.Labels, state.ContainerName)] = true
+		} else if storedConfig := m.getStoredConfig(state.Image, state.Ports); storedConfig != nil {
+			expected[storedConfig.AppName] = true
+		} else if m.configProvider != nil {
+			// Docker lists no published ports for stopped containers, so the
+			// image|ports key misses; keep every app configured for the image
+			for _, appName := range m.configProvider.AppNamesByImage(state.Image) {
+				expected[appName] = true
+			}
+		}
+		return true
+	})
+	return expected
+}
@@ -0,0 +1,267 @@
+package docker
+
+import (
+	"context"
+	"log/slog"
+	"strings"
+	"sync"
+	"time"
+
+	"watchcow/internal/app"
+)
+
+const (
+	// maxOperationAttempts is the number of attempts before an operation is recorded as failed
+	maxOperationAttempts = 5
+
+	// retryBaseDelay is the delay before the first retry, doubled on each further attempt
+	retryBaseDelay = 5 * time.Second
+
+	// retryMaxDelay caps the backoff delay
+	retryMaxDelay = 5 * time.Minute
+)
+
+// FailedOperation is an operation that exhausted its retries.
+// Failed operations are persisted and can be retried from the dashboard.
+type FailedOperation struct {
+	Key       string // Operation key, one failed operation per container or app
+	Operation AppOperation
+	Error     string
+	FailedAt  time.Time
+}
+
+// Target returns the app or container name the operation acts on.
+func (f FailedOperation) Target() string {
+	if f.Operation.AppName != "" {
+		return f.Operation.AppName
+	}
+	if f.Operation.ContainerName != "" {
+		return f.Operation.ContainerName
+	}
+	return f.Operation.ContainerID
+}
+
+// retryTracker holds the backoff timer of each scheduled retry, by operation key.
+type retryTracker struct {
+	mu      sync.Mutex
+	timers  map[string]*time.Timer
+	stopped bool // Set on monitor shutdown; no further retries are scheduled
+}
+
+// retryDelay returns the backoff delay before the given retry attempt (1-based).
+func retryDelay(attempt int) time.Duration {
+	delay := retryBaseDelay
+	for i := 1; i < attempt; i++ {
+		delay *= 2
+		if delay >= retryMaxDelay {
+			return retryMaxDelay
+		}
+	}
+	return delay
+}
+
+// retryOperation schedules a retry of a failed operation with exponential backoff.
+// After maxOperationAttempts the operation is recorded in the failed operations list.
+// Retries are dropped if a newer operation for the same container or app was queued.
+func (m *Monitor) retryOperation(op *AppOperation, err error) {
+	retry := *op
+	retry.Attempt++
+
+	if retry.Attempt >= maxOperationAttempts {
+		slog.Error("Operation failed, giving up", "type", op.Type, "app", op.AppName, "container", op.ContainerName, "attempts", retry.Attempt, "error", err)
+		m.state.AddFailedOperation(FailedOperation{
+			Key:       operationKey(op),
+			Operation: retry,
+			Error:     err.Error(),
+			FailedAt:  time.Now(),
+		})
+		return
+	}
+
+	key := operationKey(op)
+	delay := retryDelay(retry.Attempt)
+
+	m.retries.mu.Lock()
+	defer m.retries.mu.Unlock()
+	if m.retries.stopped {
+		return
+	}
+	if m.retries.timers == nil {
+		m.retries.timers = make(map[string]*time.Timer)
+	}
+	if prev, exists := m.retries.timers[key]; exists {
+		prev.Stop()
+	}
+
+	slog.Warn("Operation failed, retrying", "type", op.Type, "app", op.AppName, "container", op.ContainerName, "attempt", retry.Attempt, "delay", delay, "error", err)
+	var timer *time.Timer
+	timer = time.AfterFunc(delay, func() {
+		m.retries.mu.Lock()
+		if m.retries.timers[key] == timer {
+			delete(m.retries.timers, key)
+		}
+		m.retries.mu.Unlock()
+
+		if !m.opQueue.pushRetry(&retry) {
+			slog.Debug("Retry superseded by newer operation", "type", retry.Type, "key", key)
+		}
+	})
+	m.retries.timers[key] = timer
+}
+
+// cancelRetry stops the scheduled retry of an operation key, if any.
+// Called when a newer operation for the same key is queued.
+func (m *Monitor) cancelRetry(key string) {
+	m.retries.mu.Lock()
+	defer m.retries.mu.Unlock()
+
+	if timer, exists := m.retries.timers[key]; exists {
+		timer.Stop()
+		delete(m.retries.timers, key)
+		slog.Debug("Cancelled retry superseded by newer operation", "key", key)
+	}
+}
+
+// stopRetries stops all scheduled retries on shutdown.
+func (m *Monitor) stopRetries() {
+	m.retries.mu.Lock()
+	defer m.retries.mu.Unlock()
+
+	m.retries.stopped = true
+	for key, timer := range m.retries.timers {
+		timer.Stop()
+		delete(m.retries.timers, key)
+	}
+}
+
+// FailedOperations returns operations that exhausted their retries, oldest first.
+func (m *Monitor) FailedOperations() []FailedOperation {
+	return m.state.FailedOperations()
+}
+
+// RetryFailedOperation removes a failed operation from the list and queues it again.
+// Returns false if no failed operation has the given key.
+func (m *Monitor) RetryFailedOperation(key string) bool {
+	f, ok := m.state.RemoveFailedOperation(key)
+	if !ok {
+		return false
+	}
+
+	slog.Info("Retrying failed operation", "type", f.Operation.Type, "target", f.Target())
+	op := f.Operation
+	op.Attempt = 0
+	m.queueOperation(&op)
+	return true
+}
+
+// UninstallFailure is an app that is still installed in fnOS because its uninstall failed.
+// The entry is kept until a later uninstall succeeds or a container adopts the app.
+type UninstallFailure struct {
+	AppName  string
+	Error    string
+	FailedAt time.Time
+	Attempts int // Number of failed attempts so far
+}
+
+// UninstallFailures returns apps whose uninstall failed, oldest first.
+func (m *Monitor) UninstallFailures() []UninstallFailure {
+	return m.state.UninstallFailures()
+}
+
+// RetryUninstall queues another uninstall of an app whose uninstall failed.
+// Returns false if the app is not a WatchCow app in the uninstall failures list.
+func (m *Monitor) RetryUninstall(appName string) bool {
+	if !strings.HasPrefix(appName, managedAppPrefix) {
+		return false
+	}
+	found := false
+	for _, f := range m.state.UninstallFailures() {
+		if f.AppName == appName {
+			found = true
+			break
+		}
+	}
+	if !found {
+		return false
+	}
+
+	slog.Info("Retrying failed uninstall", "app", appName)
+	m.queueOperation(&AppOperation{
+		Type:    "dashboard_uninstall",
+		AppName: appName,
+	})
+	return true
+}
+
+// uninstallApp uninstalls an app from fnOS, retrying failures as "uninstall" operations.
+// Returns whether the app was uninstalled.
+func (m *Monitor) uninstallApp(ctx context.Context, appName string, attempt int) bool {
+	if m.installer == nil {
+		return false
+	}
+
+	if err := m.tryUninstall(ctx, appName, attempt); err != nil {
+		op := &AppOperation{Type: "uninstall", AppName: appName, Attempt: attempt}
+		m.opQueue.stamp(op)
+		m.retryOperation(op, err)
+		return false
+	}
+	return true
+}
+
+// tryUninstall uninstalls an app once without scheduling a retry.
+// Failures are recorded in the uninstall failures list and the app is marked as
+// StatusUninstallFailed in the registry.
+func (m *Monitor) tryUninstall(ctx context.Context, appName string, attempt int) error {
+	result, err := m.installer.Uninstall(ctx, appName)
+	// The list that verified the uninstall is current, no need to run appcenter-cli list again
+	verified := result != nil && result.Verified
+	if verified {
+		m.storeInstalled(result.Installed)
+	}
+	if err != nil {
+		slog.Error("Failed to uninstall fnOS app", "app", appName, "attempt", attempt+1, "error", err)
+		m.state.SetUninstallFailure(UninstallFailure{
+			AppName:  appName,
+			Error:    err.Error(),
+			FailedAt: time.Now(),
+			Attempts: attempt + 1,
+		})
+		m.registry.UpdateStatus(appName, app.StatusUninstallFailed)
+		return err
+	}
+	if result != nil && result.StopErr != nil {
+		slog.Warn("App uninstalled but could not be stopped first", "app", appName, "error", result.StopErr)
+	}
+
+	m.state.ClearUninstallFailure(appName)
+	m.state.DeleteFingerprint(appName)
+	m.markUninstalled(appName)
+	if !verified {
+		m.queueInstalledRefresh()
+	}
+	return nil
+}
+
+// processUninstall retries the uninstall of an app, unless a container has adopted it since.
+func (m *Monitor) processUninstall(ctx context.Context, op *AppOperation) {
+	if m.isAppOwned(op.AppName) {
+		slog.Info("App adopted by a container, skipping uninstall", "app", op.AppName)
+		return
+	}
+	m.uninstallApp(ctx, op.AppName, op.Attempt)
+}
+
+// isAppOwned reports whether a tracked container has the app installed.
+func (m *Monitor) isAppOwned(appName string) bool {
+	owned := false
+	m.containers.Range(func(key, value any) bool {
+		state := value.(*ContainerState)
+		if state.AppName == appName && state.Installed {
+			owned = true
+			return false
+		}
+		return true
+	})
+	return owned
+}
@@ -0,0 +1,90 @@
+package docker
+
+import (
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestRetryDelay(t *testing.T) {
+	tests := []struct {
+		attempt int
+		want    time.Duration
+	}{
+		{1, retryBaseDelay},
+		{2, 2 * retryBaseDelay},
+		{3, 4 * retryBaseDelay},
+		{20, retryMaxDelay},
+	}
+
+	for _, tt := range tests {
+		if got := retryDelay(tt.attempt); got != tt.want {
+			t.Errorf("retryDelay(%d) = %v, want %v", tt.attempt, got, tt.want)
+		}
+	}
+}
+
+func TestRetryOperation_RecordsFailureAfterMaxAttempts(t *testing.T) {
+	t.Setenv("TRIM_PKGVAR", t.TempDir())
+	m := &Monitor{opQueue: newOperationQueue(), state: newStateStore()}
+
+	op := &AppOperation{Type: "install", ContainerID: "abc123", ContainerName: "nginx", Attempt: maxOperationAttempts - 1}
+	m.retryOperation(op, errors.New("exit status 1"))
+
+	failed := m.FailedOperations()
+	if len(failed) != 1 {
+		t.Fatalf("FailedOperations() len = %d, want 1", len(failed))
+	}
+	if failed[0].Key != "container:abc123" || failed[0].Error != "exit status 1" || failed[0].Target() != "nginx" {
+		t.Errorf("FailedOperations()[0] = %+v", failed[0])
+	}
+
+	// Failed operations survive a restart
+	if got := newStateStore().FailedOperations(); len(got) != 1 {
+		t.Errorf("persisted FailedOperations() len = %d, want 1", len(got))
+	}
+
+	// Manual retry removes the entry and queues the operation with a fresh attempt count
+	if !m.RetryFailedOperation("container:abc123") {
+		t.Fatal("RetryFailedOperation() = false, want true")
+	}
+	if len(m.FailedOperations()) != 0 {
+		t.Error("FailedOperations() not empty after retry")
+	}
+	queued, ok := m.opQueue.pop()
+	if !ok || queued.Type != "install" || queued.Attempt != 0 {
+		t.Errorf("queued operation = %+v, want install with attempt 0", queued)
+	}
+	if m.RetryFailedOperation("container:abc123") {
+		t.Error("RetryFailedOperation() = true for unknown key")
+	}
+}
+
+func TestRetryOperation_TimersAreCancelled(t *testing.T) {
+	t.Setenv("TRIM_PKGVAR", t.TempDir())
+	m := &Monitor{opQueue: newOperationQueue(), state: newStateStore()}
+
+	// A newer operation for the same key cancels the pending retry
+	m.retryOperation(&AppOperation{Type: "stop", ContainerID: "abc123"}, errors.New("exit status 1"))
+	if len(m.retries.timers) != 1 {
+		t.Fatalf("retry timers = %d, want 1", len(m.retries.timers))
+	}
+	m.queueOperation(&AppOperation{Type: "container_start", ContainerID: "abc123"})
+	if len(m.retries.timers) != 0 {
+		t.Errorf("retry timers = %d after newer operation, want 0", len(m.retries.timers))
+	}
+
+	// A second failure of the same key replaces the first timer
+	m.retryOperation(&AppOperation{Type: "uninstall", AppName: "watchcow.nginx"}, errors.New("exit status 1"))
+	m.retryOperation(&AppOperation{Type: "uninstall", AppName: "watchcow.nginx", Attempt: 1}, errors.New("exit status 1"))
+	if len(m.retries.timers) != 1 {
+		t.Errorf("retry timers = %d, want 1 per key", len(m.retries.timers))
+	}
+
+	// Shutdown stops all timers and schedules no further retries
+	m.stopRetries()
+	m.retryOperation(&AppOperation{Type: "stop", ContainerID: "def456"}, errors.New("exit status 1"))
+	if len(m.retries.timers) != 0 {
+		t.Errorf("retry timers = %d after stop, want 0", len(m.retries.timers))
+	}
+}
@@ -0,0 +1,273 @@
+package docker
+
+import (
+	"encoding/gob"
+	"log/slog"
+	"maps"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+	"time"
+)
+
+// monitorState is the monitor's persisted runtime state.
+type monitorState struct {
+	Fingerprints map[string]string          // appName -> fingerprint of the installed package
+	FailedOps    map[string]FailedOperation // operation key -> operation that exhausted its retries
+
+	UninstallFailures map[string]UninstallFailure // appName -> last failed uninstall
+	Pinned            map[string]string           // appName -> fingerprint not upgraded to after a rollback
+	GraceDeadlines    map[string]time.Time        // appName -> end of the destroy grace period
+}
+
+// stateStore persists monitorState across restarts.
+// If TRIM_PKGVAR is set, uses ${TRIM_PKGVAR}/monitor.gob.
+// Otherwise uses /tmp/watchcow/monitor.gob.
+type stateStore struct {
+	mu       sync.Mutex
+	data     monitorState
+	filePath string
+}
+
+// newStateStore creates a state store and loads existing state.
+// Load errors are logged and the store starts empty.
+func newStateStore() *stateStore {
+	var filePath string
+	if pkgVar := os.Getenv("TRIM_PKGVAR"); pkgVar != "" {
+		filePath = filepath.Join(pkgVar, "monitor.gob")
+	} else {
+		filePath = "/tmp/watchcow/monitor.gob"
+	}
+
+	s := &stateStore{filePath: filePath}
+	if err := s.load(); err != nil {
+		slog.Warn("Failed to load monitor state, starting fresh", "path", filePath, "error", err)
+	}
+	s.init()
+	return s
+}
+
+// init allocates nil maps (fresh store or state saved by an older version)
+func (s *stateStore) init() {
+	if s.data.Fingerprints == nil {
+		s.data.Fingerprints = make(map[string]string)
+	}
+	if s.data.FailedOps == nil {
+		s.data.FailedOps = make(map[string]FailedOperation)
+	}
+	if s.data.UninstallFailures == nil {
+		s.data.UninstallFailures = make(map[string]UninstallFailure)
+	}
+	if s.data.Pinned == nil {
+		s.data.Pinned = make(map[string]string)
+	}
+	if s.data.GraceDeadlines == nil {
+		s.data.GraceDeadlines = make(map[string]time.Time)
+	}
+}
+
+// load reads state from disk.
+func (s *stateStore) load() error {
+	f, err := os.Open(s.filePath)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil
+		}
+		return err
+	}
+	defer f.Close()
+
+	return gob.NewDecoder(f).Decode(&s.data)
+}
+
+// save writes state to disk using atomic write (write-to-temp + rename).
+// Caller must hold s.mu.
+func (s *stateStore) save() {
+	if err := os.MkdirAll(filepath.Dir(s.filePath), 0755); err != nil {
+		slog.Warn("Failed to create monitor state directory", "error", err)
+		return
+	}
+
+	tmpPath := s.filePath + ".tmp"
+	f, err := os.Create(tmpPath)
+	if err != nil {
+		slog.Warn("Failed to save monitor state", "error", err)
+		return
+	}
+
+	if err := gob.NewEncoder(f).Encode(s.data); err != nil {
+		f.Close()
+		os.Remove(tmpPath)
+		slog.Warn("Failed to encode monitor state", "error", err)
+		return
+	}
+
+	if err := f.Sync(); err != nil {
+		f.Close()
+		os.Remove(tmpPath)
+		slog.Warn("Failed to sync monitor state", "error", err)
+		return
+	}
+	f.Close()
+
+	if err := os.Rename(tmpPath, s.filePath); err != nil {
+		slog.Warn("Failed to replace monitor state", "error", err)
+	}
+}
+
+// Fingerprint returns the fingerprint of the installed package, or "" if unknown.
+func (s *stateStore) Fingerprint(appName string) string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.data.Fingerprints[appName]
+}
+
+// SetFingerprint records the fingerprint of the installed package.
+func (s *stateStore) SetFingerprint(appName, fingerprint string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.data.Fingerprints[appName] == fingerprint {
+		return
+	}
+	s.data.Fingerprints[appName] = fingerprint
+	s.save()
+}
+
+// DeleteFingerprint forgets the fingerprint and rollback pin of an uninstalled app.
+func (s *stateStore) DeleteFingerprint(appName string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	_, hasFingerprint := s.data.Fingerprints[appName]
+	_, hasPin := s.data.Pinned[appName]
+	if !hasFingerprint && !hasPin {
+		return
+	}
+	delete(s.data.Fingerprints, appName)
+	delete(s.data.Pinned, appName)
+	s.save()
+}
+
+// Pinned returns the fingerprint an app was rolled back from, or "" if none.
+func (s *stateStore) Pinned(appName string) string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.data.Pinned[appName]
+}
+
+// SetPinned records that an app was rolled back from the given fingerprint.
+func (s *stateStore) SetPinned(appName, fingerprint string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.data.Pinned[appName] = fingerprint
+	s.save()
+}
+
+// Unpin forgets a rollback once the app is installed from a new config.
+func (s *stateStore) Unpin(appName string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if _, ok := s.data.Pinned[appName]; !ok {
+		return
+	}
+	delete(s.data.Pinned, appName)
+	s.save()
+}
+
+// FailedOperations returns the failed operations, oldest first.
+func (s *stateStore) FailedOperations() []FailedOperation {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	result := make([]FailedOperation, 0, len(s.data.FailedOps))
+	for _, f := range s.data.FailedOps {
+		result = append(result, f)
+	}
+	sort.Slice(result, func(i, j int) bool {
+		return result[i].FailedAt.Before(result[j].FailedAt)
+	})
+	return result
+}
+
+// AddFailedOperation records an operation that exhausted its retries.
+// A previous failure with the same key is replaced.
+func (s *stateStore) AddFailedOperation(f FailedOperation) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.data.FailedOps[f.Key] = f
+	s.save()
+}
+
+// RemoveFailedOperation removes a failed operation.
+// Returns the removed operation and whether it existed.
+func (s *stateStore) RemoveFailedOperation(key string) (FailedOperation, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	f, ok := s.data.FailedOps[key]
+	if !ok {
+		return FailedOperation{}, false
+	}
+	delete(s.data.FailedOps, key)
+	s.save()
+	return f, true
+}
+
+// UninstallFailures returns apps whose last uninstall failed, oldest first.
+func (s *stateStore) UninstallFailures() []UninstallFailure {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	result := make([]UninstallFailure, 0, len(s.data.UninstallFailures))
+	for _, f := range s.data.UninstallFailures {
+		result = append(result, f)
+	}
+	sort.Slice(result, func(i, j int) bool {
+		return result[i].FailedAt.Before(result[j].FailedAt)
+	})
+	return result
+}
+
+// SetUninstallFailure records a failed uninstall, replacing any earlier failure of the app.
+func (s *stateStore) SetUninstallFailure(f UninstallFailure) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.data.UninstallFailures[f.AppName] = f
+	s.save()
+}
+
+// ClearUninstallFailure forgets a failed uninstall once the app is gone or adopted.
+func (s *stateStore) ClearUninstallFailure(appName string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if _, ok := s.data.UninstallFailures[appName]; !ok {
+		return
+	}
+	delete(s.data.UninstallFailures, appName)
+	s.save()
+}
+
+// GraceDeadlines returns the apps waiting out the destroy grace period and when it ends.
+func (s *stateStore) GraceDeadlines() map[string]time.Time {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return maps.Clone(s.data.GraceDeadlines)
+}
+
+// SetGraceDeadline records the end of an app's destroy grace period.
+func (s *stateStore) SetGraceDeadline(appName string, deadline time.Time) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.data.GraceDeadlines[appName] = deadline
+	s.save()
+}
+
+// ClearGraceDeadline forgets a grace period once the app is adopted or uninstalled.
+func (s *stateStore) ClearGraceDeadline(appName string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if _, ok := s.data.GraceDeadlines[appName]; !ok {
+		return
+	}
+	delete(s.data.GraceDeadlines, appName)
+	s.save()
+}
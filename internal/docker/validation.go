@@ -0,0 +1,58 @@
+package docker
+
+import (
+	"log/slog"
+	"sort"
+	"time"
+
+	"watchcow/internal/fpkgen"
+)
+
+// LabelReport lists the label problems of a label-configured container.
+type LabelReport struct {
+	ContainerID   string
+	ContainerName string
+	AppName       string
+	Problems      fpkgen.Problems
+	CheckedAt     time.Time
+}
+
+// checkLabels validates the watchcow labels of an operation's container.
+// Problems are logged and kept for the dashboard until the labels are fixed.
+// Returns an error wrapping fpkgen.ErrInvalidLabels if installation must be blocked.
+func (m *Monitor) checkLabels(op *AppOperation) error {
+	problems := fpkgen.ValidateLabels(op.Labels)
+	if len(problems) == 0 {
+		m.labelReports.Delete(op.ContainerID)
+		return nil
+	}
+
+	for _, p := range problems {
+		if p.Severity == fpkgen.SeverityError {
+			slog.Error("Invalid watchcow label", "container", op.ContainerName, "label", p.Label, "value", p.Value, "problem", p.Message)
+		} else {
+			slog.Warn("Suspicious watchcow label", "container", op.ContainerName, "label", p.Label, "value", p.Value, "problem", p.Message)
+		}
+	}
+	m.labelReports.Store(op.ContainerID, &LabelReport{
+		ContainerID:   op.ContainerID,
+		ContainerName: op.ContainerName,
+		AppName:       getAppNameFromLabels(op.Labels, op.ContainerName),
+		Problems:      problems,
+		CheckedAt:     time.Now(),
+	})
+	return problems.Err()
+}
+
+// LabelReports returns the label problems of all containers, sorted by container name.
+func (m *Monitor) LabelReports() []LabelReport {
+	var result []LabelReport
+	m.labelReports.Range(func(_, value any) bool {
+		result = append(result, *value.(*LabelReport))
+		return true
+	})
+	sort.Slice(result, func(i, j int) bool {
+		return result[i].ContainerName < result[j].ContainerName
+	})
+	return result
+}
@@ -0,0 +1,72 @@
+package docker
+
+import (
+	"testing"
+
+	"watchcow/internal/app"
+	"watchcow/internal/fpkgen"
+)
+
+func TestMonitor_InvalidLabelsBlockInstall(t *testing.T) {
+	cli := newFakeDocker()
+	labels := testLabels("watchcow.nginx")
+	labels["watchcow.all_users"] = "yes"
+	labels["watchcow.titel"] = "Nginx"
+	// The container never becomes healthy: labels must be checked before the readiness wait
+	labels["watchcow.wait_for"] = "healthy"
+	labels["watchcow.wait_timeout"] = "10m"
+	cli.Create("aaaaaaaaaaaa", "nginx", "nginx:1.25", labels, map[string]string{"80": "8080"})
+	cli.Start("aaaaaaaaaaaa")
+	installer := newFakeInstaller()
+	m := newTestMonitor(t, cli, installer)
+
+	startTestMonitor(t, m)
+
+	waitFor(t, "label report", func() bool { return len(m.LabelReports()) == 1 })
+	waitIdle(t, m)
+	if installer.Has("watchcow.nginx") {
+		t.Error("app with invalid labels should not be installed")
+	}
+	if _, waiting := m.readinessWaits.Load("aaaaaaaaaaaa"); waiting {
+		t.Error("container with invalid labels should not wait for readiness")
+	}
+	if _, tracked := m.containers.Load("aaaaaaaaaaaa"); !tracked {
+		t.Error("container with invalid labels should stay tracked")
+	}
+	if a := m.Registry().Get("watchcow.nginx"); a == nil || a.Status != app.StatusInstallFailed {
+		t.Errorf("registry app = %+v, want status %s", a, app.StatusInstallFailed)
+	}
+
+	report := m.LabelReports()[0]
+	if report.ContainerName != "nginx" || report.AppName != "watchcow.nginx" {
+		t.Errorf("report = %+v", report)
+	}
+	if len(report.Problems) != 2 || !report.Problems.HasErrors() {
+		t.Errorf("Problems = %v, want an error and a warning", report.Problems)
+	}
+
+	cli.Remove("aaaaaaaaaaaa")
+	waitFor(t, "report cleared", func() bool { return len(m.LabelReports()) == 0 })
+	waitIdle(t, m)
+	if _, tracked := m.containers.Load("aaaaaaaaaaaa"); tracked {
+		t.Error("destroyed container should no longer be tracked")
+	}
+}
+
+func TestMonitor_LabelWarningsDoNotBlockInstall(t *testing.T) {
+	cli := newFakeDocker()
+	labels := testLabels("watchcow.nginx")
+	labels["watchcow.titel"] = "Nginx"
+	cli.Create("aaaaaaaaaaaa", "nginx", "nginx:1.25", labels, map[string]string{"80": "8080"})
+	cli.Start("aaaaaaaaaaaa")
+	installer := newFakeInstaller()
+	m := newTestMonitor(t, cli, installer)
+
+	startTestMonitor(t, m)
+
+	waitFor(t, "install", func() bool { return installer.Has("watchcow.nginx") })
+	reports := m.LabelReports()
+	if len(reports) != 1 || reports[0].Problems[0].Kind != fpkgen.ProblemUnknownKey {
+		t.Errorf("LabelReports() = %+v, want the unknown label warning", reports)
+	}
+}
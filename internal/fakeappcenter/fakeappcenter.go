@@ -0,0 +1,283 @@
+// Package fakeappcenter implements a fake appcenter-cli for testing WatchCow off-device.
+//
+// Installed apps are kept in a JSON state file instead of the fnOS app center,
+// and "list" prints the same box-drawing table as the real CLI.
+//
+// Environment:
+//   - FAKE_APPCENTER_STATE: state file path (default: $TMPDIR/fake-appcenter/state.json)
+//   - FAKE_APPCENTER_FAIL: comma-separated subcommands that exit with an error
+//   - FAKE_APPCENTER_IGNORE: comma-separated subcommands that report success without effect
+//   - FAKE_APPCENTER_DELAY: Go duration to sleep before each command (simulates a hung CLI)
+package fakeappcenter
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"time"
+	"unicode/utf8"
+)
+
+// App status values reported by list
+const (
+	StatusRunning = "running"
+	StatusStopped = "stopped"
+)
+
+// App is an installed app in the fake app center.
+type App struct {
+	AppName     string `json:"appname"`
+	Version     string `json:"version"`
+	DisplayName string `json:"display_name"`
+	Status      string `json:"status"`
+}
+
+// State is the persisted fake app center state.
+type State struct {
+	Apps []App `json:"apps"`
+}
+
+// StatePath returns the state file path from environment.
+func StatePath() string {
+	if path := os.Getenv("FAKE_APPCENTER_STATE"); path != "" {
+		return path
+	}
+	return filepath.Join(os.TempDir(), "fake-appcenter", "state.json")
+}
+
+// LoadState reads the state file. A missing file is an empty state.
+func LoadState(path string) (*State, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return &State{}, nil
+		}
+		return nil, err
+	}
+
+	var state State
+	if err := json.Unmarshal(data, &state); err != nil {
+		return nil, fmt.Errorf("parse state file: %w", err)
+	}
+	return &state, nil
+}
+
+// Save writes the state file atomically.
+func (s *State) Save(path string) error {
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return err
+	}
+
+	data, err := json.MarshalIndent(s, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	tmpPath := path + ".tmp"
+	if err := os.WriteFile(tmpPath, data, 0644); err != nil {
+		return err
+	}
+	return os.Rename(tmpPath, path)
+}
+
+// find returns the index of an installed app, or -1.
+func (s *State) find(appName string) int {
+	return slices.IndexFunc(s.Apps, func(a App) bool {
+		return a.AppName == appName
+	})
+}
+
+// Run executes a fake appcenter-cli command and returns the process exit code.
+// The working directory is used as the app directory for install-local.
+func Run(args []string, stdout, stderr io.Writer) int {
+	if len(args) == 0 {
+		fmt.Fprintln(stderr, "usage: appcenter-cli <install-local|list|start|stop|uninstall> [appname]")
+		return 2
+	}
+
+	if v := os.Getenv("FAKE_APPCENTER_DELAY"); v != "" {
+		if d, err := time.ParseDuration(v); err == nil {
+			time.Sleep(d)
+		}
+	}
+
+	cmd := args[0]
+	if listed("FAKE_APPCENTER_IGNORE", cmd) {
+		fmt.Fprintf(stdout, "%s: ok\n", cmd)
+		return 0
+	}
+	if listed("FAKE_APPCENTER_FAIL", cmd) {
+		fmt.Fprintf(stderr, "Error: %s failed (injected by FAKE_APPCENTER_FAIL)\n", cmd)
+		return 1
+	}
+
+	path := StatePath()
+	state, err := LoadState(path)
+	if err != nil {
+		fmt.Fprintf(stderr, "Error: load state: %v\n", err)
+		return 1
+	}
+
+	switch cmd {
+	case "list":
+		printTable(stdout, state.Apps)
+		return 0
+
+	case "install-local":
+		err = installLocal(state, stdout)
+
+	case "start", "stop", "uninstall":
+		if len(args) < 2 {
+			fmt.Fprintf(stderr, "usage: appcenter-cli %s <appname>\n", cmd)
+			return 2
+		}
+		err = appCommand(state, cmd, args[1], stdout)
+
+	default:
+		fmt.Fprintf(stderr, "Error: unknown command %q\n", cmd)
+		return 2
+	}
+
+	if err != nil {
+		fmt.Fprintf(stderr, "Error: %v\n", err)
+		return 1
+	}
+
+	if err := state.Save(path); err != nil {
+		fmt.Fprintf(stderr, "Error: save state: %v\n", err)
+		return 1
+	}
+	return 0
+}
+
+// listed reports whether the comma-separated environment variable lists the subcommand
+func listed(env, cmd string) bool {
+	for _, c := range strings.Split(os.Getenv(env), ",") {
+		if strings.TrimSpace(c) == cmd {
+			return true
+		}
+	}
+	return false
+}
+
+// installLocal installs or upgrades the app described by ./manifest.
+// Replacing an installed app keeps its status.
+func installLocal(state *State, stdout io.Writer) error {
+	manifest, err := readManifest("manifest")
+	if err != nil {
+		return fmt.Errorf("read manifest: %w", err)
+	}
+
+	appName := manifest["appname"]
+	if appName == "" {
+		return fmt.Errorf("manifest: appname is required")
+	}
+	if manifest["version"] == "" {
+		return fmt.Errorf("manifest: version is required")
+	}
+
+	app := App{
+		AppName:     appName,
+		Version:     manifest["version"],
+		DisplayName: manifest["display_name"],
+		Status:      StatusRunning,
+	}
+
+	if i := state.find(appName); i >= 0 {
+		app.Status = state.Apps[i].Status
+		state.Apps[i] = app
+		fmt.Fprintf(stdout, "Upgraded %s to %s\n", appName, app.Version)
+		return nil
+	}
+
+	state.Apps = append(state.Apps, app)
+	fmt.Fprintf(stdout, "Installed %s %s\n", appName, app.Version)
+	return nil
+}
+
+// appCommand runs start, stop or uninstall on an installed app
+func appCommand(state *State, cmd, appName string, stdout io.Writer) error {
+	i := state.find(appName)
+	if i < 0 {
+		return fmt.Errorf("app %s is not installed", appName)
+	}
+
+	switch cmd {
+	case "start":
+		state.Apps[i].Status = StatusRunning
+	case "stop":
+		state.Apps[i].Status = StatusStopped
+	case "uninstall":
+		state.Apps = slices.Delete(state.Apps, i, i+1)
+	}
+
+	fmt.Fprintf(stdout, "%s %s: ok\n", cmd, appName)
+	return nil
+}
+
+// readManifest parses a fnOS manifest (key = value lines, # comments)
+func readManifest(path string) (map[string]string, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	values := make(map[string]string)
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		key, value, ok := strings.Cut(line, "=")
+		if !ok {
+			continue
+		}
+		values[strings.TrimSpace(key)] = strings.Trim(strings.TrimSpace(value), `"`)
+	}
+	return values, scanner.Err()
+}
+
+// printTable prints apps as a box-drawing table like the real appcenter-cli
+func printTable(w io.Writer, apps []App) {
+	rows := [][]string{{"appname", "version", "status"}}
+	for _, a := range apps {
+		rows = append(rows, []string{a.AppName, a.Version, a.Status})
+	}
+
+	widths := make([]int, len(rows[0]))
+	for _, row := range rows {
+		for i, cell := range row {
+			widths[i] = max(widths[i], utf8.RuneCountInString(cell))
+		}
+	}
+
+	border := func(left, mid, right string) {
+		parts := make([]string, len(widths))
+		for i, width := range widths {
+			parts[i] = strings.Repeat("─", width+2)
+		}
+		fmt.Fprintln(w, left+strings.Join(parts, mid)+right)
+	}
+	line := func(row []string) {
+		parts := make([]string, len(row))
+		for i, cell := range row {
+			parts[i] = " " + cell + strings.Repeat(" ", widths[i]-utf8.RuneCountInString(cell)) + " "
+		}
+		fmt.Fprintln(w, "│"+strings.Join(parts, "│")+"│")
+	}
+
+	border("┌", "┬", "┐")
+	line(rows[0])
+	border("├", "┼", "┤")
+	for _, row := range rows[1:] {
+		line(row)
+	}
+	border("└", "┴", "┘")
+}
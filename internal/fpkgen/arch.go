@@ -0,0 +1,76 @@
+package fpkgen
+
+import (
+	"context"
+	"log/slog"
+	"runtime"
+	"strings"
+
+	"github.com/docker/docker/api/types/image"
+	"github.com/docker/docker/client"
+
+	"watchcow/internal/app"
+)
+
+// imageInspector inspects Docker images.
+// Implemented by *client.Client; clients without it fall back to the host architecture.
+type imageInspector interface {
+	ImageInspect(ctx context.Context, imageID string, opts ...client.ImageInspectOption) (image.InspectResponse, error)
+}
+
+// NormalizeArch maps a Docker, Go or fnOS architecture name to the fnOS manifest arch.
+// Returns false for architectures fnOS does not support.
+func NormalizeArch(arch string) (string, bool) {
+	switch strings.ToLower(arch) {
+	case "x86_64", "amd64":
+		return app.ArchX86_64, true
+	case "arm64", "aarch64":
+		return app.ArchARM64, true
+	default:
+		return "", false
+	}
+}
+
+// HostArch returns the fnOS manifest arch of the host WatchCow runs on.
+func HostArch() string {
+	if arch, ok := NormalizeArch(runtime.GOARCH); ok {
+		return arch
+	}
+	return app.ArchX86_64
+}
+
+// platformForArch returns the fnOS manifest platform for an arch.
+func platformForArch(arch string) string {
+	if arch == app.ArchARM64 {
+		return "arm"
+	}
+	return "x86"
+}
+
+// ResolveArch returns the fnOS manifest arch for a container.
+// The watchcow.arch label wins; otherwise the architecture of the container image
+// is used, falling back to the host architecture if the image cannot be inspected.
+func (g *Generator) ResolveArch(ctx context.Context, labels map[string]string, imageID string) string {
+	if v := labels["watchcow.arch"]; v != "" {
+		if arch, ok := NormalizeArch(v); ok {
+			return arch
+		}
+		slog.Warn("Unknown watchcow.arch, using image architecture", "value", v)
+	}
+
+	inspector, ok := g.dockerClient.(imageInspector)
+	if !ok || imageID == "" {
+		return HostArch()
+	}
+	img, err := inspector.ImageInspect(ctx, imageID)
+	if err != nil {
+		slog.Debug("Failed to inspect image, using host architecture", "image", imageID, "error", err)
+		return HostArch()
+	}
+	arch, ok := NormalizeArch(img.Architecture)
+	if !ok {
+		slog.Warn("Unsupported image architecture, using host architecture", "image", imageID, "arch", img.Architecture)
+		return HostArch()
+	}
+	return arch
+}
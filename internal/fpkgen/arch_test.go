@@ -0,0 +1,105 @@
+package fpkgen
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+
+	dockercontainer "github.com/docker/docker/api/types/container"
+	"github.com/docker/docker/api/types/image"
+	"github.com/docker/docker/client"
+)
+
+// fakeImageClient serves container and image inspection for arch resolution tests.
+type fakeImageClient struct {
+	archs map[string]string // image ID -> Docker architecture
+}
+
+func (f *fakeImageClient) ContainerInspect(ctx context.Context, containerID string) (dockercontainer.InspectResponse, error) {
+	return dockercontainer.InspectResponse{}, errors.New("not implemented")
+}
+
+func (f *fakeImageClient) ImageInspect(ctx context.Context, imageID string, opts ...client.ImageInspectOption) (image.InspectResponse, error) {
+	arch, ok := f.archs[imageID]
+	if !ok {
+		return image.InspectResponse{}, errors.New("no such image")
+	}
+	return image.InspectResponse{ID: imageID, Architecture: arch}, nil
+}
+
+func TestNormalizeArch(t *testing.T) {
+	tests := []struct {
+		in   string
+		want string
+		ok   bool
+	}{
+		{"amd64", "x86_64", true},
+		{"x86_64", "x86_64", true},
+		{"arm64", "arm64", true},
+		{"AArch64", "arm64", true},
+		{"arm", "", false},
+		{"riscv64", "", false},
+	}
+	for _, tt := range tests {
+		got, ok := NormalizeArch(tt.in)
+		if got != tt.want || ok != tt.ok {
+			t.Errorf("NormalizeArch(%q) = %q, %v; want %q, %v", tt.in, got, ok, tt.want, tt.ok)
+		}
+	}
+}
+
+func TestResolveArch(t *testing.T) {
+	g := &Generator{dockerClient: &fakeImageClient{archs: map[string]string{
+		"sha256:arm":   "arm64",
+		"sha256:amd":   "amd64",
+		"sha256:riscv": "riscv64",
+	}}}
+
+	tests := []struct {
+		name   string
+		labels map[string]string
+		image  string
+		want   string
+	}{
+		{"image arm64", nil, "sha256:arm", "arm64"},
+		{"image amd64", nil, "sha256:amd", "x86_64"},
+		{"label overrides image", map[string]string{"watchcow.arch": "aarch64"}, "sha256:amd", "arm64"},
+		{"unknown label uses image", map[string]string{"watchcow.arch": "mips"}, "sha256:arm", "arm64"},
+		{"unsupported image uses host", nil, "sha256:riscv", HostArch()},
+		{"missing image uses host", nil, "sha256:missing", HostArch()},
+	}
+	for _, tt := range tests {
+		if got := g.ResolveArch(context.Background(), tt.labels, tt.image); got != tt.want {
+			t.Errorf("%s: ResolveArch() = %q, want %q", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestManifest_Arch(t *testing.T) {
+	te, err := NewTemplateEngine()
+	if err != nil {
+		t.Fatalf("NewTemplateEngine() error = %v", err)
+	}
+
+	tests := []struct {
+		arch string
+		want []string
+	}{
+		{"x86_64", []string{"arch=x86_64\n", "platform=x86\n"}},
+		{"arm64", []string{"arch=arm64\n", "platform=arm\n"}},
+	}
+	for _, tt := range tests {
+		config := fingerprintTestConfig()
+		config.Arch = tt.arch
+		out, err := te.Render("manifest.tmpl", NewTemplateData(config))
+		if err != nil {
+			t.Fatalf("Render() error = %v", err)
+		}
+		for _, want := range tt.want {
+			if !strings.Contains(string(out), want) {
+				t.Errorf("arch %s: manifest missing %q:\n%s", tt.arch, want, out)
+			}
+		}
+	}
+}
@@ -0,0 +1,44 @@
+package fpkgen
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+)
+
+// Fingerprint returns a hash identifying the app config a package is generated from,
+// so the monitor can detect label, image or dashboard changes and upgrade installed
+// apps in place.
+//
+// Runtime-only fields (container ID, environment, raw labels, status) are excluded:
+// they change on every container recreate without affecting the package.
+// Template contents are excluded too: a WatchCow release or template override that
+// changes templates must not upgrade every installed app at startup. The new
+// templates apply the next time an app's config changes.
+// The resolved arch is excluded as well, only an explicit watchcow.arch label counts:
+// it falls back to the host arch when the image cannot be inspected, and a
+// temporary inspect failure must not upgrade the app.
+func Fingerprint(config *AppConfig) string {
+	c := *config
+	c.ContainerID = ""
+	c.Environment = nil
+	c.Arch = ""
+	c.Labels = nil
+	c.RestartPolicy = ""
+	c.Status = ""
+
+	input := struct {
+		Config    AppConfig
+		BasePath  string // resolves relative file:// icons
+		ArchLabel string
+	}{
+		Config:    c,
+		BasePath:  getBasePath(config.Labels),
+		ArchLabel: config.Labels["watchcow.arch"],
+	}
+
+	// AppConfig only holds plain data, marshalling cannot fail
+	data, _ := json.Marshal(input)
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:])
+}
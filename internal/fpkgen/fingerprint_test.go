@@ -0,0 +1,71 @@
+package fpkgen
+
+import "testing"
+
+func newFingerprintTestGenerator(t *testing.T) *Generator {
+	t.Helper()
+	te, err := NewTemplateEngine()
+	if err != nil {
+		t.Fatalf("NewTemplateEngine() error = %v", err)
+	}
+	return &Generator{templateEngine: te}
+}
+
+func fingerprintTestConfig() *AppConfig {
+	return &AppConfig{
+		AppName:       "watchcow.nginx",
+		DisplayName:   "Nginx",
+		Version:       "1.0.0",
+		ContainerID:   "abc123",
+		ContainerName: "nginx",
+		Image:         "nginx:1.25",
+		Labels:        map[string]string{"watchcow.enable": "true"},
+		Entries: []Entry{
+			{Title: "Nginx", Protocol: "http", Port: "8080", Path: "/"},
+		},
+	}
+}
+
+// TestFingerprint_IgnoresRuntimeFields tests that recreating a container does not change the fingerprint
+func TestFingerprint_IgnoresRuntimeFields(t *testing.T) {
+	a := fingerprintTestConfig()
+	b := fingerprintTestConfig()
+	b.ContainerID = "def456"
+	b.Environment = []string{"FOO=bar"}
+	b.Labels["com.docker.compose.container-number"] = "2"
+	b.Arch = "arm64" // resolved from the image, or the host when inspect fails
+
+	if Fingerprint(a) != Fingerprint(b) {
+		t.Error("Fingerprint() changed for runtime-only fields")
+	}
+}
+
+// TestFingerprint_DetectsChanges tests that package-affecting changes change the fingerprint
+func TestFingerprint_DetectsChanges(t *testing.T) {
+	base := Fingerprint(fingerprintTestConfig())
+
+	tests := []struct {
+		name   string
+		modify func(c *AppConfig)
+	}{
+		{"image", func(c *AppConfig) { c.Image = "nginx:1.27" }},
+		{"display name", func(c *AppConfig) { c.DisplayName = "Web" }},
+		{"entry port", func(c *AppConfig) { c.Entries[0].Port = "9090" }},
+		{"lifecycle", func(c *AppConfig) { c.Lifecycle = "bidirectional" }},
+		{"arch label", func(c *AppConfig) { c.Labels["watchcow.arch"] = "arm64" }},
+		{"share", func(c *AppConfig) { c.Shares = []DataShare{{Name: "html", Source: "/srv/html"}} }},
+		{"wizard", func(c *AppConfig) { c.Wizard.RemoveContainer = true }},
+		{"manifest field", func(c *AppConfig) { c.Manifest = map[string]string{"ctl_stop": "false"} }},
+		{"compose dir", func(c *AppConfig) { c.Labels["com.docker.compose.project.working_dir"] = "/srv/nginx" }},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := fingerprintTestConfig()
+			tt.modify(c)
+			if Fingerprint(c) == base {
+				t.Errorf("Fingerprint() unchanged after %s change", tt.name)
+			}
+		})
+	}
+}
@@ -3,6 +3,7 @@ package fpkgen
 import (
 	"context"
 	"fmt"
+	"io"
 	"log/slog"
 	"os"
 	"path/filepath"
@@ -10,12 +11,20 @@ import (
 
 	dockercontainer "github.com/docker/docker/api/types/container"
 	"github.com/docker/docker/client"
+
+	"watchcow/internal/app"
 )
 
+// ContainerInspector inspects Docker containers.
+// Implemented by *client.Client; tests use an in-memory fake.
+type ContainerInspector interface {
+	ContainerInspect(ctx context.Context, containerID string) (dockercontainer.InspectResponse, error)
+}
+
 // Generator handles fnOS application package generation from Docker containers
 type Generator struct {
-	dockerClient   *client.Client  // Docker API client
-	templateEngine *TemplateEngine // Template engine for rendering
+	dockerClient   ContainerInspector // Docker API client
+	templateEngine *TemplateEngine    // Template engine for rendering
 }
 
 // NewGenerator creates a new application generator
@@ -25,10 +34,20 @@ func NewGenerator() (*Generator, error) {
 		return nil, fmt.Errorf("failed to create Docker client: %w", err)
 	}
 
+	g, err := NewGeneratorWithClient(cli)
+	if err != nil {
+		cli.Close()
+		return nil, err
+	}
+	return g, nil
+}
+
+// NewGeneratorWithClient creates a generator that inspects containers through cli.
+// The generator closes cli on Close if it implements io.Closer.
+func NewGeneratorWithClient(cli ContainerInspector) (*Generator, error) {
 	// Initialize template engine
 	tmplEngine, err := NewTemplateEngine()
 	if err != nil {
-		cli.Close()
 		return nil, fmt.Errorf("failed to create template engine: %w", err)
 	}
 
@@ -41,42 +60,45 @@ func NewGenerator() (*Generator, error) {
 // GenerateFromContainer creates fnOS app structure from a running container
 // Returns the config, temp directory path (caller should clean up after install)
 func (g *Generator) GenerateFromContainer(ctx context.Context, containerID string) (*AppConfig, string, error) {
-	// 1. Inspect container for full details
-	container, err := g.dockerClient.ContainerInspect(ctx, containerID)
+	config, err := g.ConfigFromContainer(ctx, containerID)
 	if err != nil {
-		return nil, "", fmt.Errorf("failed to inspect container: %w", err)
+		return nil, "", err
 	}
 
-	// 2. Extract configuration from container
-	config := g.extractConfig(&container)
-
-	// 3. Create temp directory for app package
-	appDir, err := os.MkdirTemp("", "watchcow-"+config.AppName+"-")
+	appDir, err := g.GenerateToTempDir(config)
 	if err != nil {
-		return nil, "", fmt.Errorf("failed to create temp directory: %w", err)
+		return nil, "", err
 	}
 
-	if err := g.createDirectoryStructure(appDir); err != nil {
-		os.RemoveAll(appDir)
-		return nil, "", fmt.Errorf("failed to create directory structure: %w", err)
-	}
+	return config, appDir, nil
+}
 
-	// 4. Generate all files using templates
-	slog.Info("Generating fnOS app package", "appName", config.AppName, "container", config.ContainerName)
+// ConfigFromContainer inspects a container and extracts its AppConfig without generating files
+func (g *Generator) ConfigFromContainer(ctx context.Context, containerID string) (*AppConfig, error) {
+	container, err := g.dockerClient.ContainerInspect(ctx, containerID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to inspect container: %w", err)
+	}
 
-	data := NewTemplateData(config)
+	config := g.extractConfig(&container)
+	config.Arch = g.ResolveArch(ctx, container.Config.Labels, container.Image)
+	return config, nil
+}
 
-	if err := g.generateFromTemplates(appDir, data); err != nil {
-		return nil, "", err
+// GenerateToTempDir generates the package for config into a new temp directory
+// Returns the directory path (caller should clean up after install)
+func (g *Generator) GenerateToTempDir(config *AppConfig) (string, error) {
+	appDir, err := os.MkdirTemp("", "watchcow-"+config.AppName+"-")
+	if err != nil {
+		return "", fmt.Errorf("failed to create temp directory: %w", err)
 	}
 
-	if err := g.handleIcons(appDir, config); err != nil {
-		return nil, "", fmt.Errorf("failed to handle icons: %w", err)
+	if err := g.GenerateFromConfig(config, appDir); err != nil {
+		os.RemoveAll(appDir)
+		return "", err
 	}
 
-	slog.Info("Successfully generated fnOS app package", "appDir", appDir)
-
-	return config, appDir, nil
+	return appDir, nil
 }
 
 // GenerateFromConfig creates fnOS app structure from an AppConfig directly
@@ -96,7 +118,7 @@ func (g *Generator) GenerateFromConfig(config *AppConfig, appDir string) error {
 
 	data := NewTemplateData(config)
 
-	if err := g.generateFromTemplates(appDir, data); err != nil {
+	if err := g.generateFromTemplates(g.templatesFor(config), appDir, data); err != nil {
 		return err
 	}
 
@@ -108,8 +130,19 @@ func (g *Generator) GenerateFromConfig(config *AppConfig, appDir string) error {
 	return nil
 }
 
+// templatesFor returns the template set selected by config.
+// An unknown set falls back to the default set.
+func (g *Generator) templatesFor(config *AppConfig) *TemplateEngine {
+	engine, ok := g.templateEngine.Set(config.TemplateSet)
+	if !ok {
+		slog.Warn("Unknown watchcow.template_set, using default templates", "app", config.AppName, "set", config.TemplateSet)
+		return g.templateEngine
+	}
+	return engine
+}
+
 // generateFromTemplates generates all files using template engine
-func (g *Generator) generateFromTemplates(appDir string, data *TemplateData) error {
+func (g *Generator) generateFromTemplates(engine *TemplateEngine, appDir string, data *TemplateData) error {
 	// Define template -> file mappings
 	mappings := []struct {
 		template string
@@ -118,6 +151,7 @@ func (g *Generator) generateFromTemplates(appDir string, data *TemplateData) err
 	}{
 		{"manifest.tmpl", "manifest", 0644},
 		{"cmd_main.tmpl", "cmd/main", 0755},
+		{"cmd_uninstall_callback.tmpl", "cmd/uninstall_callback", 0755},
 		{"config_privilege.json.tmpl", "config/privilege", 0644},
 		{"config_resource.json.tmpl", "config/resource", 0644},
 		{"LICENSE.tmpl", "LICENSE", 0644},
@@ -125,7 +159,7 @@ func (g *Generator) generateFromTemplates(appDir string, data *TemplateData) err
 
 	for _, m := range mappings {
 		filePath := filepath.Join(appDir, m.path)
-		if err := g.templateEngine.RenderToFile(m.template, filePath, data, m.perm); err != nil {
+		if err := engine.RenderToFile(m.template, filePath, data, m.perm); err != nil {
 			return fmt.Errorf("failed to generate %s: %w", m.path, err)
 		}
 	}
@@ -140,18 +174,21 @@ func (g *Generator) generateFromTemplates(appDir string, data *TemplateData) err
 		return fmt.Errorf("failed to write UI config: %w", err)
 	}
 
+	if err := writeWizards(appDir, data); err != nil {
+		return fmt.Errorf("failed to generate wizards: %w", err)
+	}
+
 	// Generate install_callback with CGI symlink support
 	installCallbackPath := filepath.Join(appDir, "cmd", "install_callback")
-	if err := g.templateEngine.RenderToFile("cmd_install_callback.tmpl", installCallbackPath, data, 0755); err != nil {
+	if err := engine.RenderToFile("cmd_install_callback.tmpl", installCallbackPath, data, 0755); err != nil {
 		return fmt.Errorf("failed to generate cmd/install_callback: %w", err)
 	}
 
 	// Generate other empty cmd scripts
-	cmdScripts := []string{"install_init", "uninstall_init", "uninstall_callback",
-		"upgrade_init", "upgrade_callback", "config_init", "config_callback"}
+	cmdScripts := []string{"install_init", "uninstall_init", "upgrade_init", "upgrade_callback", "config_init", "config_callback"}
 	for _, script := range cmdScripts {
 		filePath := filepath.Join(appDir, "cmd", script)
-		if err := g.templateEngine.RenderToFile("cmd_empty.tmpl", filePath, data, 0755); err != nil {
+		if err := engine.RenderToFile("cmd_empty.tmpl", filePath, data, 0755); err != nil {
 			return fmt.Errorf("failed to generate cmd/%s: %w", script, err)
 		}
 	}
@@ -171,13 +208,18 @@ func (g *Generator) generateFromTemplates(appDir string, data *TemplateData) err
 //	watchcow.protocol     -> UI config (http/https)
 //	watchcow.path         -> UI config (url path)
 //	watchcow.icon         -> app icon URL
+//	watchcow.lifecycle    -> cmd/main start/stop (docker or bidirectional)
+//	watchcow.arch         -> manifest.arch/platform (see ResolveArch)
+//	watchcow.manifest.<key> -> manifest.<key> (see ManifestKeys)
+//	watchcow.template_set -> template set from the override directory
+//	watchcow.share.<name> -> config/resource data-share (see SharesFromLabels)
+//	watchcow.share_binds  -> data-shares for all bind mounts
+//	watchcow.wizard.*     -> wizard/install, wizard/uninstall (see WizardFromLabels)
 func (g *Generator) extractConfig(container *dockercontainer.InspectResponse) *AppConfig {
 	name := strings.TrimPrefix(container.Name, "/")
 	labels := container.Config.Labels
 
-	// Generate sanitized app name
-	sanitizedName := sanitizeAppName(name)
-	appName := getLabel(labels, "watchcow.appname", fmt.Sprintf("watchcow.%s", sanitizedName))
+	appName := AppNameFromLabels(labels, name)
 
 	defaultIcon := getLabel(labels, "watchcow.icon", buildIconURLFromImage(container.Config.Image)) // URL → URLIconSource
 	displayName := getLabel(labels, "watchcow.display_name", prettifyName(name))
@@ -198,6 +240,9 @@ func (g *Generator) extractConfig(container *dockercontainer.InspectResponse) *A
 		AllUsers:      getLabel(labels, "watchcow.all_users", "true") == "true",
 		Icon:          defaultIcon,
 		Environment:   filterEnvironment(container.Config.Env),
+		Lifecycle:     LifecycleFromLabels(labels),
+		Manifest:      ManifestFromLabels(labels),
+		TemplateSet:   labels["watchcow.template_set"],
 		Labels:        labels,
 	}
 
@@ -227,15 +272,10 @@ func (g *Generator) extractConfig(container *dockercontainer.InspectResponse) *A
 		}}
 	}
 
-	// Extract volumes
-	for _, mount := range container.Mounts {
-		config.Volumes = append(config.Volumes, VolumeMapping{
-			Source:      mount.Source,
-			Destination: mount.Destination,
-			ReadOnly:    !mount.RW,
-			Type:        string(mount.Type),
-		})
-	}
+	// Extract volumes and the ones exposed as data-shares
+	config.Volumes = VolumesFromMounts(container.Mounts)
+	config.Shares = SharesFromLabels(labels, config.Volumes)
+	config.Wizard = WizardFromLabels(labels, config.Volumes)
 
 	// Extract restart policy
 	if container.HostConfig.RestartPolicy.Name != "" {
@@ -267,12 +307,29 @@ func (g *Generator) createDirectoryStructure(appDir string) error {
 
 // Close closes the Docker client
 func (g *Generator) Close() error {
-	if g.dockerClient != nil {
-		return g.dockerClient.Close()
+	if closer, ok := g.dockerClient.(io.Closer); ok {
+		return closer.Close()
 	}
 	return nil
 }
 
+// AppNameFromLabels returns the fnOS app name for a container: the
+// watchcow.appname label if set, otherwise "watchcow.<sanitized container name>".
+func AppNameFromLabels(labels map[string]string, containerName string) string {
+	return getLabel(labels, "watchcow.appname", "watchcow."+sanitizeAppName(containerName))
+}
+
+// ShouldInstall reports whether the labels enable installing the container as an fnOS app:
+// watchcow.enable=true, with watchcow.install unset, "fnos" or "true".
+func ShouldInstall(labels map[string]string) bool {
+	if labels["watchcow.enable"] != "true" {
+		return false
+	}
+
+	installMode := labels["watchcow.install"]
+	return installMode == "fnos" || installMode == "true" || installMode == ""
+}
+
 // Helper functions
 
 // sanitizeAppName ensures the app name conforms to fnOS requirements
@@ -288,6 +345,20 @@ func sanitizeAppName(name string) string {
 	return result.String()
 }
 
+// LifecycleFromLabels returns the lifecycle mode from the watchcow.lifecycle label.
+// Unknown values fall back to app.LifecycleDocker.
+func LifecycleFromLabels(labels map[string]string) string {
+	switch v := labels["watchcow.lifecycle"]; v {
+	case "", app.LifecycleDocker:
+		return app.LifecycleDocker
+	case app.LifecycleBidirectional:
+		return app.LifecycleBidirectional
+	default:
+		slog.Warn("Unknown watchcow.lifecycle, using docker", "value", v)
+		return app.LifecycleDocker
+	}
+}
+
 // getLabel gets a label value with fallback
 func getLabel(labels map[string]string, key, fallback string) string {
 	if val, ok := labels[key]; ok && val != "" {
@@ -6,6 +6,7 @@ import (
 	"fmt"
 	"image"
 	"image/png"
+	"log/slog"
 	"os"
 	"path/filepath"
 
@@ -32,7 +33,7 @@ func (g *Generator) handleIcons(appDir string, config *AppConfig) error {
 	for _, entry := range config.Entries {
 		entryIcon, err := loadIcon(entry.Icon, basePath)
 		if err != nil && entry.Icon != "" {
-			fmt.Printf("Warning: Failed to load icon for entry '%s': %v\n", entry.Name, err)
+			slog.Warn("Failed to load icon, using default", "entry", entry.Name, "error", err)
 		}
 
 		// Use default icon if loading failed
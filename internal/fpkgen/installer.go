@@ -1,16 +1,65 @@
 package fpkgen
 
 import (
+	"bytes"
+	"context"
+	"errors"
 	"fmt"
 	"log/slog"
 	"os"
 	"os/exec"
+	"slices"
 	"strings"
+	"time"
 )
 
+const (
+	// defaultCLITimeout bounds a single appcenter-cli call when WATCHCOW_APPCENTER_TIMEOUT is not set
+	defaultCLITimeout = 2 * time.Minute
+
+	// cliWaitDelay is how long to wait for output after appcenter-cli is killed
+	cliWaitDelay = 5 * time.Second
+)
+
+// CLIError describes a failed appcenter-cli call.
+type CLIError struct {
+	Subcommand string   // e.g. "install-local", "uninstall"
+	Args       []string // Arguments after the subcommand
+	ExitCode   int      // Process exit code, -1 if it did not exit normally (killed, timed out, not found)
+	Output     string   // Combined stdout and stderr
+	Err        error    // Underlying error (context.DeadlineExceeded on timeout)
+}
+
+func (e *CLIError) Error() string {
+	msg := fmt.Sprintf("appcenter-cli %s failed", e.Subcommand)
+	if errors.Is(e.Err, context.DeadlineExceeded) {
+		msg += " (timed out)"
+	} else if e.ExitCode >= 0 {
+		msg += fmt.Sprintf(" (exit code %d)", e.ExitCode)
+	} else {
+		msg += fmt.Sprintf(": %v", e.Err)
+	}
+	if e.Output != "" {
+		msg += ": " + e.Output
+	}
+	return msg
+}
+
+func (e *CLIError) Unwrap() error {
+	return e.Err
+}
+
+// InstalledApp is an app listed by appcenter-cli list.
+type InstalledApp struct {
+	Name    string
+	Version string
+	Status  string // e.g. "running", "stopped"
+}
+
 // Installer handles fnOS application installation via appcenter-cli
 type Installer struct {
 	appcenterCLIPath string
+	timeout          time.Duration // Per-call timeout
 }
 
 // NewInstaller creates a new installer
@@ -23,11 +72,36 @@ func NewInstaller() (*Installer, error) {
 
 	return &Installer{
 		appcenterCLIPath: cliPath,
+		timeout:          getCLITimeout(),
 	}, nil
 }
 
-// findAppcenterCLI locates the appcenter-cli binary
+// getCLITimeout returns the appcenter-cli call timeout from WATCHCOW_APPCENTER_TIMEOUT
+// (Go duration such as "90s" or "5m"), defaulting to defaultCLITimeout.
+func getCLITimeout() time.Duration {
+	v := os.Getenv("WATCHCOW_APPCENTER_TIMEOUT")
+	if v == "" {
+		return defaultCLITimeout
+	}
+	d, err := time.ParseDuration(v)
+	if err != nil || d <= 0 {
+		slog.Warn("Invalid WATCHCOW_APPCENTER_TIMEOUT, using default", "value", v, "default", defaultCLITimeout)
+		return defaultCLITimeout
+	}
+	return d
+}
+
+// findAppcenterCLI locates the appcenter-cli binary.
+// WATCHCOW_APPCENTER_CLI overrides discovery, e.g. to use fake-appcenter-cli off-device.
 func findAppcenterCLI() (string, error) {
+	if p := os.Getenv("WATCHCOW_APPCENTER_CLI"); p != "" {
+		if _, err := os.Stat(p); err != nil {
+			return "", fmt.Errorf("WATCHCOW_APPCENTER_CLI: %w", err)
+		}
+		slog.Debug("Using appcenter-cli from WATCHCOW_APPCENTER_CLI", "path", p)
+		return p, nil
+	}
+
 	// Try common locations on fnOS
 	paths := []string{
 		"/var/apps/appcenter/target/bin/appcenter-cli",
@@ -64,105 +138,216 @@ func findAppcenterCLI() (string, error) {
 }
 
 // InstallLocal installs an application from local directory
-func (i *Installer) InstallLocal(appDir string) error {
+func (i *Installer) InstallLocal(ctx context.Context, appDir string) error {
 	slog.Info("Installing fnOS app via appcenter-cli", "appDir", appDir)
 
-	cmd := exec.Command(i.appcenterCLIPath, "install-local")
-	cmd.Dir = appDir
-	cmd.Stdout = os.Stdout
-	cmd.Stderr = os.Stderr
-
-	if err := cmd.Run(); err != nil {
-		return fmt.Errorf("appcenter-cli install-local failed: %w", err)
+	if _, err := i.run(ctx, appDir, "install-local"); err != nil {
+		return err
 	}
 
 	slog.Info("Successfully installed fnOS app")
 	return nil
 }
 
-// Uninstall uninstalls an application
-func (i *Installer) Uninstall(appName string) error {
+// Upgrade replaces an installed application from local directory without uninstalling it first.
+// appcenter-cli has no upgrade command, so this runs install-local over the installed app;
+// fnOS's upgrade_init/upgrade_callback flow is not used.
+func (i *Installer) Upgrade(ctx context.Context, appDir string) error {
+	slog.Info("Upgrading fnOS app via appcenter-cli", "appDir", appDir)
+
+	if _, err := i.run(ctx, appDir, "install-local"); err != nil {
+		return err
+	}
+
+	slog.Info("Successfully upgraded fnOS app")
+	return nil
+}
+
+// ErrStillInstalled is returned by Uninstall when the app is still listed afterwards.
+var ErrStillInstalled = errors.New("app still installed after uninstall")
+
+// UninstallResult describes the outcome of an uninstall.
+type UninstallResult struct {
+	AppName   string
+	StopErr   error          // Failure to stop the app first; uninstall is attempted anyway
+	Verified  bool           // Removal was confirmed by appcenter-cli list
+	Installed []InstalledApp // Apps listed after the uninstall, if Verified
+}
+
+// Uninstall stops and uninstalls an application, then verifies via appcenter-cli list
+// that it is gone. An app that is no longer listed counts as uninstalled even if the
+// uninstall command failed (e.g. it was removed manually). Returns an error if the app
+// is still installed, or if uninstall failed and the result could not be verified.
+func (i *Installer) Uninstall(ctx context.Context, appName string) (*UninstallResult, error) {
 	slog.Info("Uninstalling fnOS app", "appName", appName)
+	result := &UninstallResult{AppName: appName}
 
 	// First stop the app
-	stopCmd := exec.Command(i.appcenterCLIPath, "stop", appName)
-	stopCmd.Run() // Ignore stop errors
+	if _, err := i.run(ctx, "", "stop", appName); err != nil {
+		slog.Debug("Failed to stop app before uninstall", "appName", appName, "error", err)
+		result.StopErr = err
+	}
 
-	// Try to uninstall with appName as argument
-	uninstallCmd := exec.Command(i.appcenterCLIPath, "uninstall", appName)
-	output, err := uninstallCmd.CombinedOutput()
-	if err != nil {
-		// Try without argument (some versions may work differently)
-		slog.Debug("Uninstall with appName failed, trying alternate method",
-			"appName", appName,
-			"output", string(output))
-
-		// Log warning but don't fail - app may need manual uninstall
-		slog.Warn("Could not uninstall fnOS app automatically",
-			"appName", appName,
-			"hint", "may need manual uninstall from App Center")
-		return nil
+	_, uninstallErr := i.run(ctx, "", "uninstall", appName)
+
+	// Verify against the installed list
+	apps, listErr := i.ListInstalled(ctx)
+	if listErr != nil {
+		if uninstallErr != nil {
+			return result, uninstallErr
+		}
+		slog.Warn("Could not verify fnOS app uninstall", "appName", appName, "error", listErr)
+		slog.Info("Successfully uninstalled fnOS app", "appName", appName)
+		return result, nil
 	}
 
+	result.Verified = true
+	result.Installed = apps
+	if slices.ContainsFunc(apps, func(a InstalledApp) bool { return a.Name == appName }) {
+		if uninstallErr != nil {
+			return result, uninstallErr
+		}
+		return result, fmt.Errorf("%w: %s", ErrStillInstalled, appName)
+	}
+
+	if uninstallErr != nil {
+		slog.Debug("Uninstall command failed but app is no longer installed", "appName", appName, "error", uninstallErr)
+	}
 	slog.Info("Successfully uninstalled fnOS app", "appName", appName)
-	return nil
+	return result, nil
 }
 
 // StartApp starts an installed application
-func (i *Installer) StartApp(appName string) error {
+func (i *Installer) StartApp(ctx context.Context, appName string) error {
 	slog.Info("Starting fnOS app", "appName", appName)
 
-	cmd := exec.Command(i.appcenterCLIPath, "start", appName)
-	cmd.Stdout = os.Stdout
-	cmd.Stderr = os.Stderr
-
-	if err := cmd.Run(); err != nil {
-		return fmt.Errorf("failed to start app: %w", err)
-	}
-
-	return nil
+	_, err := i.run(ctx, "", "start", appName)
+	return err
 }
 
 // StopApp stops an installed application
-func (i *Installer) StopApp(appName string) error {
+func (i *Installer) StopApp(ctx context.Context, appName string) error {
 	slog.Info("Stopping fnOS app", "appName", appName)
 
-	cmd := exec.Command(i.appcenterCLIPath, "stop", appName)
-	cmd.Stdout = os.Stdout
-	cmd.Stderr = os.Stderr
+	_, err := i.run(ctx, "", "stop", appName)
+	return err
+}
 
-	if err := cmd.Run(); err != nil {
-		return fmt.Errorf("failed to stop app: %w", err)
+// ListInstalled returns all installed apps with version and status
+func (i *Installer) ListInstalled(ctx context.Context) ([]InstalledApp, error) {
+	output, err := i.run(ctx, "", "list")
+	if err != nil {
+		return nil, err
 	}
 
-	return nil
+	return parseInstalledApps(output), nil
 }
 
 // IsAppInstalled checks if an app is installed by parsing appcenter-cli list output
-func (i *Installer) IsAppInstalled(appName string) bool {
-	cmd := exec.Command(i.appcenterCLIPath, "list")
-	output, err := cmd.Output()
+func (i *Installer) IsAppInstalled(ctx context.Context, appName string) bool {
+	apps, err := i.ListInstalled(ctx)
 	if err != nil {
 		slog.Debug("Failed to list apps", "error", err)
 		return false
 	}
 
-	// Parse table output - look for appName in the first column
-	lines := strings.Split(string(output), "\n")
-	for _, line := range lines {
-		// Skip header and separator lines
-		if strings.HasPrefix(line, "│") {
-			// Extract first column (app name)
-			parts := strings.Split(line, "│")
-			if len(parts) >= 2 {
-				installedApp := strings.TrimSpace(parts[1])
-				if installedApp == appName {
-					slog.Debug("App already installed", "appName", appName)
-					return true
-				}
-			}
+	for _, installedApp := range apps {
+		if installedApp.Name == appName {
+			slog.Debug("App already installed", "appName", appName)
+			return true
 		}
 	}
 
 	return false
 }
+
+// run executes an appcenter-cli subcommand in dir (empty for the current directory),
+// bounded by the installer timeout. Returns the combined stdout and stderr output;
+// failures are returned as *CLIError.
+func (i *Installer) run(ctx context.Context, dir, subcommand string, args ...string) (string, error) {
+	ctx, cancel := context.WithTimeout(ctx, i.timeout)
+	defer cancel()
+
+	var output bytes.Buffer
+	cmd := exec.CommandContext(ctx, i.appcenterCLIPath, append([]string{subcommand}, args...)...)
+	cmd.Dir = dir
+	cmd.Stdout = &output
+	cmd.Stderr = &output
+	// Don't wait forever for grandchildren holding the output pipe after a kill
+	cmd.WaitDelay = cliWaitDelay
+
+	err := cmd.Run()
+	slog.Debug("appcenter-cli finished", "subcommand", subcommand, "args", args, "output", output.String())
+	if err == nil {
+		return output.String(), nil
+	}
+
+	cliErr := &CLIError{
+		Subcommand: subcommand,
+		Args:       args,
+		ExitCode:   -1,
+		Output:     strings.TrimSpace(output.String()),
+		Err:        err,
+	}
+	var exitErr *exec.ExitError
+	if errors.As(err, &exitErr) {
+		cliErr.ExitCode = exitErr.ExitCode()
+	}
+	if ctxErr := ctx.Err(); ctxErr != nil {
+		cliErr.Err = ctxErr
+	}
+	return "", cliErr
+}
+
+// parseInstalledApps parses the appcenter-cli list table into records.
+// Columns are located by the header row (appname, version, status);
+// without a recognizable header the first three columns are used in that order.
+func parseInstalledApps(output string) []InstalledApp {
+	nameCol, versionCol, statusCol := 0, 1, 2
+	var apps []InstalledApp
+	headerSeen := false
+
+	for _, line := range strings.Split(output, "\n") {
+		if !strings.HasPrefix(line, "│") {
+			continue
+		}
+
+		parts := strings.Split(strings.Trim(strings.TrimSpace(line), "│"), "│")
+		cells := make([]string, len(parts))
+		for i, p := range parts {
+			cells[i] = strings.TrimSpace(p)
+		}
+
+		if !headerSeen {
+			headerSeen = true
+			if cols := headerColumns(cells); cols != nil {
+				nameCol, versionCol, statusCol = cols["appname"], cols["version"], cols["status"]
+				continue
+			}
+		}
+
+		cell := func(i int) string {
+			if i >= 0 && i < len(cells) {
+				return cells[i]
+			}
+			return ""
+		}
+		if name := cell(nameCol); name != "" {
+			apps = append(apps, InstalledApp{Name: name, Version: cell(versionCol), Status: cell(statusCol)})
+		}
+	}
+	return apps
+}
+
+// headerColumns returns column indexes by lowercase header name,
+// or nil if the row is not a header (no appname column).
+func headerColumns(cells []string) map[string]int {
+	cols := map[string]int{"version": -1, "status": -1}
+	for i, c := range cells {
+		cols[strings.ToLower(c)] = i
+	}
+	if _, ok := cols["appname"]; !ok {
+		return nil
+	}
+	return cols
+}
@@ -0,0 +1,233 @@
+package fpkgen
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"watchcow/internal/fakeappcenter"
+)
+
+// TestMain lets the test binary act as fake appcenter-cli when re-executed by Installer.
+func TestMain(m *testing.M) {
+	if os.Getenv("WATCHCOW_TEST_FAKE_APPCENTER") == "1" {
+		os.Exit(fakeappcenter.Run(os.Args[1:], os.Stdout, os.Stderr))
+	}
+	os.Exit(m.Run())
+}
+
+// newFakeInstaller returns an Installer backed by the fake appcenter-cli with a fresh state file.
+func newFakeInstaller(t *testing.T) *Installer {
+	t.Helper()
+	t.Setenv("WATCHCOW_TEST_FAKE_APPCENTER", "1")
+	t.Setenv("FAKE_APPCENTER_STATE", filepath.Join(t.TempDir(), "state.json"))
+	t.Setenv("FAKE_APPCENTER_FAIL", "")
+	t.Setenv("FAKE_APPCENTER_IGNORE", "")
+	t.Setenv("FAKE_APPCENTER_DELAY", "")
+	return &Installer{appcenterCLIPath: os.Args[0], timeout: defaultCLITimeout}
+}
+
+// writeTestApp writes a minimal app directory with a manifest.
+func writeTestApp(t *testing.T, appName, version string) string {
+	t.Helper()
+	appDir := t.TempDir()
+	manifest := "appname=" + appName + "\nversion=" + version + "\ndisplay_name=Test\n"
+	if err := os.WriteFile(filepath.Join(appDir, "manifest"), []byte(manifest), 0644); err != nil {
+		t.Fatal(err)
+	}
+	return appDir
+}
+
+func TestParseInstalledApps(t *testing.T) {
+	output := `┌──────────────────┬─────────┬─────────┐
+│ appname          │ version │ status  │
+├──────────────────┼─────────┼─────────┤
+│ watchcow         │ 0.3.0   │ running │
+│ watchcow.nginx   │ 1.0.0   │ stopped │
+└──────────────────┴─────────┴─────────┘
+`
+	got := parseInstalledApps(output)
+	want := []InstalledApp{
+		{Name: "watchcow", Version: "0.3.0", Status: "running"},
+		{Name: "watchcow.nginx", Version: "1.0.0", Status: "stopped"},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("parseInstalledApps() = %v, want %v", got, want)
+	}
+}
+
+func TestParseInstalledApps_HeaderOrder(t *testing.T) {
+	output := `│ Status  │ AppName        │ Version │
+│ running │ watchcow.nginx │ 1.0.0   │
+`
+	got := parseInstalledApps(output)
+	want := []InstalledApp{{Name: "watchcow.nginx", Version: "1.0.0", Status: "running"}}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("parseInstalledApps() = %v, want %v", got, want)
+	}
+}
+
+func TestParseInstalledApps_Empty(t *testing.T) {
+	if got := parseInstalledApps(""); len(got) != 0 {
+		t.Errorf("parseInstalledApps(\"\") = %v, want empty", got)
+	}
+}
+
+func TestInstaller_Lifecycle(t *testing.T) {
+	ctx := context.Background()
+	installer := newFakeInstaller(t)
+
+	if installer.IsAppInstalled(ctx, "watchcow.nginx") {
+		t.Fatal("IsAppInstalled() = true before install")
+	}
+
+	if err := installer.InstallLocal(ctx, writeTestApp(t, "watchcow.nginx", "1.0.0")); err != nil {
+		t.Fatalf("InstallLocal() error = %v", err)
+	}
+	if !installer.IsAppInstalled(ctx, "watchcow.nginx") {
+		t.Fatal("IsAppInstalled() = false after install")
+	}
+
+	if err := installer.Upgrade(ctx, writeTestApp(t, "watchcow.nginx", "1.1.0")); err != nil {
+		t.Fatalf("Upgrade() error = %v", err)
+	}
+	if err := installer.StopApp(ctx, "watchcow.nginx"); err != nil {
+		t.Fatalf("StopApp() error = %v", err)
+	}
+	if err := installer.StartApp(ctx, "watchcow.nginx"); err != nil {
+		t.Fatalf("StartApp() error = %v", err)
+	}
+
+	installed, err := installer.ListInstalled(ctx)
+	if err != nil {
+		t.Fatalf("ListInstalled() error = %v", err)
+	}
+	if want := []InstalledApp{{Name: "watchcow.nginx", Version: "1.1.0", Status: "running"}}; !reflect.DeepEqual(installed, want) {
+		t.Errorf("ListInstalled() = %v, want %v", installed, want)
+	}
+
+	result, err := installer.Uninstall(ctx, "watchcow.nginx")
+	if err != nil {
+		t.Fatalf("Uninstall() error = %v", err)
+	}
+	if !result.Verified || len(result.Installed) != 0 {
+		t.Errorf("Uninstall() result = %+v, want verified with no apps left", result)
+	}
+	if installer.IsAppInstalled(ctx, "watchcow.nginx") {
+		t.Error("IsAppInstalled() = true after uninstall")
+	}
+}
+
+func TestInstaller_StartNotInstalled(t *testing.T) {
+	ctx := context.Background()
+	installer := newFakeInstaller(t)
+
+	if err := installer.StartApp(ctx, "watchcow.missing"); err == nil {
+		t.Error("StartApp() error = nil for app that is not installed")
+	}
+}
+
+func TestInstaller_InjectedFailure(t *testing.T) {
+	ctx := context.Background()
+	installer := newFakeInstaller(t)
+	t.Setenv("FAKE_APPCENTER_FAIL", "install-local")
+
+	if err := installer.InstallLocal(ctx, writeTestApp(t, "watchcow.nginx", "1.0.0")); err == nil {
+		t.Error("InstallLocal() error = nil with injected failure")
+	}
+	if installer.IsAppInstalled(ctx, "watchcow.nginx") {
+		t.Error("IsAppInstalled() = true after failed install")
+	}
+}
+
+func TestInstaller_CLIError(t *testing.T) {
+	ctx := context.Background()
+	installer := newFakeInstaller(t)
+
+	err := installer.StartApp(ctx, "watchcow.missing")
+
+	var cliErr *CLIError
+	if !errors.As(err, &cliErr) {
+		t.Fatalf("StartApp() error = %v, want *CLIError", err)
+	}
+	if cliErr.Subcommand != "start" || cliErr.ExitCode != 1 {
+		t.Errorf("CLIError = {Subcommand: %q, ExitCode: %d}, want {start, 1}", cliErr.Subcommand, cliErr.ExitCode)
+	}
+	if !strings.Contains(cliErr.Output, "watchcow.missing is not installed") {
+		t.Errorf("CLIError.Output = %q, want captured stderr", cliErr.Output)
+	}
+	if !strings.Contains(err.Error(), "exit code 1") {
+		t.Errorf("Error() = %q, want exit code", err.Error())
+	}
+}
+
+func TestInstaller_Timeout(t *testing.T) {
+	installer := newFakeInstaller(t)
+	installer.timeout = 100 * time.Millisecond
+	t.Setenv("FAKE_APPCENTER_DELAY", "10s")
+
+	start := time.Now()
+	_, err := installer.ListInstalled(context.Background())
+
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("ListInstalled() error = %v, want deadline exceeded", err)
+	}
+	if elapsed := time.Since(start); elapsed > 5*time.Second {
+		t.Errorf("ListInstalled() returned after %v, want bounded by timeout", elapsed)
+	}
+}
+
+func TestInstaller_UninstallFailure(t *testing.T) {
+	ctx := context.Background()
+	installer := newFakeInstaller(t)
+	if err := installer.InstallLocal(ctx, writeTestApp(t, "watchcow.nginx", "1.0.0")); err != nil {
+		t.Fatalf("InstallLocal() error = %v", err)
+	}
+	t.Setenv("FAKE_APPCENTER_FAIL", "stop,uninstall")
+
+	result, err := installer.Uninstall(ctx, "watchcow.nginx")
+
+	var cliErr *CLIError
+	if !errors.As(err, &cliErr) || cliErr.Subcommand != "uninstall" {
+		t.Fatalf("Uninstall() error = %v, want uninstall CLIError", err)
+	}
+	if result.StopErr == nil {
+		t.Error("Uninstall() result should record stop failure")
+	}
+}
+
+func TestInstaller_UninstallNotVerified(t *testing.T) {
+	ctx := context.Background()
+	installer := newFakeInstaller(t)
+	if err := installer.InstallLocal(ctx, writeTestApp(t, "watchcow.nginx", "1.0.0")); err != nil {
+		t.Fatalf("InstallLocal() error = %v", err)
+	}
+	// uninstall exits 0 but leaves the app installed
+	t.Setenv("FAKE_APPCENTER_IGNORE", "uninstall")
+
+	_, err := installer.Uninstall(ctx, "watchcow.nginx")
+
+	if !errors.Is(err, ErrStillInstalled) {
+		t.Errorf("Uninstall() error = %v, want ErrStillInstalled", err)
+	}
+}
+
+func TestInstaller_UninstallAlreadyRemoved(t *testing.T) {
+	ctx := context.Background()
+	installer := newFakeInstaller(t)
+
+	// uninstall fails because the app is not installed, which is the desired state
+	result, err := installer.Uninstall(ctx, "watchcow.gone")
+
+	if err != nil {
+		t.Errorf("Uninstall() error = %v, want nil for app that is not installed", err)
+	}
+	if result == nil || !result.Verified {
+		t.Error("Uninstall() result should be verified")
+	}
+}
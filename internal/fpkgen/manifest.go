@@ -0,0 +1,84 @@
+package fpkgen
+
+import (
+	"fmt"
+	"log/slog"
+	"regexp"
+	"slices"
+	"strings"
+)
+
+// manifestLabelPrefix is the label namespace for extra fnOS manifest fields
+const manifestLabelPrefix = "watchcow.manifest."
+
+// ManifestKeys lists the fnOS manifest fields that can be set through
+// watchcow.manifest.<key> labels or the dashboard, in manifest order.
+// Fields WatchCow derives itself (appname, version, arch, ...) have dedicated labels.
+var ManifestKeys = []string{
+	"maintainer_url",
+	"distributor",
+	"distributor_url",
+	"os_min_version",
+	"os_max_version",
+	"ctl_stop",
+	"changelog",
+}
+
+// defaultOSMinVersion is written unless os_min_version is set
+const defaultOSMinVersion = "0.9.0"
+
+var manifestVersionPattern = regexp.MustCompile(`^[0-9]+(\.[0-9]+)*$`)
+
+// ValidateManifestField checks a manifest field value set by a label or the dashboard.
+func ValidateManifestField(key, value string) error {
+	if !slices.Contains(ManifestKeys, key) {
+		return fmt.Errorf("unknown manifest field %q", key)
+	}
+	if strings.ContainsAny(value, "\r\n") {
+		return fmt.Errorf("manifest field %s must be a single line", key)
+	}
+
+	switch key {
+	case "maintainer_url", "distributor_url":
+		if !strings.HasPrefix(value, "http://") && !strings.HasPrefix(value, "https://") {
+			return fmt.Errorf("manifest field %s must be an http(s) URL", key)
+		}
+	case "os_min_version", "os_max_version":
+		if !manifestVersionPattern.MatchString(value) {
+			return fmt.Errorf("manifest field %s must be a version like 0.9.0", key)
+		}
+	case "ctl_stop":
+		if value != "true" && value != "false" {
+			return fmt.Errorf("manifest field %s must be true or false", key)
+		}
+	}
+	return nil
+}
+
+// ManifestFromLabels returns the extra manifest fields set by watchcow.manifest.<key> labels.
+// Invalid fields are logged and skipped. Returns nil if no field is set.
+func ManifestFromLabels(labels map[string]string) map[string]string {
+	var fields map[string]string
+	for label, value := range labels {
+		key, ok := strings.CutPrefix(label, manifestLabelPrefix)
+		if !ok {
+			continue
+		}
+		value = strings.TrimSpace(value)
+		if err := ValidateManifestField(key, value); err != nil {
+			slog.Warn("Ignoring invalid manifest label", "label", label, "error", err)
+			continue
+		}
+		if fields == nil {
+			fields = make(map[string]string)
+		}
+		fields[key] = value
+	}
+	return fields
+}
+
+// ManifestField is an extra key=value line of the generated manifest.
+type ManifestField struct {
+	Key   string
+	Value string
+}
@@ -0,0 +1,97 @@
+package fpkgen
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestValidateManifestField(t *testing.T) {
+	tests := []struct {
+		key, value string
+		wantErr    bool
+	}{
+		{"maintainer_url", "https://example.com", false},
+		{"maintainer_url", "example.com", true},
+		{"os_min_version", "0.9.27", false},
+		{"os_min_version", "latest", true},
+		{"ctl_stop", "false", false},
+		{"ctl_stop", "no", true},
+		{"changelog", "Fix login", false},
+		{"changelog", "line1\nline2", true},
+		{"appname", "watchcow.evil", true},
+		{"install_type", "user", true},
+	}
+	for _, tt := range tests {
+		err := ValidateManifestField(tt.key, tt.value)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("ValidateManifestField(%q, %q) error = %v, wantErr %v", tt.key, tt.value, err, tt.wantErr)
+		}
+	}
+}
+
+func TestManifestFromLabels(t *testing.T) {
+	fields := ManifestFromLabels(map[string]string{
+		"watchcow.enable":                  "true",
+		"watchcow.manifest.changelog":      " Fix login ",
+		"watchcow.manifest.ctl_stop":       "false",
+		"watchcow.manifest.appname":        "watchcow.evil",
+		"watchcow.manifest.os_min_version": "soon",
+	})
+
+	want := map[string]string{"changelog": "Fix login", "ctl_stop": "false"}
+	if len(fields) != len(want) {
+		t.Fatalf("ManifestFromLabels() = %v, want %v", fields, want)
+	}
+	for k, v := range want {
+		if fields[k] != v {
+			t.Errorf("fields[%s] = %q, want %q", k, fields[k], v)
+		}
+	}
+
+	if fields := ManifestFromLabels(map[string]string{"watchcow.enable": "true"}); fields != nil {
+		t.Errorf("ManifestFromLabels() without manifest labels = %v, want nil", fields)
+	}
+}
+
+func TestManifest_ExtraFields(t *testing.T) {
+	te, err := NewTemplateEngine()
+	if err != nil {
+		t.Fatalf("NewTemplateEngine() error = %v", err)
+	}
+
+	config := fingerprintTestConfig()
+	config.Maintainer = "Tester"
+	out, err := te.Render("manifest.tmpl", NewTemplateData(config))
+	if err != nil {
+		t.Fatalf("Render() error = %v", err)
+	}
+	for _, want := range []string{"distributor=Tester\n", "os_min_version=0.9.0\n"} {
+		if !strings.Contains(string(out), want) {
+			t.Errorf("default manifest missing %q:\n%s", want, out)
+		}
+	}
+
+	config.Manifest = map[string]string{
+		"distributor":    "Example Inc",
+		"os_min_version": "0.9.27",
+		"ctl_stop":       "false",
+		"maintainer_url": "https://example.com",
+	}
+	out, err = te.Render("manifest.tmpl", NewTemplateData(config))
+	if err != nil {
+		t.Fatalf("Render() error = %v", err)
+	}
+	manifest := string(out)
+	for _, want := range []string{
+		"distributor=Example Inc\n",
+		"os_min_version=0.9.27\n",
+		"maintainer_url=https://example.com\nctl_stop=false\n",
+	} {
+		if !strings.Contains(manifest, want) {
+			t.Errorf("manifest missing %q:\n%s", want, manifest)
+		}
+	}
+	if strings.Count(manifest, "os_min_version=") != 1 {
+		t.Errorf("os_min_version written more than once:\n%s", manifest)
+	}
+}
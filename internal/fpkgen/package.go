@@ -0,0 +1,318 @@
+package fpkgen
+
+import (
+	"archive/tar"
+	"bufio"
+	"bytes"
+	"compress/gzip"
+	"crypto/md5"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+)
+
+// PackageExt is the file extension of fnOS app packages.
+const PackageExt = ".fpk"
+
+// packageAppArchive is the name of the compressed app/ tree inside a package.
+const packageAppArchive = "app.tgz"
+
+// packageModTime is the modification time recorded for every archive entry,
+// so packing the same tree always produces identical bytes.
+var packageModTime = time.Unix(0, 0).UTC()
+
+// PackageFileName returns the .fpk file name for an app, e.g. "watchcow.nginx.fpk".
+func PackageFileName(appName string) string {
+	return appName + PackageExt
+}
+
+// Pack writes the generated app tree in appDir to w as an .fpk archive.
+//
+// The layout follows fnpack: the app/ directory is compressed into app.tgz,
+// its MD5 is written to the manifest "checksum" field, and everything else
+// (manifest, cmd/, config/, wizard/, LICENSE, ICON*.PNG) is stored alongside it.
+// The outer archive is a gzip-compressed tar.
+//
+// Packing is deterministic and offline: entries are sorted, timestamps and
+// ownership are fixed and file modes are normalized to 0644/0755.
+func Pack(appDir string, w io.Writer) error {
+	var appArchive bytes.Buffer
+	if err := writeTarGz(&appArchive, filepath.Join(appDir, "app")); err != nil {
+		return fmt.Errorf("failed to pack app directory: %w", err)
+	}
+	sum := md5.Sum(appArchive.Bytes())
+
+	manifest, err := os.ReadFile(filepath.Join(appDir, "manifest"))
+	if err != nil {
+		return fmt.Errorf("failed to read manifest: %w", err)
+	}
+	manifest = setManifestField(manifest, "checksum", hex.EncodeToString(sum[:]))
+
+	extra := map[string][]byte{
+		"manifest":        manifest,
+		packageAppArchive: appArchive.Bytes(),
+	}
+	skip := func(rel string) bool {
+		return rel == "app" || strings.HasPrefix(rel, "app/")
+	}
+
+	gz := newDeterministicGzip(w)
+	tw := tar.NewWriter(gz)
+	if err := addTree(tw, appDir, skip, extra); err != nil {
+		return err
+	}
+	if err := tw.Close(); err != nil {
+		return err
+	}
+	return gz.Close()
+}
+
+// WritePackage packs appDir into the .fpk file at path.
+// The file is written to a temp file first and renamed into place.
+func WritePackage(appDir, path string) error {
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return fmt.Errorf("failed to create package directory: %w", err)
+	}
+
+	tmp, err := os.CreateTemp(filepath.Dir(path), ".pack-*"+PackageExt)
+	if err != nil {
+		return fmt.Errorf("failed to create package file: %w", err)
+	}
+	defer os.Remove(tmp.Name())
+
+	bw := bufio.NewWriter(tmp)
+	if err := Pack(appDir, bw); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := bw.Flush(); err != nil {
+		tmp.Close()
+		return fmt.Errorf("failed to write package: %w", err)
+	}
+	if err := tmp.Chmod(0644); err != nil {
+		tmp.Close()
+		return fmt.Errorf("failed to write package: %w", err)
+	}
+	if err := tmp.Close(); err != nil {
+		return fmt.Errorf("failed to write package: %w", err)
+	}
+
+	return os.Rename(tmp.Name(), path)
+}
+
+// Unpack extracts an .fpk archive into destDir, expanding app.tgz back into app/.
+// The result can be passed to Installer.InstallLocal.
+func Unpack(fpkPath, destDir string) error {
+	f, err := os.Open(fpkPath)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	if err := extractTarGz(f, destDir); err != nil {
+		return fmt.Errorf("failed to unpack %s: %w", filepath.Base(fpkPath), err)
+	}
+
+	appArchive := filepath.Join(destDir, packageAppArchive)
+	af, err := os.Open(appArchive)
+	if err != nil {
+		return fmt.Errorf("failed to unpack %s: %w", filepath.Base(fpkPath), err)
+	}
+	defer af.Close()
+
+	if err := extractTarGz(af, filepath.Join(destDir, "app")); err != nil {
+		return fmt.Errorf("failed to unpack %s: %w", packageAppArchive, err)
+	}
+	af.Close()
+	return os.Remove(appArchive)
+}
+
+// writeTarGz writes the tree rooted at dir to w as a deterministic tar.gz.
+func writeTarGz(w io.Writer, dir string) error {
+	gz := newDeterministicGzip(w)
+	tw := tar.NewWriter(gz)
+	if err := addTree(tw, dir, nil, nil); err != nil {
+		return err
+	}
+	if err := tw.Close(); err != nil {
+		return err
+	}
+	return gz.Close()
+}
+
+// newDeterministicGzip returns a gzip writer with an empty header (no name or timestamp).
+func newDeterministicGzip(w io.Writer) *gzip.Writer {
+	gz := gzip.NewWriter(w)
+	gz.ModTime = time.Time{}
+	return gz
+}
+
+// addTree adds files under dir to tw in sorted order.
+// Paths for which skip returns true are left out; extra adds in-memory files,
+// replacing files of the same name in dir.
+func addTree(tw *tar.Writer, dir string, skip func(rel string) bool, extra map[string][]byte) error {
+	type entry struct {
+		name string
+		path string // source file; empty for extra or directories
+		dir  bool
+		mode fs.FileMode
+	}
+	var entries []entry
+
+	err := filepath.WalkDir(dir, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		rel, err := filepath.Rel(dir, path)
+		if err != nil {
+			return err
+		}
+		if rel == "." {
+			return nil
+		}
+		rel = filepath.ToSlash(rel)
+		if skip != nil && skip(rel) {
+			if d.IsDir() {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if _, ok := extra[rel]; ok {
+			return nil
+		}
+
+		info, err := d.Info()
+		if err != nil {
+			return err
+		}
+		switch {
+		case d.IsDir():
+			entries = append(entries, entry{name: rel, dir: true})
+		case info.Mode().IsRegular():
+			entries = append(entries, entry{name: rel, path: path, mode: info.Mode()})
+		default:
+			return fmt.Errorf("unsupported file type: %s", rel)
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	for name := range extra {
+		entries = append(entries, entry{name: name, mode: 0644})
+	}
+	sort.Slice(entries, func(i, j int) bool { return entries[i].name < entries[j].name })
+
+	for _, e := range entries {
+		hdr := &tar.Header{
+			Name:    e.name,
+			ModTime: packageModTime,
+		}
+		if e.dir {
+			hdr.Typeflag = tar.TypeDir
+			hdr.Name += "/"
+			hdr.Mode = 0755
+			if err := tw.WriteHeader(hdr); err != nil {
+				return err
+			}
+			continue
+		}
+
+		var data []byte
+		if e.path == "" {
+			data = extra[e.name]
+		} else {
+			var err error
+			if data, err = os.ReadFile(e.path); err != nil {
+				return err
+			}
+		}
+
+		hdr.Typeflag = tar.TypeReg
+		hdr.Size = int64(len(data))
+		hdr.Mode = 0644
+		if e.mode&0111 != 0 {
+			hdr.Mode = 0755
+		}
+		if err := tw.WriteHeader(hdr); err != nil {
+			return err
+		}
+		if _, err := tw.Write(data); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// extractTarGz extracts a tar.gz stream into destDir, rejecting entries that escape it.
+func extractTarGz(r io.Reader, destDir string) error {
+	gz, err := gzip.NewReader(r)
+	if err != nil {
+		return err
+	}
+	defer gz.Close()
+
+	tr := tar.NewReader(gz)
+	for {
+		hdr, err := tr.Next()
+		if errors.Is(err, io.EOF) {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+
+		target := filepath.Join(destDir, filepath.FromSlash(hdr.Name))
+		if !strings.HasPrefix(target, filepath.Clean(destDir)+string(os.PathSeparator)) {
+			return fmt.Errorf("invalid archive entry: %s", hdr.Name)
+		}
+
+		switch hdr.Typeflag {
+		case tar.TypeDir:
+			if err := os.MkdirAll(target, 0755); err != nil {
+				return err
+			}
+		case tar.TypeReg:
+			if err := os.MkdirAll(filepath.Dir(target), 0755); err != nil {
+				return err
+			}
+			f, err := os.OpenFile(target, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, fs.FileMode(hdr.Mode)&0755)
+			if err != nil {
+				return err
+			}
+			if _, err := io.Copy(f, tr); err != nil {
+				f.Close()
+				return err
+			}
+			if err := f.Close(); err != nil {
+				return err
+			}
+		default:
+			return fmt.Errorf("unsupported archive entry: %s", hdr.Name)
+		}
+	}
+}
+
+// setManifestField sets key=value in a manifest, replacing an existing line or appending one.
+func setManifestField(manifest []byte, key, value string) []byte {
+	lines := strings.Split(strings.TrimRight(string(manifest), "\n"), "\n")
+	line := key + "=" + value
+	replaced := false
+	for i, l := range lines {
+		if k, _, ok := strings.Cut(l, "="); ok && strings.TrimSpace(k) == key {
+			lines[i] = line
+			replaced = true
+		}
+	}
+	if !replaced {
+		lines = append(lines, line)
+	}
+	return []byte(strings.Join(lines, "\n") + "\n")
+}
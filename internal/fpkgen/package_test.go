@@ -0,0 +1,155 @@
+package fpkgen
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"crypto/md5"
+	"encoding/hex"
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+// writePackageTree writes a minimal generated app tree.
+func writePackageTree(t *testing.T, dir string) {
+	t.Helper()
+	files := map[string]struct {
+		content string
+		perm    os.FileMode
+	}{
+		"manifest":               {"appname=watchcow.nginx\nversion=1.0.0\n", 0644},
+		"cmd/main":               {"#!/bin/bash\n", 0755},
+		"config/privilege":       {"{}\n", 0644},
+		"app/ui/config":          {`{".url":{}}`, 0644},
+		"app/ui/images/icon.png": {"png", 0600},
+		"ICON.PNG":               {"icon", 0644},
+	}
+	for name, f := range files {
+		path := filepath.Join(dir, name)
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(f.content), f.perm); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+// readTarGz returns the entry names and file contents of a tar.gz stream.
+func readTarGz(t *testing.T, data []byte) ([]string, map[string]string) {
+	t.Helper()
+	gz, err := gzip.NewReader(bytes.NewReader(data))
+	if err != nil {
+		t.Fatalf("gzip.NewReader() error = %v", err)
+	}
+	tr := tar.NewReader(gz)
+
+	var names []string
+	contents := make(map[string]string)
+	for {
+		hdr, err := tr.Next()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			t.Fatalf("tar Next() error = %v", err)
+		}
+		names = append(names, hdr.Name)
+		if hdr.Typeflag == tar.TypeReg {
+			b, _ := io.ReadAll(tr)
+			contents[hdr.Name] = string(b)
+		}
+	}
+	return names, contents
+}
+
+func TestPack_Layout(t *testing.T) {
+	dir := t.TempDir()
+	writePackageTree(t, dir)
+
+	var buf bytes.Buffer
+	if err := Pack(dir, &buf); err != nil {
+		t.Fatalf("Pack() error = %v", err)
+	}
+
+	names, contents := readTarGz(t, buf.Bytes())
+	want := "ICON.PNG,app.tgz,cmd/,cmd/main,config/,config/privilege,manifest"
+	if got := strings.Join(names, ","); got != want {
+		t.Errorf("entries = %s, want %s", got, want)
+	}
+
+	appNames, _ := readTarGz(t, []byte(contents["app.tgz"]))
+	if got := strings.Join(appNames, ","); got != "ui/,ui/config,ui/images/,ui/images/icon.png" {
+		t.Errorf("app.tgz entries = %s", got)
+	}
+
+	sum := md5.Sum([]byte(contents["app.tgz"]))
+	if !strings.Contains(contents["manifest"], "checksum="+hex.EncodeToString(sum[:])+"\n") {
+		t.Errorf("manifest missing app.tgz checksum:\n%s", contents["manifest"])
+	}
+}
+
+func TestPack_Deterministic(t *testing.T) {
+	dirA := t.TempDir()
+	dirB := t.TempDir()
+	writePackageTree(t, dirA)
+	writePackageTree(t, dirB)
+
+	// Different timestamps must not change the archive
+	old := time.Now().Add(-24 * time.Hour)
+	os.Chtimes(filepath.Join(dirB, "manifest"), old, old)
+
+	var a, b bytes.Buffer
+	if err := Pack(dirA, &a); err != nil {
+		t.Fatalf("Pack() error = %v", err)
+	}
+	if err := Pack(dirB, &b); err != nil {
+		t.Fatalf("Pack() error = %v", err)
+	}
+	if !bytes.Equal(a.Bytes(), b.Bytes()) {
+		t.Error("Pack() output differs for identical trees")
+	}
+}
+
+func TestWritePackage_Unpack(t *testing.T) {
+	dir := t.TempDir()
+	writePackageTree(t, dir)
+	fpkPath := filepath.Join(t.TempDir(), "out", PackageFileName("watchcow.nginx"))
+
+	if err := WritePackage(dir, fpkPath); err != nil {
+		t.Fatalf("WritePackage() error = %v", err)
+	}
+
+	dest := t.TempDir()
+	if err := Unpack(fpkPath, dest); err != nil {
+		t.Fatalf("Unpack() error = %v", err)
+	}
+
+	if b, err := os.ReadFile(filepath.Join(dest, "app", "ui", "config")); err != nil || string(b) != `{".url":{}}` {
+		t.Errorf("app/ui/config = %q, %v", b, err)
+	}
+	if _, err := os.Stat(filepath.Join(dest, "app.tgz")); !os.IsNotExist(err) {
+		t.Error("app.tgz should be removed after unpack")
+	}
+	info, err := os.Stat(filepath.Join(dest, "cmd", "main"))
+	if err != nil || info.Mode().Perm() != 0755 {
+		t.Errorf("cmd/main mode = %v, %v; want 0755", info.Mode().Perm(), err)
+	}
+}
+
+func TestSetManifestField(t *testing.T) {
+	got := string(setManifestField([]byte("appname=a\nchecksum=old\n"), "checksum", "new"))
+	if got != "appname=a\nchecksum=new\n" {
+		t.Errorf("setManifestField() replace = %q", got)
+	}
+
+	got = string(setManifestField([]byte("appname=a"), "checksum", "new"))
+	if got != "appname=a\nchecksum=new\n" {
+		t.Errorf("setManifestField() append = %q", got)
+	}
+}
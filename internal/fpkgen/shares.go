@@ -0,0 +1,143 @@
+package fpkgen
+
+import (
+	"fmt"
+	"log/slog"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+
+	dockercontainer "github.com/docker/docker/api/types/container"
+)
+
+// shareLabelPrefix is the label namespace for data-shares: watchcow.share.<name>=<bind source>
+const shareLabelPrefix = "watchcow.share."
+
+var shareNamePattern = regexp.MustCompile(`^[a-zA-Z0-9][a-zA-Z0-9_-]*$`)
+
+// VolumesFromMounts converts container mounts to volume mappings.
+func VolumesFromMounts(mounts []dockercontainer.MountPoint) []VolumeMapping {
+	var volumes []VolumeMapping
+	for _, mount := range mounts {
+		volumes = append(volumes, VolumeMapping{
+			Source:      mount.Source,
+			Destination: mount.Destination,
+			ReadOnly:    !mount.RW,
+			Type:        string(mount.Type),
+		})
+	}
+	return volumes
+}
+
+// SharesFromLabels returns the fnOS data-shares of a container, sorted by name.
+//
+// watchcow.share.<name>=<bind source> declares a data-share for one bind mount;
+// watchcow.share_binds=true declares one for every bind-mounted directory, named
+// after the last element of its source. Only sources the container bind-mounts
+// can be shared, so labels cannot expose other host paths.
+func SharesFromLabels(labels map[string]string, volumes []VolumeMapping) []DataShare {
+	binds := make(map[string]bool)
+	for _, v := range volumes {
+		if v.Type == "bind" {
+			binds[v.Source] = true
+		}
+	}
+
+	shares := make(map[string]string) // name -> source
+	shared := make(map[string]bool)   // sources already shared
+	for label, source := range labels {
+		name, ok := strings.CutPrefix(label, shareLabelPrefix)
+		if !ok {
+			continue
+		}
+		source = filepath.Clean(source)
+		if err := validateShare(name, source, binds); err != nil {
+			slog.Warn("Ignoring invalid data-share label", "label", label, "error", err)
+			continue
+		}
+		shares[name] = source
+		shared[source] = true
+	}
+
+	if labels["watchcow.share_binds"] == "true" {
+		for _, v := range volumes {
+			if v.Type != "bind" || shared[v.Source] {
+				continue
+			}
+			name := uniqueShareName(shareNameFromPath(v.Source), shares)
+			if err := validateShare(name, v.Source, binds); err != nil {
+				slog.Debug("Not sharing bind mount", "source", v.Source, "error", err)
+				continue
+			}
+			shares[name] = v.Source
+			shared[v.Source] = true
+		}
+	}
+
+	if len(shares) == 0 {
+		return nil
+	}
+	result := make([]DataShare, 0, len(shares))
+	for name, source := range shares {
+		result = append(result, DataShare{Name: name, Source: source})
+	}
+	sort.Slice(result, func(i, j int) bool { return result[i].Name < result[j].Name })
+	return result
+}
+
+// validateShare checks that a data-share name is valid and its source is an absolute
+// bind source of the container outside the system directories (such as /var/run with
+// docker.sock). Only the labels and mounts are checked, not the host
+// filesystem, so the same container produces the same package wherever it is generated.
+func validateShare(name, source string, binds map[string]bool) error {
+	if !shareNamePattern.MatchString(name) {
+		return fmt.Errorf("invalid share name %q", name)
+	}
+	if !safeHostPath(source) {
+		return fmt.Errorf("invalid share source %q", source)
+	}
+	if !binds[source] {
+		return fmt.Errorf("%s is not a bind mount of the container", source)
+	}
+	if tree, ok := inProtectedTree(source); ok {
+		return fmt.Errorf("%s is inside system directory %s", source, tree)
+	}
+	return nil
+}
+
+// safeHostPath reports whether path is absolute and can be double-quoted
+// safely in the generated shell scripts.
+func safeHostPath(path string) bool {
+	return filepath.IsAbs(path) && !strings.ContainsAny(path, "\"$`\\\n")
+}
+
+// shareNameFromPath derives a data-share name from the last element of a path.
+func shareNameFromPath(path string) string {
+	var b strings.Builder
+	for _, c := range filepath.Base(path) {
+		if (c >= 'a' && c <= 'z') || (c >= 'A' && c <= 'Z') || (c >= '0' && c <= '9') || c == '-' || c == '_' {
+			b.WriteRune(c)
+		} else {
+			b.WriteRune('_')
+		}
+	}
+	name := strings.TrimLeft(b.String(), "_-")
+	if name == "" {
+		return "data"
+	}
+	return name
+}
+
+// uniqueShareName appends a counter to name until it is not taken.
+func uniqueShareName(name string, taken map[string]string) string {
+	if _, ok := taken[name]; !ok {
+		return name
+	}
+	for i := 2; ; i++ {
+		candidate := fmt.Sprintf("%s-%d", name, i)
+		if _, ok := taken[candidate]; !ok {
+			return candidate
+		}
+	}
+}
@@ -0,0 +1,152 @@
+package fpkgen
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestSharesFromLabels(t *testing.T) {
+	root := t.TempDir()
+	media := filepath.Join(root, "media")
+	config := filepath.Join(root, "config")
+	otherConfig := filepath.Join(root, "other", "config")
+	for _, dir := range []string{media, config, otherConfig} {
+		if err := os.MkdirAll(dir, 0755); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	volumes := []VolumeMapping{
+		{Source: media, Destination: "/media", Type: "bind"},
+		{Source: config, Destination: "/config", Type: "bind"},
+		{Source: otherConfig, Destination: "/config2", Type: "bind"},
+		{Source: "/var/run/docker.sock", Destination: "/var/run/docker.sock", Type: "bind"},
+		{Source: "/var/lib/docker/volumes/cache/_data", Destination: "/cache", Type: "volume"},
+	}
+
+	t.Run("labels", func(t *testing.T) {
+		shares := SharesFromLabels(map[string]string{
+			"watchcow.share.movies": media + "/",
+			"watchcow.share.etc":    "/etc",
+			"watchcow.share.rel":    "media",
+			"watchcow.share.sock":   "/var/run/docker.sock",
+			"watchcow.share.bad/x":  config,
+		}, volumes)
+		if len(shares) != 1 || shares[0] != (DataShare{Name: "movies", Source: media}) {
+			t.Errorf("SharesFromLabels() = %v, want only movies", shares)
+		}
+	})
+
+	t.Run("all binds", func(t *testing.T) {
+		shares := SharesFromLabels(map[string]string{
+			"watchcow.share_binds":  "true",
+			"watchcow.share.movies": media,
+		}, volumes)
+		want := []DataShare{
+			{Name: "config", Source: config},
+			{Name: "config-2", Source: otherConfig},
+			{Name: "movies", Source: media},
+		}
+		if len(shares) != len(want) {
+			t.Fatalf("SharesFromLabels() = %v, want %v", shares, want)
+		}
+		for i := range want {
+			if shares[i] != want[i] {
+				t.Errorf("shares[%d] = %v, want %v", i, shares[i], want[i])
+			}
+		}
+	})
+
+	t.Run("none", func(t *testing.T) {
+		if shares := SharesFromLabels(map[string]string{"watchcow.enable": "true"}, volumes); shares != nil {
+			t.Errorf("SharesFromLabels() = %v, want nil", shares)
+		}
+	})
+}
+
+func TestSharesFromLabels_SourceNotOnHost(t *testing.T) {
+	// Generating off-host (watchcow generate, lint) must give the same shares as on the NAS
+	source := filepath.Join(t.TempDir(), "not", "created", "yet")
+	volumes := []VolumeMapping{{Source: source, Destination: "/data", Type: "bind"}}
+
+	for _, labels := range []map[string]string{
+		{"watchcow.share.data": source},
+		{"watchcow.share_binds": "true"},
+	} {
+		shares := SharesFromLabels(labels, volumes)
+		if len(shares) != 1 || shares[0].Source != source {
+			t.Errorf("SharesFromLabels(%v) = %v, want %s shared", labels, shares, source)
+		}
+	}
+}
+
+func TestSharesFromLabels_UnsafeSource(t *testing.T) {
+	dir := filepath.Join(t.TempDir(), "a$b")
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		t.Fatal(err)
+	}
+	volumes := []VolumeMapping{{Source: dir, Destination: "/data", Type: "bind"}}
+
+	if shares := SharesFromLabels(map[string]string{"watchcow.share.data": dir}, volumes); shares != nil {
+		t.Errorf("SharesFromLabels() = %v, want nil for source with shell characters", shares)
+	}
+}
+
+func TestShares_Templates(t *testing.T) {
+	te, err := NewTemplateEngine()
+	if err != nil {
+		t.Fatalf("NewTemplateEngine() error = %v", err)
+	}
+
+	config := fingerprintTestConfig()
+	out, err := te.Render("config_resource.json.tmpl", NewTemplateData(config))
+	if err != nil {
+		t.Fatalf("Render() error = %v", err)
+	}
+	if string(out) != "{}\n" {
+		t.Errorf("config/resource without shares = %q, want {}", out)
+	}
+
+	config.Shares = []DataShare{
+		{Name: "config", Source: "/vol1/docker/nginx/config"},
+		{Name: "html", Source: "/vol1/docker/nginx/html"},
+	}
+	data := NewTemplateData(config)
+
+	out, err = te.Render("config_resource.json.tmpl", data)
+	if err != nil {
+		t.Fatalf("Render() error = %v", err)
+	}
+	var resource struct {
+		DataShare struct {
+			Shares []struct {
+				Name       string `json:"name"`
+				Permission struct {
+					RW []string `json:"rw"`
+				} `json:"permission"`
+			} `json:"shares"`
+		} `json:"data-share"`
+	}
+	if err := json.Unmarshal(out, &resource); err != nil {
+		t.Fatalf("config/resource is not valid JSON: %v\n%s", err, out)
+	}
+	shares := resource.DataShare.Shares
+	if len(shares) != 2 || shares[0].Name != "config" || shares[1].Name != "html" {
+		t.Fatalf("data-share shares = %+v", shares)
+	}
+	if len(shares[0].Permission.RW) != 1 || shares[0].Permission.RW[0] != "watchcow.nginx" {
+		t.Errorf("share permission = %+v, want rw for the app", shares[0].Permission)
+	}
+
+	// Shares are only declared; cmd/main and uninstall never touch the container folders
+	out, err = te.Render("cmd_main.tmpl", data)
+	if err != nil {
+		t.Fatalf("Render() error = %v", err)
+	}
+	if strings.Contains(string(out), "mount") {
+		t.Errorf("cmd/main should not mount shares:\n%s", out)
+	}
+}
@@ -5,9 +5,15 @@ import (
 	"embed"
 	"encoding/json"
 	"fmt"
+	"io"
+	"log/slog"
+	"maps"
 	"os"
 	"path/filepath"
+	"sort"
 	"text/template"
+
+	"watchcow/internal/app"
 )
 
 //go:embed templates/*.tmpl
@@ -16,12 +22,36 @@ var templateFS embed.FS
 // TemplateEngine handles template loading and rendering
 type TemplateEngine struct {
 	templates map[string]*template.Template
+	sets      map[string]*TemplateEngine // named template sets (watchcow.template_set)
+}
+
+// getTemplateOverrideDir returns the directory of user template overrides.
+// If TRIM_PKGETC is set, uses ${TRIM_PKGETC}/templates; otherwise overrides are disabled.
+func getTemplateOverrideDir() string {
+	if pkgEtc := os.Getenv("TRIM_PKGETC"); pkgEtc != "" {
+		return filepath.Join(pkgEtc, "templates")
+	}
+	return ""
 }
 
 // NewTemplateEngine creates a new template engine with embedded templates
+// and the user overrides from the template override directory.
 func NewTemplateEngine() (*TemplateEngine, error) {
+	return NewTemplateEngineWithOverrides(getTemplateOverrideDir())
+}
+
+// NewTemplateEngineWithOverrides creates a template engine whose embedded templates
+// are replaced by same-named files in dir:
+//
+//	<dir>/<name>.tmpl        overrides the default template set
+//	<dir>/<set>/<name>.tmpl  overrides the template set selected by watchcow.template_set=<set>
+//
+// Every override must parse and render sample app data; an invalid override is an error.
+// A missing dir means no overrides.
+func NewTemplateEngineWithOverrides(dir string) (*TemplateEngine, error) {
 	engine := &TemplateEngine{
 		templates: make(map[string]*template.Template),
+		sets:      make(map[string]*TemplateEngine),
 	}
 
 	// Load all embedded templates
@@ -49,9 +79,112 @@ func NewTemplateEngine() (*TemplateEngine, error) {
 		engine.templates[name] = tmpl
 	}
 
+	if dir == "" {
+		return engine, nil
+	}
+	entries, err = os.ReadDir(dir)
+	if os.IsNotExist(err) {
+		return engine, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read template override directory: %w", err)
+	}
+
+	// Top-level files override the default set; named sets build on top of it
+	if err := engine.applyOverrides(dir); err != nil {
+		return nil, err
+	}
+	for _, entry := range entries {
+		if !entry.IsDir() {
+			continue
+		}
+		set := &TemplateEngine{
+			templates: maps.Clone(engine.templates),
+		}
+		if err := set.applyOverrides(filepath.Join(dir, entry.Name())); err != nil {
+			return nil, err
+		}
+		engine.sets[entry.Name()] = set
+		slog.Info("Loaded template set", "set", entry.Name())
+	}
+
 	return engine, nil
 }
 
+// applyOverrides replaces templates with the .tmpl files in dir.
+// Files that do not match an embedded template are ignored with a warning.
+func (e *TemplateEngine) applyOverrides(dir string) error {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return fmt.Errorf("failed to read template override directory: %w", err)
+	}
+
+	sample := sampleTemplateData()
+	for _, entry := range entries {
+		name := entry.Name()
+		if entry.IsDir() || filepath.Ext(name) != ".tmpl" {
+			continue
+		}
+		path := filepath.Join(dir, name)
+		if _, ok := e.templates[name]; !ok {
+			slog.Warn("Ignoring template override without embedded template", "path", path)
+			continue
+		}
+
+		content, err := os.ReadFile(path)
+		if err != nil {
+			return fmt.Errorf("failed to read template override %s: %w", path, err)
+		}
+		tmpl, err := template.New(name).Parse(string(content))
+		if err != nil {
+			return fmt.Errorf("failed to parse template override %s: %w", path, err)
+		}
+		if err := tmpl.Execute(io.Discard, sample); err != nil {
+			return fmt.Errorf("failed to render template override %s: %w", path, err)
+		}
+
+		e.templates[name] = tmpl
+		slog.Info("Loaded template override", "path", path)
+	}
+	return nil
+}
+
+// sampleTemplateData returns app data used to check that template overrides render.
+func sampleTemplateData() *TemplateData {
+	return NewTemplateData(&AppConfig{
+		AppName:       "watchcow.example",
+		Version:       "1.0.0",
+		DisplayName:   "Example",
+		Maintainer:    "WatchCow",
+		ContainerName: "example",
+		Image:         "example:latest",
+		Port:          "8080",
+		Entries: []Entry{
+			{Title: "Example", Protocol: "http", Port: "8080", Path: "/", UIType: "url", AllUsers: true},
+		},
+	})
+}
+
+// Set returns the engine of a named template set.
+// The empty name is the default set. Returns false if the set does not exist.
+func (e *TemplateEngine) Set(name string) (*TemplateEngine, bool) {
+	if name == "" {
+		return e, true
+	}
+	set, ok := e.sets[name]
+	return set, ok
+}
+
+// Sets returns the names of the named template sets, sorted.
+func (e *TemplateEngine) Sets() []string {
+	names := make([]string, 0, len(e.sets))
+	for name := range e.sets {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
 // Render renders a template with the given data
 func (e *TemplateEngine) Render(templateName string, data interface{}) ([]byte, error) {
 	tmpl, ok := e.templates[templateName]
@@ -223,15 +356,31 @@ type TemplateData struct {
 	// Collections
 	Ports       []string
 	Volumes     []VolumeMapping
+	Shares      []DataShare
 	Environment []string
 
+	// Install and uninstall wizards
+	Wizard WizardConfig
+
 	// Other
 	RestartPolicy string
 	Icon          string
 
+	// Lifecycle: cmd/main starts/stops the container on fnOS start/stop
+	Bidirectional bool
+
+	// Architecture (manifest arch and platform)
+	Arch     string
+	Platform string
+
+	// Manifest fields with defaults, and extra fields set by labels or the dashboard
+	Distributor   string
+	OSMinVersion  string
+	ManifestExtra []ManifestField
+
 	// CGI redirect mode
 	HasRedirect     bool   // True if any entry uses redirect mode
-	WatchCowAppDest string // TRIM_APPDEST of watchcow package (for CGI binary path)
+	WatchCowAppDest string // TRIM_APPDEST of watchcow package (for CGI and status binary path)
 	WatchCowPkgVar  string // TRIM_PKGVAR of watchcow package (for socket path)
 }
 
@@ -252,9 +401,12 @@ func NewTemplateData(config *AppConfig) *TemplateData {
 		UIType:        config.UIType,
 		AllUsers:      config.AllUsers,
 		Volumes:       config.Volumes,
+		Shares:        config.Shares,
+		Wizard:        config.Wizard,
 		Environment:   config.Environment,
 		RestartPolicy: config.RestartPolicy,
 		Icon:          config.Icon,
+		Bidirectional: config.Lifecycle == app.LifecycleBidirectional,
 	}
 
 	// Set defaults
@@ -270,6 +422,28 @@ func NewTemplateData(config *AppConfig) *TemplateData {
 	if data.RestartPolicy == "" {
 		data.RestartPolicy = "unless-stopped"
 	}
+	data.Arch = config.Arch
+	if data.Arch == "" {
+		data.Arch = HostArch()
+	}
+	data.Platform = platformForArch(data.Arch)
+
+	data.Distributor = config.Maintainer
+	data.OSMinVersion = defaultOSMinVersion
+	for _, key := range ManifestKeys {
+		value, ok := config.Manifest[key]
+		if !ok || value == "" {
+			continue
+		}
+		switch key {
+		case "distributor":
+			data.Distributor = escapeForTemplate(value)
+		case "os_min_version":
+			data.OSMinVersion = escapeForTemplate(value)
+		default:
+			data.ManifestExtra = append(data.ManifestExtra, ManifestField{Key: key, Value: escapeForTemplate(value)})
+		}
+	}
 
 	// Build ports list
 	if config.Port != "" {
@@ -345,11 +519,9 @@ func NewTemplateData(config *AppConfig) *TemplateData {
 	// Set default launch entry to first displayable entry's full name
 	data.DefaultLaunchEntry = defaultLaunchEntry
 
-	// Set WatchCow's paths for CGI script
-	if data.HasRedirect {
-		data.WatchCowAppDest = os.Getenv("TRIM_APPDEST")
-		data.WatchCowPkgVar = os.Getenv("TRIM_PKGVAR")
-	}
+	// Set WatchCow's paths for CGI script, status queries and lifecycle markers
+	data.WatchCowAppDest = os.Getenv("TRIM_APPDEST")
+	data.WatchCowPkgVar = os.Getenv("TRIM_PKGVAR")
 
 	return data
 }
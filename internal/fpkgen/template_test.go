@@ -0,0 +1,184 @@
+package fpkgen
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"watchcow/internal/app"
+)
+
+func TestLifecycleFromLabels(t *testing.T) {
+	tests := []struct {
+		value string
+		want  string
+	}{
+		{"", app.LifecycleDocker},
+		{"docker", app.LifecycleDocker},
+		{"bidirectional", app.LifecycleBidirectional},
+		{"both", app.LifecycleDocker},
+	}
+
+	for _, tt := range tests {
+		labels := map[string]string{}
+		if tt.value != "" {
+			labels["watchcow.lifecycle"] = tt.value
+		}
+		if got := LifecycleFromLabels(labels); got != tt.want {
+			t.Errorf("LifecycleFromLabels(%q) = %q, want %q", tt.value, got, tt.want)
+		}
+	}
+}
+
+func TestCmdMain_Lifecycle(t *testing.T) {
+	te, err := NewTemplateEngine()
+	if err != nil {
+		t.Fatalf("NewTemplateEngine() error = %v", err)
+	}
+	t.Setenv("TRIM_APPDEST", "/var/apps/watchcow/target")
+	t.Setenv("TRIM_PKGVAR", "/var/apps/watchcow/var")
+
+	config := fingerprintTestConfig()
+	out, err := te.Render("cmd_main.tmpl", NewTemplateData(config))
+	if err != nil {
+		t.Fatalf("Render() error = %v", err)
+	}
+	if !strings.Contains(string(out), "No-op: container lifecycle managed by Docker") {
+		t.Errorf("default cmd/main should not control the container:\n%s", out)
+	}
+	if !strings.Contains(string(out), `"/var/apps/watchcow/target/watchcow" --mode status --socket "/var/apps/watchcow/var/watchcow.sock"`) {
+		t.Errorf("cmd/main status should query the WatchCow daemon:\n%s", out)
+	}
+
+	config.Lifecycle = app.LifecycleBidirectional
+	out, err = te.Render("cmd_main.tmpl", NewTemplateData(config))
+	if err != nil {
+		t.Fatalf("Render() error = %v", err)
+	}
+	script := string(out)
+	for _, want := range []string{
+		`MARKER_DIR="/var/apps/watchcow/var/lifecycle"`,
+		"run_marked start",
+		"run_marked stop",
+		`docker "$1" "$CONTAINER_NAME"`,
+		"{{.State.Running}}",
+	} {
+		if !strings.Contains(script, want) {
+			t.Errorf("bidirectional cmd/main missing %q:\n%s", want, script)
+		}
+	}
+}
+
+// writeTemplateOverride writes an override template file below dir.
+func writeTemplateOverride(t *testing.T, dir, name, content string) {
+	t.Helper()
+	path := filepath.Join(dir, name)
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestTemplateEngine_Overrides(t *testing.T) {
+	dir := t.TempDir()
+	writeTemplateOverride(t, dir, "LICENSE.tmpl", "Custom license for {{.AppName}}\n")
+	writeTemplateOverride(t, dir, "unknown.tmpl", "ignored")
+	writeTemplateOverride(t, dir, "strict/LICENSE.tmpl", "Strict license for {{.AppName}}\n")
+
+	te, err := NewTemplateEngineWithOverrides(dir)
+	if err != nil {
+		t.Fatalf("NewTemplateEngineWithOverrides() error = %v", err)
+	}
+	embedded, err := NewTemplateEngineWithOverrides("")
+	if err != nil {
+		t.Fatalf("NewTemplateEngineWithOverrides() error = %v", err)
+	}
+	data := NewTemplateData(fingerprintTestConfig())
+
+	out, err := te.Render("LICENSE.tmpl", data)
+	if err != nil {
+		t.Fatalf("Render() error = %v", err)
+	}
+	if string(out) != "Custom license for watchcow.nginx\n" {
+		t.Errorf("default set LICENSE = %q", out)
+	}
+	if _, err := te.Render("unknown.tmpl", data); err == nil {
+		t.Error("unknown override should not be loaded")
+	}
+
+	strict, ok := te.Set("strict")
+	if !ok {
+		t.Fatalf("Set(strict) not found, sets = %v", te.Sets())
+	}
+	out, err = strict.Render("LICENSE.tmpl", data)
+	if err != nil {
+		t.Fatalf("Render() error = %v", err)
+	}
+	if string(out) != "Strict license for watchcow.nginx\n" {
+		t.Errorf("strict set LICENSE = %q", out)
+	}
+
+	// Templates not overridden by the set come from the default set
+	want, _ := embedded.Render("manifest.tmpl", data)
+	if got, _ := strict.Render("manifest.tmpl", data); string(got) != string(want) {
+		t.Errorf("strict set manifest differs from embedded:\n%s", got)
+	}
+
+	if _, ok := te.Set("missing"); ok {
+		t.Error("Set(missing) should not exist")
+	}
+}
+
+func TestTemplateEngine_InvalidOverride(t *testing.T) {
+	tests := []struct {
+		name    string
+		file    string
+		content string
+	}{
+		{"parse error", "cmd_main.tmpl", "{{if .Bidirectional}}unterminated"},
+		{"unknown field", "manifest.tmpl", "appname={{.NoSuchField}}\n"},
+		{"invalid set", "custom/LICENSE.tmpl", "{{end}}"},
+	}
+	for _, tt := range tests {
+		dir := t.TempDir()
+		writeTemplateOverride(t, dir, tt.file, tt.content)
+		if _, err := NewTemplateEngineWithOverrides(dir); err == nil {
+			t.Errorf("%s: NewTemplateEngineWithOverrides() error = nil", tt.name)
+		}
+	}
+}
+
+func TestTemplateEngine_MissingOverrideDir(t *testing.T) {
+	if _, err := NewTemplateEngineWithOverrides(filepath.Join(t.TempDir(), "missing")); err != nil {
+		t.Errorf("NewTemplateEngineWithOverrides() error = %v for missing dir", err)
+	}
+}
+
+func TestGenerator_TemplateSet(t *testing.T) {
+	dir := t.TempDir()
+	writeTemplateOverride(t, dir, "custom/LICENSE.tmpl", "Custom\n")
+	te, err := NewTemplateEngineWithOverrides(dir)
+	if err != nil {
+		t.Fatalf("NewTemplateEngineWithOverrides() error = %v", err)
+	}
+	g := &Generator{templateEngine: te}
+
+	config := fingerprintTestConfig()
+	base := Fingerprint(config)
+
+	config.TemplateSet = "custom"
+	if g.templatesFor(config) == te {
+		t.Error("templatesFor() should select the custom set")
+	}
+	if Fingerprint(config) == base {
+		t.Error("Fingerprint() should change with the template set")
+	}
+
+	config.TemplateSet = "missing"
+	if g.templatesFor(config) != te {
+		t.Error("templatesFor() should fall back to the default set")
+	}
+}
@@ -12,4 +12,6 @@ type (
 	Entry         = app.Entry
 	EntryControl  = app.EntryControl
 	VolumeMapping = app.VolumeMapping
+	DataShare     = app.DataShare
+	WizardConfig  = app.WizardConfig
 )
@@ -0,0 +1,348 @@
+package fpkgen
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Severity tells whether a label problem blocks installation.
+type Severity string
+
+const (
+	SeverityError   Severity = "error"   // Blocks installation
+	SeverityWarning Severity = "warning" // Reported; the label is ignored or a default is used
+)
+
+// ProblemKind classifies a label problem.
+type ProblemKind string
+
+const (
+	ProblemUnknownKey     ProblemKind = "unknown_key"     // Not a watchcow label (typo?)
+	ProblemBadEnum        ProblemKind = "bad_enum"        // Value is not one of the allowed values
+	ProblemBadBool        ProblemKind = "bad_bool"        // Value is not "true" or "false"
+	ProblemInvalidPort    ProblemKind = "invalid_port"    // Port is not a number in 1-65535
+	ProblemInvalidAppName ProblemKind = "invalid_appname" // App name has characters fnOS does not accept
+	ProblemInvalidValue   ProblemKind = "invalid_value"   // Value has the wrong format
+	ProblemEntryCollision ProblemKind = "entry_collision" // Entry name clashes with another entry or label namespace
+)
+
+// ErrInvalidLabels is returned when labels have problems of SeverityError.
+var ErrInvalidLabels = errors.New("invalid watchcow labels")
+
+// Problem is a single issue found in the watchcow labels of a container.
+type Problem struct {
+	Label    string      `json:"label"`
+	Value    string      `json:"value"`
+	Kind     ProblemKind `json:"kind"`
+	Severity Severity    `json:"severity"`
+	Message  string      `json:"message"`
+}
+
+// String formats the problem as "<label>: <message>".
+func (p Problem) String() string {
+	return p.Label + ": " + p.Message
+}
+
+// Problems is the result of ValidateLabels, sorted by label.
+type Problems []Problem
+
+// HasErrors reports whether any problem blocks installation.
+func (ps Problems) HasErrors() bool {
+	for _, p := range ps {
+		if p.Severity == SeverityError {
+			return true
+		}
+	}
+	return false
+}
+
+// Err returns an error wrapping ErrInvalidLabels that lists all errors, or nil if there are none.
+func (ps Problems) Err() error {
+	var msgs []string
+	for _, p := range ps {
+		if p.Severity == SeverityError {
+			msgs = append(msgs, p.String())
+		}
+	}
+	if len(msgs) == 0 {
+		return nil
+	}
+	return fmt.Errorf("%w: %s", ErrInvalidLabels, strings.Join(msgs, "; "))
+}
+
+var (
+	appNamePattern   = regexp.MustCompile(`^[a-zA-Z0-9][a-zA-Z0-9._-]*$`)
+	entryNamePattern = regexp.MustCompile(`^[a-zA-Z0-9][a-zA-Z0-9_-]*$`)
+)
+
+// labelCheck validates the value of a label; it returns the problem kind and message,
+// or an empty kind if the value is valid.
+type labelCheck func(value string) (ProblemKind, string)
+
+// appLabels are the app-level labels (without "watchcow.") and their checks.
+var appLabels = map[string]labelCheck{
+	"enable":                                checkBool,
+	"install":                               nil,
+	"appname":                               checkAppName,
+	"display_name":                          nil,
+	"desc":                                  nil,
+	"version":                               nil,
+	"maintainer":                            nil,
+	"wait_for":                              checkEnum("healthy", "port", "none"),
+	"wait_timeout":                          checkDuration,
+	"lifecycle":                             checkEnum("docker", "bidirectional"),
+	"arch":                                  checkArch,
+	"template_set":                          nil,
+	"share_binds":                           checkBool,
+	"icon":                                  nil,
+	"title":                                 nil,
+	"file_types":                            nil,
+	"redirect":                              nil,
+	"all_users":                             checkBool,
+	"no_display":                            checkBool,
+	"service_port":                          checkPort,
+	"protocol":                              checkEnum("http", "https"),
+	"path":                                  checkPath,
+	"ui_type":                               checkEnum("url", "iframe"),
+	"wizard.install.tips":                   nil,
+	"wizard.uninstall.remove_container":     checkBool,
+	"wizard.uninstall.confirm_data_removal": checkBool,
+	"wizard.uninstall.data_paths":           nil,
+	"control.access_perm":                   checkPerm,
+	"control.port_perm":                     checkPerm,
+	"control.path_perm":                     checkPerm,
+}
+
+// reservedNamespaces are label namespaces that cannot be used as entry names.
+var reservedNamespaces = []string{"manifest", "share", "wizard", "control"}
+
+// ValidateLabels checks the watchcow labels of a container and returns all problems found.
+// Labels outside the "watchcow." namespace are ignored.
+func ValidateLabels(labels map[string]string) Problems {
+	var problems Problems
+	add := func(label, value string, kind ProblemKind, severity Severity, msg string) {
+		problems = append(problems, Problem{Label: label, Value: value, Kind: kind, Severity: severity, Message: msg})
+	}
+
+	entryNames := make(map[string]bool)
+	for label, value := range labels {
+		key, ok := strings.CutPrefix(label, "watchcow.")
+		if !ok {
+			continue
+		}
+
+		if check, known := appLabels[key]; known {
+			if check != nil {
+				if kind, msg := check(value); kind != "" {
+					add(label, value, kind, SeverityError, msg)
+				}
+			}
+			continue
+		}
+
+		if field, ok := strings.CutPrefix(label, manifestLabelPrefix); ok {
+			if err := ValidateManifestField(field, strings.TrimSpace(value)); err != nil {
+				add(label, value, ProblemInvalidValue, SeverityError, err.Error())
+			}
+			continue
+		}
+		if name, ok := strings.CutPrefix(label, shareLabelPrefix); ok && !isEntryField(name) {
+			if !shareNamePattern.MatchString(name) {
+				add(label, value, ProblemInvalidValue, SeverityError, "share name may only contain letters, digits, '-' and '_'")
+			} else if !safeHostPath(value) {
+				add(label, value, ProblemInvalidValue, SeverityError, "share source must be an absolute path without quotes, '$', '`' or '\\'")
+			}
+			continue
+		}
+
+		// Named entry field: watchcow.<entry>.<field>
+		name, field, isEntry := strings.Cut(key, ".")
+		if isEntry && isEntryField(field) {
+			check, known := appLabels[field]
+			if !known || !entryFields[field] {
+				add(label, value, ProblemUnknownKey, SeverityWarning, unknownLabelMessage(entrySuggestion(name, field)))
+				continue
+			}
+			if check != nil {
+				if kind, msg := check(value); kind != "" {
+					add(label, value, kind, SeverityError, msg)
+				}
+			}
+			entryNames[name] = true
+			continue
+		}
+
+		suggestion := ""
+		if k := closestKey(key, knownLabelKeys()); k != "" {
+			suggestion = "watchcow." + k
+		} else if isEntry {
+			suggestion = entrySuggestion(name, field)
+		}
+		add(label, value, ProblemUnknownKey, SeverityWarning, unknownLabelMessage(suggestion))
+	}
+
+	byLower := make(map[string][]string) // entry names that differ only in case
+	for name := range entryNames {
+		lower := strings.ToLower(name)
+		byLower[lower] = append(byLower[lower], name)
+		switch {
+		case slices.Contains(reservedNamespaces, lower):
+			add("watchcow."+name, "", ProblemEntryCollision, SeverityError,
+				fmt.Sprintf("entry name %q is reserved for watchcow.%s.* labels", name, lower))
+		case !entryNamePattern.MatchString(name):
+			add("watchcow."+name, "", ProblemInvalidValue, SeverityError,
+				fmt.Sprintf("entry name %q may only contain letters, digits, '-' and '_'", name))
+		}
+	}
+	for _, names := range byLower {
+		if len(names) < 2 {
+			continue
+		}
+		sort.Strings(names)
+		for _, name := range names[1:] {
+			add("watchcow."+name, "", ProblemEntryCollision, SeverityError,
+				fmt.Sprintf("entry %q differs from entry %q only in case", name, names[0]))
+		}
+	}
+
+	sort.SliceStable(problems, func(i, j int) bool {
+		if problems[i].Label != problems[j].Label {
+			return problems[i].Label < problems[j].Label
+		}
+		return problems[i].Message < problems[j].Message
+	})
+	return problems
+}
+
+func checkBool(v string) (ProblemKind, string) {
+	if v != "true" && v != "false" {
+		return ProblemBadBool, fmt.Sprintf("must be \"true\" or \"false\", got %q", v)
+	}
+	return "", ""
+}
+
+func checkEnum(values ...string) labelCheck {
+	return func(v string) (ProblemKind, string) {
+		if !slices.Contains(values, v) {
+			return ProblemBadEnum, fmt.Sprintf("must be one of %s, got %q", strings.Join(values, ", "), v)
+		}
+		return "", ""
+	}
+}
+
+func checkPerm(v string) (ProblemKind, string) {
+	return checkEnum("editable", "readonly", "hidden")(v)
+}
+
+func checkPort(v string) (ProblemKind, string) {
+	port, err := strconv.Atoi(v)
+	if err != nil || port < 1 || port > 65535 {
+		return ProblemInvalidPort, fmt.Sprintf("must be a port number between 1 and 65535, got %q", v)
+	}
+	return "", ""
+}
+
+func checkAppName(v string) (ProblemKind, string) {
+	if !appNamePattern.MatchString(v) {
+		return ProblemInvalidAppName, "may only contain letters, digits, '.', '-' and '_', and must not start with a symbol"
+	}
+	return "", ""
+}
+
+func checkPath(v string) (ProblemKind, string) {
+	if !strings.HasPrefix(v, "/") {
+		return ProblemInvalidValue, fmt.Sprintf("must start with \"/\", got %q", v)
+	}
+	return "", ""
+}
+
+func checkArch(v string) (ProblemKind, string) {
+	if _, ok := NormalizeArch(v); !ok {
+		return ProblemBadEnum, fmt.Sprintf("must be x86_64 (amd64) or arm64 (aarch64), got %q", v)
+	}
+	return "", ""
+}
+
+func checkDuration(v string) (ProblemKind, string) {
+	if secs, err := strconv.Atoi(v); err == nil && secs >= 0 {
+		return "", ""
+	}
+	if d, err := time.ParseDuration(v); err == nil && d >= 0 {
+		return "", ""
+	}
+	return ProblemInvalidValue, fmt.Sprintf("must be a duration like 90s or 5m, or a number of seconds, got %q", v)
+}
+
+// unknownLabelMessage describes an unknown label, with the suggested label if any.
+func unknownLabelMessage(suggestion string) string {
+	if suggestion == "" {
+		return "unknown label"
+	}
+	return fmt.Sprintf("unknown label, did you mean %q?", suggestion)
+}
+
+// entrySuggestion returns the entry label closest to a misspelled entry field, or "".
+func entrySuggestion(entry, field string) string {
+	if f := closestKey(field, entryFieldNames()); f != "" {
+		return "watchcow." + entry + "." + f
+	}
+	return ""
+}
+
+// closestKey returns the known key within two edits of key, or "" if there is none.
+func closestKey(key string, known []string) string {
+	best, bestDist := "", 3
+	for _, k := range known {
+		if d := editDistance(key, k); d < bestDist {
+			best, bestDist = k, d
+		}
+	}
+	return best
+}
+
+// knownLabelKeys returns the app-level label keys, sorted.
+func knownLabelKeys() []string {
+	keys := make([]string, 0, len(appLabels))
+	for k := range appLabels {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// entryFieldNames returns the entry field names, sorted.
+func entryFieldNames() []string {
+	keys := make([]string, 0, len(entryFields))
+	for k := range entryFields {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// editDistance returns the Levenshtein distance between a and b.
+func editDistance(a, b string) int {
+	prev := make([]int, len(b)+1)
+	for j := range prev {
+		prev[j] = j
+	}
+	for i := 1; i <= len(a); i++ {
+		cur := make([]int, len(b)+1)
+		cur[0] = i
+		for j := 1; j <= len(b); j++ {
+			cost := 1
+			if a[i-1] == b[j-1] {
+				cost = 0
+			}
+			cur[j] = min(prev[j]+1, cur[j-1]+1, prev[j-1]+cost)
+		}
+		prev = cur
+	}
+	return prev[len(b)]
+}
@@ -0,0 +1,116 @@
+package fpkgen
+
+import (
+	"errors"
+	"testing"
+)
+
+// findProblem returns the problem reported for label, or nil.
+func findProblem(problems Problems, label string) *Problem {
+	for i := range problems {
+		if problems[i].Label == label {
+			return &problems[i]
+		}
+	}
+	return nil
+}
+
+func TestValidateLabels_Valid(t *testing.T) {
+	problems := ValidateLabels(map[string]string{
+		"watchcow.enable":                   "true",
+		"watchcow.appname":                  "watchcow.nginx",
+		"watchcow.service_port":             "8080",
+		"watchcow.ui_type":                  "iframe",
+		"watchcow.all_users":                "false",
+		"watchcow.wait_timeout":             "90s",
+		"watchcow.admin.service_port":       "8081",
+		"watchcow.admin.control.port_perm":  "editable",
+		"watchcow.manifest.os_min_version":  "0.9.27",
+		"watchcow.share.media":              "/vol1/media",
+		"watchcow.wizard.install.tips":      "Hello",
+		"com.docker.compose.project":        "web",
+		"com.docker.compose.container-name": "nginx",
+	})
+	if len(problems) != 0 {
+		t.Errorf("ValidateLabels() = %v, want no problems", problems)
+	}
+	if problems.Err() != nil {
+		t.Errorf("Err() = %v, want nil", problems.Err())
+	}
+}
+
+func TestValidateLabels_Problems(t *testing.T) {
+	problems := ValidateLabels(map[string]string{
+		"watchcow.enable":              "true",
+		"watchcow.all_users":           "yes",
+		"watchcow.servce_port":         "8080",
+		"watchcow.ui_type":             "window",
+		"watchcow.service_port":        "http",
+		"watchcow.appname":             "my app",
+		"watchcow.admin.service_port":  "99999",
+		"watchcow.admin.servce_port":   "81",
+		"watchcow.Admin.path":          "/admin",
+		"watchcow.share.path":          "/srv",
+		"watchcow.manifest.ctl_stop":   "no",
+		"watchcow.wait_timeout":        "soon",
+		"watchcow.something_else":      "x",
+		"watchcow.admin.control.bogus": "x",
+	})
+
+	tests := []struct {
+		label    string
+		kind     ProblemKind
+		severity Severity
+	}{
+		{"watchcow.all_users", ProblemBadBool, SeverityError},
+		{"watchcow.servce_port", ProblemUnknownKey, SeverityWarning},
+		{"watchcow.ui_type", ProblemBadEnum, SeverityError},
+		{"watchcow.service_port", ProblemInvalidPort, SeverityError},
+		{"watchcow.appname", ProblemInvalidAppName, SeverityError},
+		{"watchcow.admin.service_port", ProblemInvalidPort, SeverityError},
+		{"watchcow.admin.servce_port", ProblemUnknownKey, SeverityWarning},
+		{"watchcow.admin", ProblemEntryCollision, SeverityError},
+		{"watchcow.share", ProblemEntryCollision, SeverityError},
+		{"watchcow.manifest.ctl_stop", ProblemInvalidValue, SeverityError},
+		{"watchcow.wait_timeout", ProblemInvalidValue, SeverityError},
+		{"watchcow.something_else", ProblemUnknownKey, SeverityWarning},
+		{"watchcow.admin.control.bogus", ProblemUnknownKey, SeverityWarning},
+	}
+	for _, tt := range tests {
+		p := findProblem(problems, tt.label)
+		if p == nil {
+			t.Errorf("no problem reported for %s", tt.label)
+			continue
+		}
+		if p.Kind != tt.kind || p.Severity != tt.severity {
+			t.Errorf("%s: got %s/%s, want %s/%s (%s)", tt.label, p.Kind, p.Severity, tt.kind, tt.severity, p.Message)
+		}
+	}
+	if len(problems) != len(tests) {
+		t.Errorf("ValidateLabels() returned %d problems, want %d: %v", len(problems), len(tests), problems)
+	}
+
+	if p := findProblem(problems, "watchcow.servce_port"); p != nil && p.Message != `unknown label, did you mean "watchcow.service_port"?` {
+		t.Errorf("servce_port message = %q", p.Message)
+	}
+	if p := findProblem(problems, "watchcow.admin.servce_port"); p != nil && p.Message != `unknown label, did you mean "watchcow.admin.service_port"?` {
+		t.Errorf("admin.servce_port message = %q", p.Message)
+	}
+	if !problems.HasErrors() || !errors.Is(problems.Err(), ErrInvalidLabels) {
+		t.Errorf("Err() = %v, want ErrInvalidLabels", problems.Err())
+	}
+}
+
+func TestValidateLabels_WarningsOnly(t *testing.T) {
+	problems := ValidateLabels(map[string]string{
+		"watchcow.enable":   "true",
+		"watchcow.titel":    "Nginx",
+		"watchcow.whatever": "x",
+	})
+	if len(problems) != 2 {
+		t.Fatalf("ValidateLabels() = %v, want 2 warnings", problems)
+	}
+	if problems.HasErrors() || problems.Err() != nil {
+		t.Errorf("warnings must not block installation: %v", problems.Err())
+	}
+}
@@ -0,0 +1,252 @@
+package fpkgen
+
+import (
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"sort"
+	"strings"
+)
+
+// wizardLabelPrefix is the label namespace for install and uninstall wizards
+const wizardLabelPrefix = "watchcow.wizard."
+
+// Wizard fields, passed by fnOS to the callback scripts as environment variables
+const (
+	wizardFieldRemoveContainer = "WATCHCOW_REMOVE_CONTAINER"
+	wizardFieldRemoveData      = "WATCHCOW_REMOVE_DATA"
+)
+
+// WizardStep is a page of an fnOS wizard (wizard/install, wizard/uninstall).
+type WizardStep struct {
+	StepTitle string       `json:"stepTitle"`
+	Items     []WizardItem `json:"items"`
+}
+
+// WizardItem is a tip or input of a wizard step.
+type WizardItem struct {
+	Type      string `json:"type"` // "tips" or "switch"
+	Field     string `json:"field,omitempty"`
+	Label     string `json:"label,omitempty"`
+	HelpText  string `json:"helpText,omitempty"`
+	InitValue string `json:"initValue,omitempty"`
+}
+
+// WizardFromLabels parses the wizard labels:
+//
+//	watchcow.wizard.install.tips                    -> install wizard notice
+//	watchcow.wizard.uninstall.remove_container=true -> uninstall wizard offers removing the container
+//	watchcow.wizard.uninstall.confirm_data_removal=true
+//	                                                -> uninstall wizard offers removing the container
+//	                                                   and its bind-mounted data (see removableDataPaths)
+//	watchcow.wizard.uninstall.data_paths=<path>,... -> bind sources that data removal deletes
+//
+// Unknown wizard labels are logged and ignored.
+func WizardFromLabels(labels map[string]string, volumes []VolumeMapping) WizardConfig {
+	var w WizardConfig
+	var dataPaths []string
+	for label, value := range labels {
+		key, ok := strings.CutPrefix(label, wizardLabelPrefix)
+		if !ok {
+			continue
+		}
+		switch key {
+		case "install.tips":
+			w.InstallTips = strings.TrimSpace(value)
+		case "uninstall.remove_container":
+			w.RemoveContainer = value == "true"
+		case "uninstall.confirm_data_removal":
+			w.ConfirmDataRemoval = value == "true"
+		case "uninstall.data_paths":
+			dataPaths = splitDataPaths(value)
+		default:
+			slog.Warn("Ignoring unknown wizard label", "label", label)
+		}
+	}
+
+	if w.ConfirmDataRemoval {
+		w.DataPaths = removableDataPaths(getBasePath(labels), dataPaths, volumes)
+		if len(w.DataPaths) == 0 {
+			slog.Warn("watchcow.wizard.uninstall.confirm_data_removal set without removable bind mounts")
+			w.ConfirmDataRemoval = false
+		}
+	}
+	return w
+}
+
+// splitDataPaths splits the comma-separated watchcow.wizard.uninstall.data_paths label.
+func splitDataPaths(value string) []string {
+	var paths []string
+	for _, p := range strings.Split(value, ",") {
+		if p = strings.TrimSpace(p); p != "" {
+			paths = append(paths, filepath.Clean(p))
+		}
+	}
+	return paths
+}
+
+// protectedTrees are host directories whose contents are never removed.
+var protectedTrees = []string{
+	"/bin", "/boot", "/dev", "/etc", "/lib", "/lib32", "/lib64", "/libx32", "/proc",
+	"/run", "/sbin", "/sys", "/usr", "/var/run", "/var/lib/docker", "/var/lib/containerd",
+}
+
+// protectedDirs are host directories that are never removed themselves.
+var protectedDirs = []string{
+	"/", "/home", "/root", "/mnt", "/media", "/opt", "/srv", "/tmp", "/var", "/var/lib", "/var/log",
+}
+
+// volumeRootPattern matches fnOS storage volumes and the user directories on them,
+// e.g. /vol1 and /vol1/1000.
+var volumeRootPattern = regexp.MustCompile(`^/vol[0-9]+(/[^/]+)?$`)
+
+// removableDataPaths returns the bind-mounted directories the uninstall wizard may remove.
+//
+// If data_paths lists paths, only those are removable and each must be a bind source of
+// the container. Otherwise only bind sources strictly inside the compose working directory
+// are removable, so containers started without compose have nothing to remove.
+// Either way, system directories, volume roots and home directories are refused.
+// Only the paths are checked, never the host filesystem, so the generated package does
+// not depend on the machine it is generated on; the uninstall callback skips paths that
+// are not directories (sockets, files, symlinks) when it runs.
+func removableDataPaths(workDir string, dataPaths []string, volumes []VolumeMapping) []string {
+	binds := make(map[string]bool)
+	for _, v := range volumes {
+		if v.Type == "bind" {
+			binds[filepath.Clean(v.Source)] = true
+		}
+	}
+
+	candidates := dataPaths
+	if len(candidates) == 0 {
+		for _, v := range volumes {
+			source := filepath.Clean(v.Source)
+			if v.Type == "bind" && workDir != "" && isInside(source, filepath.Clean(workDir)) {
+				candidates = append(candidates, source)
+			}
+		}
+	}
+
+	var paths []string
+	for _, source := range candidates {
+		if !binds[source] {
+			slog.Warn("Not removable, not a bind mount of the container", "path", source)
+			continue
+		}
+		if err := checkRemovable(source); err != nil {
+			slog.Warn("Not removable", "path", source, "error", err)
+			continue
+		}
+		if !slices.Contains(paths, source) {
+			paths = append(paths, source)
+		}
+	}
+	sort.Strings(paths)
+	return paths
+}
+
+// checkRemovable refuses paths the uninstall callback must never delete.
+func checkRemovable(path string) error {
+	if !safeHostPath(path) {
+		return fmt.Errorf("invalid path")
+	}
+	if slices.Contains(protectedDirs, path) || volumeRootPattern.MatchString(path) {
+		return fmt.Errorf("system or volume root directory")
+	}
+	if tree, ok := inProtectedTree(path); ok {
+		return fmt.Errorf("inside system directory %s", tree)
+	}
+	if parent := filepath.Dir(path); parent == "/home" || parent == "/root" {
+		return fmt.Errorf("home directory")
+	}
+	return nil
+}
+
+// inProtectedTree returns the protected tree that contains path, if any.
+func inProtectedTree(path string) (string, bool) {
+	for _, tree := range protectedTrees {
+		if path == tree || isInside(path, tree) {
+			return tree, true
+		}
+	}
+	return "", false
+}
+
+// isInside reports whether path is strictly inside dir.
+func isInside(path, dir string) bool {
+	rel, err := filepath.Rel(dir, path)
+	return err == nil && rel != "." && rel != ".." && !strings.HasPrefix(rel, "../")
+}
+
+// installWizard returns the install wizard steps, or nil if none is configured.
+func installWizard(data *TemplateData) []WizardStep {
+	if data.Wizard.InstallTips == "" {
+		return nil
+	}
+	return []WizardStep{{
+		StepTitle: "安装说明",
+		Items: []WizardItem{
+			{Type: "tips", HelpText: data.Wizard.InstallTips},
+		},
+	}}
+}
+
+// uninstallWizard returns the uninstall wizard steps, or nil if none is configured.
+func uninstallWizard(data *TemplateData) []WizardStep {
+	var items []WizardItem
+	if data.Wizard.RemoveContainer {
+		items = append(items, WizardItem{
+			Type:      "switch",
+			Field:     wizardFieldRemoveContainer,
+			Label:     "同时删除容器",
+			HelpText:  "删除 Docker 容器 " + data.ContainerName + "，数据目录保留",
+			InitValue: "false",
+		})
+	}
+	if data.Wizard.ConfirmDataRemoval {
+		items = append(items, WizardItem{
+			Type:      "switch",
+			Field:     wizardFieldRemoveData,
+			Label:     "同时删除容器及其数据",
+			HelpText:  "删除 Docker 容器 " + data.ContainerName + " 及以下目录，不可恢复：" + strings.Join(data.Wizard.DataPaths, "、"),
+			InitValue: "false",
+		})
+	}
+	if len(items) == 0 {
+		return nil
+	}
+	return []WizardStep{{StepTitle: "卸载选项", Items: items}}
+}
+
+// writeWizards writes wizard/install and wizard/uninstall for the configured wizards.
+func writeWizards(appDir string, data *TemplateData) error {
+	wizards := []struct {
+		path  string
+		steps []WizardStep
+	}{
+		{"install", installWizard(data)},
+		{"uninstall", uninstallWizard(data)},
+	}
+
+	for _, w := range wizards {
+		if w.steps == nil {
+			continue
+		}
+		content, err := json.MarshalIndent(w.steps, "", "  ")
+		if err != nil {
+			return err
+		}
+		path := filepath.Join(appDir, "wizard", w.path)
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			return err
+		}
+		if err := os.WriteFile(path, append(content, '\n'), 0644); err != nil {
+			return err
+		}
+	}
+	return nil
+}
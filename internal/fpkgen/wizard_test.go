@@ -0,0 +1,205 @@
+package fpkgen
+
+import (
+	"encoding/json"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestWizardFromLabels(t *testing.T) {
+	// The paths do not exist: only labels and mounts decide, not the host filesystem
+	data := "/srv/app/data"
+	outside := "/srv/shared"
+	volumes := []VolumeMapping{
+		{Source: data, Destination: "/data", Type: "bind"},
+		{Source: "/var/run/docker.sock", Destination: "/var/run/docker.sock", Type: "bind"},
+		{Source: outside, Destination: "/shared", Type: "bind"},
+		{Source: "/", Destination: "/host", Type: "bind"},
+		{Source: "/var/lib/docker/volumes/cache/_data", Destination: "/cache", Type: "volume"},
+	}
+
+	w := WizardFromLabels(map[string]string{
+		"com.docker.compose.project.working_dir":         "/srv/app",
+		"watchcow.wizard.install.tips":                   " Default password: admin ",
+		"watchcow.wizard.uninstall.remove_container":     "true",
+		"watchcow.wizard.uninstall.confirm_data_removal": "true",
+		"watchcow.wizard.upgrade.tips":                   "ignored",
+	}, volumes)
+
+	if w.InstallTips != "Default password: admin" {
+		t.Errorf("InstallTips = %q", w.InstallTips)
+	}
+	if !w.RemoveContainer || !w.ConfirmDataRemoval {
+		t.Errorf("uninstall options = %+v, want both enabled", w)
+	}
+	if len(w.DataPaths) != 1 || w.DataPaths[0] != data {
+		t.Errorf("DataPaths = %v, want only %s", w.DataPaths, data)
+	}
+
+	// Explicit data paths replace the working directory rule
+	w = WizardFromLabels(map[string]string{
+		"watchcow.wizard.uninstall.confirm_data_removal": "true",
+		"watchcow.wizard.uninstall.data_paths":           outside + ", /not/mounted",
+	}, volumes)
+	if len(w.DataPaths) != 1 || w.DataPaths[0] != outside {
+		t.Errorf("DataPaths = %v, want only %s", w.DataPaths, outside)
+	}
+
+	// Without a compose working directory or data paths there is nothing to confirm
+	w = WizardFromLabels(map[string]string{"watchcow.wizard.uninstall.confirm_data_removal": "true"}, volumes)
+	if w.ConfirmDataRemoval {
+		t.Error("ConfirmDataRemoval should be disabled without removable data")
+	}
+}
+
+func TestRemovableDataPaths_Refused(t *testing.T) {
+	for _, path := range []string{
+		"/",
+		"/vol1",
+		"/vol1/1000",
+		"/etc/ssl",
+		"/usr/local/share",
+		"/home",
+		"/home/user",
+		"/root/app",
+		"/var",
+		"/var/run",
+		"/var/run/docker.sock",
+		"/var/lib/docker/volumes/data/_data",
+		"/tmp",
+	} {
+		volumes := []VolumeMapping{{Source: path, Destination: "/data", Type: "bind"}}
+		if got := removableDataPaths("", []string{path}, volumes); len(got) != 0 {
+			t.Errorf("removableDataPaths(%s) = %v, want refused", path, got)
+		}
+	}
+
+	// Inside the working directory, but not the working directory itself
+	workDir := "/srv/app"
+	volumes := []VolumeMapping{{Source: workDir, Destination: "/app", Type: "bind"}}
+	if got := removableDataPaths(workDir, nil, volumes); len(got) != 0 {
+		t.Errorf("removableDataPaths(working dir) = %v, want refused", got)
+	}
+}
+
+func TestGenerateFromConfig_Wizards(t *testing.T) {
+	g := newFingerprintTestGenerator(t)
+	appDir := filepath.Join(t.TempDir(), "app")
+
+	config := fingerprintTestConfig()
+	config.Icon = ""
+	config.Entries[0].Icon = ""
+	if err := g.GenerateFromConfig(config, appDir); err != nil {
+		t.Fatalf("GenerateFromConfig() error = %v", err)
+	}
+	if _, err := os.Stat(filepath.Join(appDir, "wizard")); !os.IsNotExist(err) {
+		t.Error("wizard/ should not be generated without wizard labels")
+	}
+
+	config.Wizard = WizardConfig{
+		InstallTips:        "Default password: admin",
+		RemoveContainer:    true,
+		ConfirmDataRemoval: true,
+		DataPaths:          []string{"/vol1/docker/nginx/html"},
+	}
+	if err := g.GenerateFromConfig(config, appDir); err != nil {
+		t.Fatalf("GenerateFromConfig() error = %v", err)
+	}
+
+	var install, uninstall []WizardStep
+	for path, steps := range map[string]*[]WizardStep{"install": &install, "uninstall": &uninstall} {
+		content, err := os.ReadFile(filepath.Join(appDir, "wizard", path))
+		if err != nil {
+			t.Fatalf("wizard/%s: %v", path, err)
+		}
+		if err := json.Unmarshal(content, steps); err != nil {
+			t.Fatalf("wizard/%s is not valid JSON: %v", path, err)
+		}
+	}
+	if len(install) != 1 || install[0].Items[0].HelpText != "Default password: admin" {
+		t.Errorf("install wizard = %+v", install)
+	}
+	if len(uninstall) != 1 || len(uninstall[0].Items) != 2 {
+		t.Fatalf("uninstall wizard = %+v", uninstall)
+	}
+	if uninstall[0].Items[1].Field != "WATCHCOW_REMOVE_DATA" || !strings.Contains(uninstall[0].Items[1].HelpText, "/vol1/docker/nginx/html") {
+		t.Errorf("data removal item = %+v", uninstall[0].Items[1])
+	}
+
+	callback, err := os.ReadFile(filepath.Join(appDir, "cmd", "uninstall_callback"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, want := range []string{
+		`if [ "${WATCHCOW_REMOVE_DATA}" = "true" ]; then`,
+		`remove_data "/vol1/docker/nginx/html"`,
+		`if [ "${WATCHCOW_REMOVE_CONTAINER}" = "true" ]; then`,
+		`docker rm -f "$CONTAINER_NAME"`,
+	} {
+		if !strings.Contains(string(callback), want) {
+			t.Errorf("uninstall_callback missing %q:\n%s", want, callback)
+		}
+	}
+}
+
+// TestUninstallCallback_RemovesOnlyDirectories runs the generated uninstall callback
+// against paths that were replaced by a file and a symlink after generation.
+func TestUninstallCallback_RemovesOnlyDirectories(t *testing.T) {
+	if _, err := exec.LookPath("bash"); err != nil {
+		t.Skip("bash not available")
+	}
+
+	root := t.TempDir()
+	dir := filepath.Join(root, "data")
+	file := filepath.Join(root, "file")
+	target := filepath.Join(root, "target")
+	link := filepath.Join(root, "link")
+	for _, d := range []string{dir, target} {
+		if err := os.MkdirAll(d, 0755); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := os.WriteFile(file, nil, 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Symlink(target, link); err != nil {
+		t.Fatal(err)
+	}
+
+	// Stub docker so the callback never touches a real container
+	bin := filepath.Join(root, "bin")
+	if err := os.MkdirAll(bin, 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(bin, "docker"), []byte("#!/bin/sh\nexit 0\n"), 0755); err != nil {
+		t.Fatal(err)
+	}
+
+	g := newFingerprintTestGenerator(t)
+	appDir := filepath.Join(root, "app")
+	config := fingerprintTestConfig()
+	config.Icon = ""
+	config.Entries[0].Icon = ""
+	config.Wizard = WizardConfig{ConfirmDataRemoval: true, DataPaths: []string{dir, file, link}}
+	if err := g.GenerateFromConfig(config, appDir); err != nil {
+		t.Fatalf("GenerateFromConfig() error = %v", err)
+	}
+
+	cmd := exec.Command("bash", filepath.Join(appDir, "cmd", "uninstall_callback"))
+	cmd.Env = append(os.Environ(), "PATH="+bin+string(os.PathListSeparator)+os.Getenv("PATH"), "WATCHCOW_REMOVE_DATA=true")
+	if out, err := cmd.CombinedOutput(); err != nil {
+		t.Fatalf("uninstall_callback error = %v\n%s", err, out)
+	}
+
+	if _, err := os.Stat(dir); !os.IsNotExist(err) {
+		t.Errorf("directory %s was not removed", dir)
+	}
+	for _, path := range []string{file, link, target} {
+		if _, err := os.Lstat(path); err != nil {
+			t.Errorf("%s should be kept: %v", path, err)
+		}
+	}
+}
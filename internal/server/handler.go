@@ -9,7 +9,9 @@ import (
 	"image"
 	"io"
 	"log/slog"
+	"mime"
 	"net/http"
+	"os"
 	"sort"
 	"strings"
 	"time"
@@ -17,6 +19,7 @@ import (
 	"github.com/go-chi/chi/v5"
 
 	"watchcow/internal/docker"
+	"watchcow/internal/fpkgen"
 	"watchcow/web"
 )
 
@@ -31,6 +34,40 @@ type AppTrigger interface {
 	TriggerInstall(containerID string, storedConfig *docker.StoredConfig)
 	// TriggerUninstall triggers app uninstallation by app name.
 	TriggerUninstall(appName string)
+	// TriggerReconcile re-runs reconciliation between installed apps and containers.
+	TriggerReconcile()
+	// UninstallOrphan uninstalls an orphaned app awaiting review. Returns false if it is not an orphan.
+	UninstallOrphan(appName string) bool
+	// RetryUninstall retries a failed uninstall. Returns false if the app has no uninstall failure.
+	RetryUninstall(appName string) bool
+	// RetryFailedOperation re-queues a failed operation by key. Returns false if not found.
+	RetryFailedOperation(key string) bool
+	// TriggerPackageInstall re-installs a stored package generation. Returns false if not found.
+	TriggerPackageInstall(appName, id string) bool
+}
+
+// StatusProvider exposes monitor runtime state for the status page.
+type StatusProvider interface {
+	// Orphans returns installed apps without a container, awaiting review.
+	Orphans() []docker.OrphanApp
+	// QueueDepth returns the number of pending monitor operations.
+	QueueDepth() int
+	// FailedOperations returns operations that exhausted their retries.
+	FailedOperations() []docker.FailedOperation
+	// InstalledApps returns the cached list of apps installed in fnOS.
+	InstalledApps() []docker.InstalledApp
+	// InstalledRefreshedAt returns when the installed app list was last refreshed.
+	InstalledRefreshedAt() time.Time
+	// UninstallFailures returns apps that are still installed because their uninstall failed.
+	UninstallFailures() []docker.UninstallFailure
+	// Packages returns the stored package generations of all apps.
+	Packages() []docker.StoredPackage
+	// LabelReports returns the label problems of label-configured containers.
+	LabelReports() []docker.LabelReport
+	// NotReadyApps returns apps not installed because their container did not become ready in time.
+	NotReadyApps() []docker.NotReadyApp
+	// PackageFile returns the path of a stored .fpk file.
+	PackageFile(appName, id string) (string, error)
 }
 
 // DashboardHandler provides HTTP handlers for the dashboard.
@@ -38,6 +75,7 @@ type DashboardHandler struct {
 	storage *DashboardStorage
 	lister  ContainerLister
 	trigger AppTrigger
+	status  StatusProvider
 	tmpl    *template.Template
 }
 
@@ -56,6 +94,7 @@ func NewDashboardHandler(storage *DashboardStorage, lister ContainerLister, trig
 		"templates/dashboard.tmpl",
 		"templates/container_list.tmpl",
 		"templates/container_form.tmpl",
+		"templates/status.tmpl",
 	}
 
 	for _, file := range templateFiles {
@@ -79,6 +118,11 @@ func NewDashboardHandler(storage *DashboardStorage, lister ContainerLister, trig
 	}, nil
 }
 
+// SetStatusProvider sets the provider for the status page.
+func (h *DashboardHandler) SetStatusProvider(provider StatusProvider) {
+	h.status = provider
+}
+
 // Mount registers the dashboard routes on the given router.
 func (h *DashboardHandler) Mount(r chi.Router) {
 	r.Get("/", h.handleDashboard)
@@ -87,6 +131,13 @@ func (h *DashboardHandler) Mount(r chi.Router) {
 	r.Get("/containers/{id}", h.handleContainerForm)
 	r.Post("/containers/{id}", h.handleContainerSave)
 	r.Delete("/containers/{id}", h.handleContainerDelete)
+	r.Get("/status", h.handleStatus)
+	r.Post("/reconcile", h.handleReconcile)
+	r.Post("/orphans/{app}/uninstall", h.handleOrphanUninstall)
+	r.Post("/uninstall-failures/{app}/retry", h.handleUninstallRetry)
+	r.Post("/failed/{key}/retry", h.handleFailedRetry)
+	r.Get("/packages/{app}/{id}/download", h.handlePackageDownload)
+	r.Post("/packages/{app}/{id}/install", h.handlePackageInstall)
 }
 
 // listContainers fetches containers and enriches with storage info.
@@ -219,8 +270,9 @@ func (h *DashboardHandler) handleContainerList(w http.ResponseWriter, r *http.Re
 
 // containerFormData holds data for the container form partial.
 type containerFormData struct {
-	Container *ContainerInfo
-	Config    *StoredConfig
+	Container    *ContainerInfo
+	Config       *StoredConfig
+	ManifestKeys []string // Extra manifest fields editable in the form
 }
 
 // handleContainerForm renders the container config form partial (HTMX).
@@ -247,8 +299,9 @@ func (h *DashboardHandler) handleContainerForm(w http.ResponseWriter, r *http.Re
 	}
 
 	data := containerFormData{
-		Container: container,
-		Config:    config,
+		Container:    container,
+		Config:       config,
+		ManifestKeys: fpkgen.ManifestKeys,
 	}
 
 	w.Header().Set("Content-Type", "text/html; charset=utf-8")
@@ -322,6 +375,13 @@ func (h *DashboardHandler) handleContainerSave(w http.ResponseWriter, r *http.Re
 	// Parse entries
 	config.Entries = h.parseEntriesFromForm(r)
 
+	manifest, err := h.parseManifestFromForm(r)
+	if err != nil {
+		h.renderError(w, http.StatusBadRequest, "Manifest 字段无效："+err.Error())
+		return
+	}
+	config.Manifest = manifest
+
 	// Validate defaults
 	if config.DisplayName == "" {
 		config.DisplayName = container.Name
@@ -418,6 +478,113 @@ func (h *DashboardHandler) handleContainerDelete(w http.ResponseWriter, r *http.
 </article>`))
 }
 
+// statusData holds data for the status partial.
+type statusData struct {
+	QueueDepth           int
+	FailedOps            []docker.FailedOperation
+	UninstallFailures    []docker.UninstallFailure
+	Packages             []docker.StoredPackage
+	LabelReports         []docker.LabelReport
+	NotReadyApps         []docker.NotReadyApp
+	Orphans              []docker.OrphanApp
+	InstalledApps        []docker.InstalledApp
+	InstalledRefreshedAt time.Time
+}
+
+// handleStatus renders the monitor status partial (HTMX).
+func (h *DashboardHandler) handleStatus(w http.ResponseWriter, r *http.Request) {
+	var data statusData
+	if h.status != nil {
+		data.QueueDepth = h.status.QueueDepth()
+		data.FailedOps = h.status.FailedOperations()
+		data.UninstallFailures = h.status.UninstallFailures()
+		data.Packages = h.status.Packages()
+		data.LabelReports = h.status.LabelReports()
+		data.NotReadyApps = h.status.NotReadyApps()
+		data.Orphans = h.status.Orphans()
+		data.InstalledApps = h.status.InstalledApps()
+		data.InstalledRefreshedAt = h.status.InstalledRefreshedAt()
+	}
+
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	if err := h.tmpl.ExecuteTemplate(w, "status", data); err != nil {
+		slog.Error("Failed to render status", "error", err)
+	}
+}
+
+// handleReconcile queues a reconciliation pass.
+func (h *DashboardHandler) handleReconcile(w http.ResponseWriter, r *http.Request) {
+	if h.trigger != nil {
+		h.trigger.TriggerReconcile()
+	}
+
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	w.Write([]byte(`<article class="notification is-success">
+	<p>已开始同步检查，稍后刷新查看结果。</p>
+	<button class="button is-small mt-2" hx-get="status" hx-target="#main-content" hx-swap="innerHTML">刷新</button>
+</article>`))
+}
+
+// handleOrphanUninstall uninstalls an orphaned app after review.
+func (h *DashboardHandler) handleOrphanUninstall(w http.ResponseWriter, r *http.Request) {
+	appName := chi.URLParam(r, "app")
+	if appName == "" {
+		h.renderError(w, http.StatusBadRequest, "无效的应用名称")
+		return
+	}
+
+	if h.trigger == nil || !h.trigger.UninstallOrphan(appName) {
+		h.renderError(w, http.StatusNotFound, "孤立应用不存在或已卸载")
+		return
+	}
+
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	w.Write([]byte(`<article class="notification is-success">
+	<p>已开始卸载！</p>
+	<button class="button is-small mt-2" hx-get="status" hx-target="#main-content" hx-swap="innerHTML">返回状态</button>
+</article>`))
+}
+
+// handleUninstallRetry retries the uninstall of an app whose uninstall failed.
+func (h *DashboardHandler) handleUninstallRetry(w http.ResponseWriter, r *http.Request) {
+	appName := chi.URLParam(r, "app")
+	if appName == "" {
+		h.renderError(w, http.StatusBadRequest, "无效的应用名称")
+		return
+	}
+
+	if h.trigger == nil || !h.trigger.RetryUninstall(appName) {
+		h.renderError(w, http.StatusNotFound, "卸载失败记录不存在或已重试")
+		return
+	}
+
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	w.Write([]byte(`<article class="notification is-success">
+	<p>已重新开始卸载！</p>
+	<button class="button is-small mt-2" hx-get="status" hx-target="#main-content" hx-swap="innerHTML">返回状态</button>
+</article>`))
+}
+
+// handleFailedRetry re-queues an operation from the failed operations list.
+func (h *DashboardHandler) handleFailedRetry(w http.ResponseWriter, r *http.Request) {
+	key := chi.URLParam(r, "key")
+	if key == "" {
+		h.renderError(w, http.StatusBadRequest, "无效的操作")
+		return
+	}
+
+	if h.trigger == nil || !h.trigger.RetryFailedOperation(key) {
+		h.renderError(w, http.StatusNotFound, "操作不存在或已重试")
+		return
+	}
+
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	w.Write([]byte(`<article class="notification is-success">
+	<p>已重新加入操作队列！</p>
+	<button class="button is-small mt-2" hx-get="status" hx-target="#main-content" hx-swap="innerHTML">返回状态</button>
+</article>`))
+}
+
 // processIcon validates an uploaded image and returns base64 encoded data.
 // Image processing (square padding, resizing) is handled by fpkgen.handleIcons
 // during app generation, keeping the install flow consistent with label-based icons.
@@ -469,6 +636,26 @@ func (h *DashboardHandler) parseEntriesFromForm(r *http.Request) []StoredEntry {
 	return []StoredEntry{entry}
 }
 
+// parseManifestFromForm extracts the extra manifest fields from form data.
+// Empty fields are left unset so the generator uses its defaults.
+func (h *DashboardHandler) parseManifestFromForm(r *http.Request) (map[string]string, error) {
+	var manifest map[string]string
+	for _, key := range fpkgen.ManifestKeys {
+		value := strings.TrimSpace(r.FormValue("manifest_" + key))
+		if value == "" {
+			continue
+		}
+		if err := fpkgen.ValidateManifestField(key, value); err != nil {
+			return nil, err
+		}
+		if manifest == nil {
+			manifest = make(map[string]string)
+		}
+		manifest[key] = value
+	}
+	return manifest, nil
+}
+
 // createDefaultConfig creates a default configuration for a container.
 func (h *DashboardHandler) createDefaultConfig(container *ContainerInfo) *StoredConfig {
 	config := &StoredConfig{
@@ -509,6 +696,7 @@ func (h *DashboardHandler) convertToDockerConfig(config *StoredConfig) *docker.S
 		Version:     config.Version,
 		Maintainer:  config.Maintainer,
 		IconBase64:  config.IconBase64,
+		Manifest:    config.Manifest,
 		Entries:     make([]docker.StoredEntry, 0, len(config.Entries)),
 	}
 
@@ -537,3 +725,55 @@ func (h *DashboardHandler) renderError(w http.ResponseWriter, status int, msg st
 	w.WriteHeader(status)
 	fmt.Fprintf(w, `<article class="notification is-danger">%s</article>`, template.HTMLEscapeString(msg))
 }
+
+// handlePackageDownload serves a stored .fpk package as a file download.
+func (h *DashboardHandler) handlePackageDownload(w http.ResponseWriter, r *http.Request) {
+	appName := chi.URLParam(r, "app")
+	id := chi.URLParam(r, "id")
+
+	if h.status == nil {
+		h.renderError(w, http.StatusNotFound, "应用包不存在")
+		return
+	}
+	path, err := h.status.PackageFile(appName, id)
+	if err != nil {
+		h.renderError(w, http.StatusNotFound, "应用包不存在")
+		return
+	}
+
+	f, err := os.Open(path)
+	if err != nil {
+		slog.Error("Failed to open package", "path", path, "error", err)
+		h.renderError(w, http.StatusNotFound, "应用包不存在")
+		return
+	}
+	defer f.Close()
+
+	info, err := f.Stat()
+	if err != nil {
+		h.renderError(w, http.StatusInternalServerError, "读取应用包失败")
+		return
+	}
+
+	filename := appName + "-" + id + fpkgen.PackageExt
+	w.Header().Set("Content-Type", "application/octet-stream")
+	w.Header().Set("Content-Disposition", mime.FormatMediaType("attachment", map[string]string{"filename": filename}))
+	http.ServeContent(w, r, filename, info.ModTime(), f)
+}
+
+// handlePackageInstall re-installs a stored package generation (rollback).
+func (h *DashboardHandler) handlePackageInstall(w http.ResponseWriter, r *http.Request) {
+	appName := chi.URLParam(r, "app")
+	id := chi.URLParam(r, "id")
+
+	if h.trigger == nil || !h.trigger.TriggerPackageInstall(appName, id) {
+		h.renderError(w, http.StatusNotFound, "应用包不存在")
+		return
+	}
+
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	w.Write([]byte(`<article class="notification is-success">
+	<p>已开始安装该版本的应用包！</p>
+	<button class="button is-small mt-2" hx-get="status" hx-target="#main-content" hx-swap="innerHTML">返回状态</button>
+</article>`))
+}
@@ -6,12 +6,15 @@ import (
 	"net/http/httptest"
 	"net/url"
 	"os"
+	"path/filepath"
 	"strings"
 	"testing"
+	"time"
 
 	"github.com/go-chi/chi/v5"
 
 	"watchcow/internal/docker"
+	"watchcow/internal/fpkgen"
 )
 
 // mockContainerLister implements ContainerLister for testing
@@ -25,7 +28,17 @@ func (m *mockContainerLister) ListAllContainers(ctx context.Context) ([]docker.C
 
 // mockAppTrigger implements AppTrigger for testing
 type mockAppTrigger struct {
-	triggerCalls []triggerCall
+	triggerCalls   []triggerCall
+	uninstallCalls []string
+	reconcileCalls int
+	retryCalls     []string
+	failedKeys     map[string]bool
+	packageCalls   []string
+	packages       map[string]bool
+	orphans        map[string]bool
+	orphanCalls    []string
+	uninstallFails map[string]bool
+	uninstallRetry []string
 }
 
 type triggerCall struct {
@@ -38,7 +51,92 @@ func (m *mockAppTrigger) TriggerInstall(containerID string, storedConfig *docker
 }
 
 func (m *mockAppTrigger) TriggerUninstall(appName string) {
-	// Track uninstall calls if needed
+	m.uninstallCalls = append(m.uninstallCalls, appName)
+}
+
+func (m *mockAppTrigger) TriggerReconcile() {
+	m.reconcileCalls++
+}
+
+func (m *mockAppTrigger) UninstallOrphan(appName string) bool {
+	m.orphanCalls = append(m.orphanCalls, appName)
+	return m.orphans[appName]
+}
+
+func (m *mockAppTrigger) RetryUninstall(appName string) bool {
+	m.uninstallRetry = append(m.uninstallRetry, appName)
+	return m.uninstallFails[appName]
+}
+
+func (m *mockAppTrigger) RetryFailedOperation(key string) bool {
+	m.retryCalls = append(m.retryCalls, key)
+	if !m.failedKeys[key] {
+		return false
+	}
+	delete(m.failedKeys, key)
+	return true
+}
+
+func (m *mockAppTrigger) TriggerPackageInstall(appName, id string) bool {
+	m.packageCalls = append(m.packageCalls, appName+"/"+id)
+	return m.packages[appName+"/"+id]
+}
+
+// mockStatusProvider implements StatusProvider for testing
+type mockStatusProvider struct {
+	orphans    []docker.OrphanApp
+	queueDepth int
+	failedOps  []docker.FailedOperation
+	installed  []docker.InstalledApp
+	uninstalls []docker.UninstallFailure
+	packages   []docker.StoredPackage
+	labels     []docker.LabelReport
+	notReady   []docker.NotReadyApp
+}
+
+func (m *mockStatusProvider) Orphans() []docker.OrphanApp {
+	return m.orphans
+}
+
+func (m *mockStatusProvider) QueueDepth() int {
+	return m.queueDepth
+}
+
+func (m *mockStatusProvider) FailedOperations() []docker.FailedOperation {
+	return m.failedOps
+}
+
+func (m *mockStatusProvider) InstalledApps() []docker.InstalledApp {
+	return m.installed
+}
+
+func (m *mockStatusProvider) InstalledRefreshedAt() time.Time {
+	return time.Time{}
+}
+
+func (m *mockStatusProvider) UninstallFailures() []docker.UninstallFailure {
+	return m.uninstalls
+}
+
+func (m *mockStatusProvider) Packages() []docker.StoredPackage {
+	return m.packages
+}
+
+func (m *mockStatusProvider) LabelReports() []docker.LabelReport {
+	return m.labels
+}
+
+func (m *mockStatusProvider) NotReadyApps() []docker.NotReadyApp {
+	return m.notReady
+}
+
+func (m *mockStatusProvider) PackageFile(appName, id string) (string, error) {
+	for _, p := range m.packages {
+		if p.AppName == appName && p.ID == id {
+			return p.Path, nil
+		}
+	}
+	return "", docker.ErrPackageNotFound
 }
 
 func newMockAppTrigger() *mockAppTrigger {
@@ -54,6 +152,14 @@ func setChiURLParam(r *http.Request, key, value string) *http.Request {
 	return r.WithContext(context.WithValue(r.Context(), chi.RouteCtxKey, rctx))
 }
 
+// setPackageURLParams sets the {app} and {id} chi URL params of package routes
+func setPackageURLParams(r *http.Request, appName, id string) *http.Request {
+	rctx := chi.NewRouteContext()
+	rctx.URLParams.Add("app", appName)
+	rctx.URLParams.Add("id", id)
+	return r.WithContext(context.WithValue(r.Context(), chi.RouteCtxKey, rctx))
+}
+
 func setupTestHandler(t *testing.T) (*DashboardHandler, *DashboardStorage, *mockAppTrigger) {
 	t.Helper()
 
@@ -226,6 +332,73 @@ func TestDashboardHandler_ContainerSave(t *testing.T) {
 	}
 }
 
+func TestDashboardHandler_ContainerSave_Manifest(t *testing.T) {
+	handler, storage, trigger := setupTestHandler(t)
+
+	containerID := "abc123"
+	key := "nginx:alpine|80:8080"
+	form := url.Values{
+		"entry_port":              {"8080"},
+		"manifest_os_min_version": {"0.9.27"},
+		"manifest_maintainer_url": {"https://nginx.org"},
+		"manifest_changelog":      {""},
+	}
+
+	req := httptest.NewRequest("POST", "/containers/"+containerID, strings.NewReader(form.Encode()))
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	req = setChiURLParam(req, "id", containerID)
+	w := httptest.NewRecorder()
+
+	handler.handleContainerSave(w, req)
+
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected status 200, got %d: %s", w.Code, w.Body.String())
+	}
+	saved := storage.Get(ContainerKey(key))
+	if saved == nil {
+		t.Fatal("config should be saved")
+	}
+	want := map[string]string{"os_min_version": "0.9.27", "maintainer_url": "https://nginx.org"}
+	if len(saved.Manifest) != len(want) {
+		t.Errorf("Manifest = %v, want %v", saved.Manifest, want)
+	}
+	for k, v := range want {
+		if saved.Manifest[k] != v {
+			t.Errorf("Manifest[%s] = %q, want %q", k, saved.Manifest[k], v)
+		}
+	}
+	if len(trigger.triggerCalls) != 1 || trigger.triggerCalls[0].storedConfig.Manifest["os_min_version"] != "0.9.27" {
+		t.Error("TriggerInstall should receive the manifest fields")
+	}
+}
+
+func TestDashboardHandler_ContainerSave_InvalidManifest(t *testing.T) {
+	handler, storage, trigger := setupTestHandler(t)
+
+	containerID := "abc123"
+	form := url.Values{
+		"entry_port":        {"8080"},
+		"manifest_ctl_stop": {"maybe"},
+	}
+
+	req := httptest.NewRequest("POST", "/containers/"+containerID, strings.NewReader(form.Encode()))
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	req = setChiURLParam(req, "id", containerID)
+	w := httptest.NewRecorder()
+
+	handler.handleContainerSave(w, req)
+
+	if w.Code != http.StatusBadRequest {
+		t.Errorf("expected status 400, got %d", w.Code)
+	}
+	if storage.Get(ContainerKey("nginx:alpine|80:8080")) != nil {
+		t.Error("config should not be saved")
+	}
+	if len(trigger.triggerCalls) != 0 {
+		t.Error("TriggerInstall should not be called")
+	}
+}
+
 func TestDashboardHandler_ContainerSave_LabelConfigured(t *testing.T) {
 	handler, _, _ := setupTestHandler(t)
 
@@ -461,3 +634,273 @@ func TestDashboardHandler_NilTrigger(t *testing.T) {
 		t.Fatal("config should be saved with nil trigger")
 	}
 }
+
+func TestDashboardHandler_Status(t *testing.T) {
+	handler, _, _ := setupTestHandler(t)
+	handler.SetStatusProvider(&mockStatusProvider{
+		orphans:    []docker.OrphanApp{{AppName: "watchcow.gone", DetectedAt: time.Now()}},
+		queueDepth: 7,
+		installed:  []docker.InstalledApp{{Name: "watchcow.nginx", Version: "1.2.0", Status: "running"}},
+		failedOps: []docker.FailedOperation{{
+			Key:       "container:abc123",
+			Operation: docker.AppOperation{Type: "install", ContainerID: "abc123", ContainerName: "nginx", Attempt: 5},
+			Error:     "appcenter-cli install-local failed: exit status 1",
+			FailedAt:  time.Now(),
+		}},
+		uninstalls: []docker.UninstallFailure{{
+			AppName:  "watchcow.stuck",
+			Error:    "app still installed after uninstall: watchcow.stuck",
+			FailedAt: time.Now(),
+			Attempts: 2,
+		}},
+		packages: []docker.StoredPackage{
+			{AppName: "watchcow.nginx", ID: "1.2.0-def", Version: "1.2.0", CreatedAt: time.Now(), Current: true},
+			{AppName: "watchcow.nginx", ID: "1.1.0-abc", Version: "1.1.0", CreatedAt: time.Now()},
+		},
+		labels: []docker.LabelReport{{
+			ContainerName: "whoami",
+			AppName:       "watchcow.whoami",
+			Problems: fpkgen.Problems{
+				{Label: "watchcow.all_users", Value: "yes", Kind: fpkgen.ProblemBadBool, Severity: fpkgen.SeverityError, Message: `must be "true" or "false", got "yes"`},
+				{Label: "watchcow.servce_port", Value: "80", Kind: fpkgen.ProblemUnknownKey, Severity: fpkgen.SeverityWarning, Message: "unknown label"},
+			},
+		}},
+		notReady: []docker.NotReadyApp{{
+			ContainerName: "slowdb",
+			AppName:       "watchcow.slowdb",
+			WaitFor:       "healthy",
+			Timeout:       2 * time.Minute,
+			TimedOutAt:    time.Now(),
+		}},
+	})
+
+	req := httptest.NewRequest("GET", "/status", nil)
+	w := httptest.NewRecorder()
+
+	handler.handleStatus(w, req)
+
+	if w.Result().StatusCode != http.StatusOK {
+		t.Errorf("expected status 200, got %d", w.Result().StatusCode)
+	}
+	body := w.Body.String()
+	if !strings.Contains(body, "watchcow.gone") {
+		t.Error("response should list orphaned app")
+	}
+	if !strings.Contains(body, `hx-post="orphans/watchcow.gone/uninstall"`) {
+		t.Error("response should contain orphan uninstall button")
+	}
+	if !strings.Contains(body, `<span id="queue-depth">7</span>`) {
+		t.Error("response should show queue depth")
+	}
+	if !strings.Contains(body, `hx-post="failed/container:abc123/retry"`) {
+		t.Error("response should contain failed operation retry button")
+	}
+	if !strings.Contains(body, "exit status 1") {
+		t.Error("response should show failed operation error")
+	}
+	if !strings.Contains(body, "watchcow.nginx") || !strings.Contains(body, "1.2.0") {
+		t.Error("response should list installed apps")
+	}
+	if !strings.Contains(body, `hx-post="uninstall-failures/watchcow.stuck/retry"`) {
+		t.Error("response should contain failed uninstall retry button")
+	}
+	if !strings.Contains(body, `href="packages/watchcow.nginx/1.2.0-def/download"`) {
+		t.Error("response should contain package download link")
+	}
+	if !strings.Contains(body, `hx-post="packages/watchcow.nginx/1.1.0-abc/install"`) {
+		t.Error("response should contain rollback button for older package")
+	}
+	if strings.Contains(body, `hx-post="packages/watchcow.nginx/1.2.0-def/install"`) {
+		t.Error("response should not offer to reinstall the current package")
+	}
+	if !strings.Contains(body, "<code>watchcow.all_users</code>") || !strings.Contains(body, "<code>watchcow.servce_port</code>") {
+		t.Error("response should list label problems")
+	}
+	if !strings.Contains(body, "<code>watchcow.slowdb</code>") || !strings.Contains(body, "2m0s") {
+		t.Error("response should list apps whose container did not become ready")
+	}
+}
+
+func TestDashboardHandler_StatusWithoutProvider(t *testing.T) {
+	handler, _, _ := setupTestHandler(t)
+
+	req := httptest.NewRequest("GET", "/status", nil)
+	w := httptest.NewRecorder()
+
+	handler.handleStatus(w, req)
+
+	if w.Result().StatusCode != http.StatusOK {
+		t.Errorf("expected status 200, got %d", w.Result().StatusCode)
+	}
+	if !strings.Contains(w.Body.String(), "无孤立应用") {
+		t.Error("response should show empty orphan list")
+	}
+}
+
+func TestDashboardHandler_Reconcile(t *testing.T) {
+	handler, _, trigger := setupTestHandler(t)
+
+	req := httptest.NewRequest("POST", "/reconcile", nil)
+	w := httptest.NewRecorder()
+
+	handler.handleReconcile(w, req)
+
+	if trigger.reconcileCalls != 1 {
+		t.Errorf("expected 1 TriggerReconcile call, got %d", trigger.reconcileCalls)
+	}
+}
+
+func TestDashboardHandler_OrphanUninstall(t *testing.T) {
+	handler, _, trigger := setupTestHandler(t)
+	trigger.orphans = map[string]bool{"watchcow.gone": true}
+
+	req := httptest.NewRequest("POST", "/orphans/watchcow.gone/uninstall", nil)
+	req = setChiURLParam(req, "app", "watchcow.gone")
+	w := httptest.NewRecorder()
+
+	handler.handleOrphanUninstall(w, req)
+
+	if w.Result().StatusCode != http.StatusOK {
+		t.Errorf("expected status 200, got %d", w.Result().StatusCode)
+	}
+	if len(trigger.orphanCalls) != 1 || trigger.orphanCalls[0] != "watchcow.gone" {
+		t.Errorf("orphanCalls = %v, want [watchcow.gone]", trigger.orphanCalls)
+	}
+	if len(trigger.uninstallCalls) != 0 {
+		t.Errorf("uninstallCalls = %v, want none", trigger.uninstallCalls)
+	}
+}
+
+func TestDashboardHandler_OrphanUninstallNotFound(t *testing.T) {
+	handler, _, trigger := setupTestHandler(t)
+	trigger.orphans = map[string]bool{"watchcow.gone": true}
+
+	for _, appName := range []string{"watchcow.running", "watchcow", "trim.media"} {
+		req := httptest.NewRequest("POST", "/orphans/"+appName+"/uninstall", nil)
+		req = setChiURLParam(req, "app", appName)
+		w := httptest.NewRecorder()
+
+		handler.handleOrphanUninstall(w, req)
+
+		if w.Result().StatusCode != http.StatusNotFound {
+			t.Errorf("%s: expected status 404, got %d", appName, w.Result().StatusCode)
+		}
+	}
+}
+
+func TestDashboardHandler_UninstallRetry(t *testing.T) {
+	handler, _, trigger := setupTestHandler(t)
+	trigger.uninstallFails = map[string]bool{"watchcow.stuck": true}
+
+	req := httptest.NewRequest("POST", "/uninstall-failures/watchcow.stuck/retry", nil)
+	req = setChiURLParam(req, "app", "watchcow.stuck")
+	w := httptest.NewRecorder()
+
+	handler.handleUninstallRetry(w, req)
+
+	if w.Result().StatusCode != http.StatusOK {
+		t.Errorf("expected status 200, got %d", w.Result().StatusCode)
+	}
+	if len(trigger.uninstallRetry) != 1 || len(trigger.orphanCalls) != 0 {
+		t.Errorf("uninstallRetry = %v, orphanCalls = %v", trigger.uninstallRetry, trigger.orphanCalls)
+	}
+
+	// Orphans cannot be uninstalled through the retry route
+	trigger.orphans = map[string]bool{"watchcow.gone": true}
+	req = httptest.NewRequest("POST", "/uninstall-failures/watchcow.gone/retry", nil)
+	req = setChiURLParam(req, "app", "watchcow.gone")
+	w = httptest.NewRecorder()
+
+	handler.handleUninstallRetry(w, req)
+
+	if w.Result().StatusCode != http.StatusNotFound {
+		t.Errorf("expected status 404, got %d", w.Result().StatusCode)
+	}
+}
+
+func TestDashboardHandler_FailedRetry(t *testing.T) {
+	handler, _, trigger := setupTestHandler(t)
+	trigger.failedKeys = map[string]bool{"container:abc123": true}
+
+	req := httptest.NewRequest("POST", "/failed/container:abc123/retry", nil)
+	req = setChiURLParam(req, "key", "container:abc123")
+	w := httptest.NewRecorder()
+
+	handler.handleFailedRetry(w, req)
+
+	if w.Result().StatusCode != http.StatusOK {
+		t.Errorf("expected status 200, got %d", w.Result().StatusCode)
+	}
+	if len(trigger.retryCalls) != 1 || trigger.retryCalls[0] != "container:abc123" {
+		t.Errorf("retryCalls = %v, want [container:abc123]", trigger.retryCalls)
+	}
+}
+
+func TestDashboardHandler_FailedRetryNotFound(t *testing.T) {
+	handler, _, _ := setupTestHandler(t)
+
+	req := httptest.NewRequest("POST", "/failed/container:gone/retry", nil)
+	req = setChiURLParam(req, "key", "container:gone")
+	w := httptest.NewRecorder()
+
+	handler.handleFailedRetry(w, req)
+
+	if w.Result().StatusCode != http.StatusNotFound {
+		t.Errorf("expected status 404, got %d", w.Result().StatusCode)
+	}
+}
+
+func TestDashboardHandler_PackageDownload(t *testing.T) {
+	handler, _, _ := setupTestHandler(t)
+	path := filepath.Join(t.TempDir(), "watchcow.nginx.fpk")
+	if err := os.WriteFile(path, []byte("fpk-data"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	handler.SetStatusProvider(&mockStatusProvider{
+		packages: []docker.StoredPackage{{AppName: "watchcow.nginx", ID: "1.0.0-abc", Path: path}},
+	})
+
+	req := setPackageURLParams(httptest.NewRequest("GET", "/packages/watchcow.nginx/1.0.0-abc/download", nil), "watchcow.nginx", "1.0.0-abc")
+	w := httptest.NewRecorder()
+	handler.handlePackageDownload(w, req)
+
+	if w.Result().StatusCode != http.StatusOK {
+		t.Fatalf("expected status 200, got %d", w.Result().StatusCode)
+	}
+	if w.Body.String() != "fpk-data" {
+		t.Errorf("body = %q, want package contents", w.Body.String())
+	}
+	if cd := w.Header().Get("Content-Disposition"); !strings.Contains(cd, "watchcow.nginx-1.0.0-abc.fpk") {
+		t.Errorf("Content-Disposition = %q", cd)
+	}
+
+	req = setPackageURLParams(httptest.NewRequest("GET", "/packages/watchcow.nginx/gone/download", nil), "watchcow.nginx", "gone")
+	w = httptest.NewRecorder()
+	handler.handlePackageDownload(w, req)
+	if w.Result().StatusCode != http.StatusNotFound {
+		t.Errorf("expected status 404 for missing package, got %d", w.Result().StatusCode)
+	}
+}
+
+func TestDashboardHandler_PackageInstall(t *testing.T) {
+	handler, _, trigger := setupTestHandler(t)
+	trigger.packages = map[string]bool{"watchcow.nginx/1.0.0-abc": true}
+
+	req := setPackageURLParams(httptest.NewRequest("POST", "/packages/watchcow.nginx/1.0.0-abc/install", nil), "watchcow.nginx", "1.0.0-abc")
+	w := httptest.NewRecorder()
+	handler.handlePackageInstall(w, req)
+
+	if w.Result().StatusCode != http.StatusOK {
+		t.Errorf("expected status 200, got %d", w.Result().StatusCode)
+	}
+	if len(trigger.packageCalls) != 1 || trigger.packageCalls[0] != "watchcow.nginx/1.0.0-abc" {
+		t.Errorf("packageCalls = %v", trigger.packageCalls)
+	}
+
+	req = setPackageURLParams(httptest.NewRequest("POST", "/packages/watchcow.nginx/gone/install", nil), "watchcow.nginx", "gone")
+	w = httptest.NewRecorder()
+	handler.handlePackageInstall(w, req)
+	if w.Result().StatusCode != http.StatusNotFound {
+		t.Errorf("expected status 404 for missing package, got %d", w.Result().StatusCode)
+	}
+}
@@ -0,0 +1,97 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net"
+	"net/http"
+	"net/url"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+
+	"watchcow/internal/docker"
+)
+
+// healthQueryTimeout bounds a status query from generated cmd/main scripts
+const healthQueryTimeout = 5 * time.Second
+
+// HealthProvider reports the runtime health of managed containers.
+type HealthProvider interface {
+	ContainerHealth(ctx context.Context, containerName string) (*docker.ContainerHealth, error)
+}
+
+// HealthHandler serves container health as JSON for the status command of generated cmd/main.
+// Path format: /health/<container name>
+type HealthHandler struct {
+	provider HealthProvider
+	router   chi.Router
+}
+
+// NewHealthHandler creates a health handler.
+func NewHealthHandler(provider HealthProvider) *HealthHandler {
+	h := &HealthHandler{provider: provider, router: chi.NewRouter()}
+	h.router.Get("/{container}", h.handleHealth)
+	return h
+}
+
+// ServeHTTP implements http.Handler.
+func (h *HealthHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	h.router.ServeHTTP(w, r)
+}
+
+// handleHealth returns the health of one container.
+func (h *HealthHandler) handleHealth(w http.ResponseWriter, r *http.Request) {
+	containerName := chi.URLParam(r, "container")
+
+	health, err := h.provider.ContainerHealth(r.Context(), containerName)
+	if errors.Is(err, docker.ErrContainerNotTracked) {
+		http.Error(w, err.Error(), http.StatusNotFound)
+		return
+	}
+	if err != nil {
+		slog.Warn("Failed to get container health", "container", containerName, "error", err)
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(health)
+}
+
+// QueryHealth asks the daemon listening on socketPath for the health of a container.
+// A container the daemon does not track is reported as stopped.
+func QueryHealth(socketPath, containerName string) (*docker.ContainerHealth, error) {
+	client := &http.Client{
+		Timeout: healthQueryTimeout,
+		Transport: &http.Transport{
+			DialContext: func(ctx context.Context, network, addr string) (net.Conn, error) {
+				var d net.Dialer
+				return d.DialContext(ctx, "unix", socketPath)
+			},
+		},
+	}
+
+	resp, err := client.Get("http://localhost/health/" + url.PathEscape(containerName))
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	switch resp.StatusCode {
+	case http.StatusOK:
+	case http.StatusNotFound:
+		return &docker.ContainerHealth{Container: containerName, Status: docker.HealthStatusStopped}, nil
+	default:
+		return nil, fmt.Errorf("health query failed: %s", resp.Status)
+	}
+
+	var health docker.ContainerHealth
+	if err := json.NewDecoder(resp.Body).Decode(&health); err != nil {
+		return nil, fmt.Errorf("invalid health response: %w", err)
+	}
+	return &health, nil
+}
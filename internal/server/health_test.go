@@ -0,0 +1,69 @@
+package server
+
+import (
+	"context"
+	"net"
+	"net/http"
+	"path/filepath"
+	"testing"
+
+	"watchcow/internal/docker"
+)
+
+// mockHealthProvider implements HealthProvider for testing
+type mockHealthProvider struct {
+	health map[string]*docker.ContainerHealth
+}
+
+func (m *mockHealthProvider) ContainerHealth(ctx context.Context, containerName string) (*docker.ContainerHealth, error) {
+	h, ok := m.health[containerName]
+	if !ok {
+		return nil, docker.ErrContainerNotTracked
+	}
+	return h, nil
+}
+
+// serveUnix serves handler on a temporary Unix socket and returns its path.
+func serveUnix(t *testing.T, handler http.Handler) string {
+	t.Helper()
+	socketPath := filepath.Join(t.TempDir(), "watchcow.sock")
+	listener, err := net.Listen("unix", socketPath)
+	if err != nil {
+		t.Fatalf("Listen() error = %v", err)
+	}
+	srv := &http.Server{Handler: handler}
+	go srv.Serve(listener)
+	t.Cleanup(func() { srv.Close() })
+	return socketPath
+}
+
+func TestQueryHealth(t *testing.T) {
+	provider := &mockHealthProvider{health: map[string]*docker.ContainerHealth{
+		"nginx": {Container: "nginx", State: "running", Health: "unhealthy", RestartCount: 2, Status: docker.HealthStatusUnhealthy},
+	}}
+	router := NewRouter(NewRedirectHandler(nil), NewHealthHandler(provider), nil)
+	socketPath := serveUnix(t, router)
+
+	h, err := QueryHealth(socketPath, "nginx")
+	if err != nil {
+		t.Fatalf("QueryHealth() error = %v", err)
+	}
+	if h.Status != docker.HealthStatusUnhealthy || h.RestartCount != 2 || h.Health != "unhealthy" {
+		t.Errorf("QueryHealth() = %+v", h)
+	}
+
+	// Untracked containers are reported as stopped
+	h, err = QueryHealth(socketPath, "gone")
+	if err != nil {
+		t.Fatalf("QueryHealth() error = %v", err)
+	}
+	if h.Status != docker.HealthStatusStopped {
+		t.Errorf("QueryHealth(gone) status = %q, want stopped", h.Status)
+	}
+}
+
+func TestQueryHealth_DaemonUnavailable(t *testing.T) {
+	if _, err := QueryHealth(filepath.Join(t.TempDir(), "missing.sock"), "nginx"); err == nil {
+		t.Error("QueryHealth() should fail without daemon")
+	}
+}
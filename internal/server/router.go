@@ -12,8 +12,9 @@ type DashboardMounter interface {
 	Mount(r chi.Router)
 }
 
-// NewRouter creates a new chi router with handlers mounted
-func NewRouter(redirectHandler http.Handler, dashboardHandler DashboardMounter) chi.Router {
+// NewRouter creates a new chi router with handlers mounted.
+// healthHandler may be nil.
+func NewRouter(redirectHandler, healthHandler http.Handler, dashboardHandler DashboardMounter) chi.Router {
 	r := chi.NewRouter()
 
 	// Middleware
@@ -23,6 +24,12 @@ func NewRouter(redirectHandler http.Handler, dashboardHandler DashboardMounter)
 	// Path format: /redirect/<appname>/<entry>[/<path...>]
 	r.Mount("/redirect", redirectHandler)
 
+	// Mount health handler at /health (status command of generated cmd/main)
+	// Path format: /health/<container name>
+	if healthHandler != nil {
+		r.Mount("/health", healthHandler)
+	}
+
 	// Mount dashboard handler at /
 	if dashboardHandler != nil {
 		r.Group(func(r chi.Router) {
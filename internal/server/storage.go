@@ -180,6 +180,7 @@ func (s *DashboardStorage) GetByKey(key string) *docker.StoredConfig {
 		Version:     cfg.Version,
 		Maintainer:  cfg.Maintainer,
 		IconBase64:  cfg.IconBase64,
+		Manifest:    cfg.Manifest,
 		Entries:     make([]docker.StoredEntry, 0, len(cfg.Entries)),
 	}
 
@@ -201,3 +202,18 @@ func (s *DashboardStorage) GetByKey(key string) *docker.StoredConfig {
 
 	return result
 }
+
+// AppNamesByImage implements docker.ConfigProvider interface.
+// Returns the app names of all stored configs whose key has the given image.
+func (s *DashboardStorage) AppNamesByImage(image string) []string {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	var names []string
+	for key, cfg := range s.configs {
+		if key.Image() == image {
+			names = append(names, cfg.AppName)
+		}
+	}
+	return names
+}
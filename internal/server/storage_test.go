@@ -257,6 +257,28 @@ func TestDashboardStorage_GetByKey(t *testing.T) {
 	}
 }
 
+func TestDashboardStorage_AppNamesByImage(t *testing.T) {
+	tmpDir := t.TempDir()
+	os.Setenv("TRIM_PKGETC", tmpDir)
+	defer os.Unsetenv("TRIM_PKGETC")
+
+	storage, err := NewDashboardStorage()
+	if err != nil {
+		t.Fatalf("NewDashboardStorage() error = %v", err)
+	}
+
+	storage.Set(&StoredConfig{Key: NewContainerKey("nginx:alpine", map[string]string{"80": "8080"}), AppName: "watchcow.nginx"})
+	storage.Set(&StoredConfig{Key: NewContainerKey("redis:7", nil), AppName: "watchcow.redis"})
+
+	names := storage.AppNamesByImage("nginx:alpine")
+	if len(names) != 1 || names[0] != "watchcow.nginx" {
+		t.Errorf("AppNamesByImage(nginx:alpine) = %v, want [watchcow.nginx]", names)
+	}
+	if names := storage.AppNamesByImage("nginx"); len(names) != 0 {
+		t.Errorf("AppNamesByImage(nginx) = %v, want none", names)
+	}
+}
+
 func TestDashboardStorage_GetReturnsCopy(t *testing.T) {
 	tmpDir := t.TempDir()
 	os.Setenv("TRIM_PKGETC", tmpDir)
@@ -61,16 +61,17 @@ type StoredEntry struct {
 
 // StoredConfig represents a saved container configuration.
 type StoredConfig struct {
-	Key         ContainerKey  // Unique container identifier
-	AppName     string        // Unique app identifier
-	DisplayName string        // Human-readable name
-	Description string        // App description
-	Version     string        // App version
-	Maintainer  string        // Maintainer name
-	Entries     []StoredEntry // UI entries
-	IconBase64  string        // Base64-encoded PNG icon
-	CreatedAt   time.Time     // When config was created
-	UpdatedAt   time.Time     // When config was last updated
+	Key         ContainerKey      // Unique container identifier
+	AppName     string            // Unique app identifier
+	DisplayName string            // Human-readable name
+	Description string            // App description
+	Version     string            // App version
+	Maintainer  string            // Maintainer name
+	Entries     []StoredEntry     // UI entries
+	IconBase64  string            // Base64-encoded PNG icon
+	Manifest    map[string]string // Extra fnOS manifest fields, see fpkgen.ManifestKeys
+	CreatedAt   time.Time         // When config was created
+	UpdatedAt   time.Time         // When config was last updated
 }
 
 // ContainerInfo represents runtime container information.